@@ -0,0 +1,215 @@
+package feel
+
+import (
+	"strings"
+)
+
+// Dependency is a variable or function referenced by an expression
+type Dependency struct {
+	// the dotted path of a variable such as applicant.address.zip, or
+	// the function name
+	Name string
+	// the segments of a variable path
+	Path []string
+	// whether the function is provided by the prelude
+	Builtin bool
+	// the ranges of the references in the source
+	Ranges []TextRange
+}
+
+// Dependencies are the free variables and the functions referenced
+// by an expression, names bound by for, some and every expressions,
+// function parameters and context entries are excluded
+type Dependencies struct {
+	Variables []*Dependency
+	Functions []*Dependency
+}
+
+// VariableNames returns the dotted paths of the variables, in the
+// order of their first references
+func (deps Dependencies) VariableNames() []string {
+	names := make([]string, len(deps.Variables))
+	for i, dep := range deps.Variables {
+		names[i] = dep.Name
+	}
+	return names
+}
+
+// FunctionNames returns the function names, in the order of their
+// first references
+func (deps Dependencies) FunctionNames() []string {
+	names := make([]string, len(deps.Functions))
+	for i, dep := range deps.Functions {
+		names[i] = dep.Name
+	}
+	return names
+}
+
+// Function finds the reference of a function
+func (deps Dependencies) Function(name string) (*Dependency, bool) {
+	for _, dep := range deps.Functions {
+		if dep.Name == name {
+			return dep, true
+		}
+	}
+	return nil, false
+}
+
+// AnalyzeDependencies collects the dependencies of an AST without
+// evaluating it
+func AnalyzeDependencies(node Node) Dependencies {
+	analyzer := &dependencyAnalyzer{
+		variables: make(map[string]*Dependency),
+		functions: make(map[string]*Dependency),
+	}
+	analyzer.analyze(node)
+	return analyzer.deps
+}
+
+// AnalyzeDependenciesString parses the input and collects its dependencies
+func AnalyzeDependenciesString(input string) (Dependencies, error) {
+	ast, err := ParseString(input)
+	if err != nil {
+		return Dependencies{}, err
+	}
+	return AnalyzeDependencies(ast), nil
+}
+
+type dependencyAnalyzer struct {
+	deps      Dependencies
+	variables map[string]*Dependency
+	functions map[string]*Dependency
+	bound     []map[string]bool
+}
+
+func (analyzer *dependencyAnalyzer) push(names ...string) {
+	scope := make(map[string]bool)
+	for _, name := range names {
+		scope[name] = true
+	}
+	analyzer.bound = append(analyzer.bound, scope)
+}
+
+func (analyzer *dependencyAnalyzer) pop() {
+	analyzer.bound = analyzer.bound[:len(analyzer.bound)-1]
+}
+
+func (analyzer *dependencyAnalyzer) isBound(name string) bool {
+	for i := len(analyzer.bound) - 1; i >= 0; i-- {
+		if analyzer.bound[i][name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (analyzer *dependencyAnalyzer) addVariable(path []string, rng TextRange) {
+	name := strings.Join(path, ".")
+	dep, ok := analyzer.variables[name]
+	if !ok {
+		dep = &Dependency{Name: name, Path: path}
+		analyzer.variables[name] = dep
+		analyzer.deps.Variables = append(analyzer.deps.Variables, dep)
+	}
+	dep.Ranges = append(dep.Ranges, rng)
+}
+
+func (analyzer *dependencyAnalyzer) addFunction(name string, rng TextRange) {
+	dep, ok := analyzer.functions[name]
+	if !ok {
+		_, builtin := GetPrelude().Resolve(name)
+		dep = &Dependency{Name: name, Builtin: builtin}
+		analyzer.functions[name] = dep
+		analyzer.deps.Functions = append(analyzer.deps.Functions, dep)
+	}
+	dep.Ranges = append(dep.Ranges, rng)
+}
+
+// variablePath returns the path of a variable accessed by dots or by
+// string literal indexes, ok is false if the base is not a variable
+func variablePath(node Node) ([]string, bool) {
+	switch v := node.(type) {
+	case *Var:
+		return []string{v.Name}, true
+	case *DotOp:
+		if path, ok := variablePath(v.Left); ok {
+			return append(path, v.Attr), true
+		}
+	case *Binop:
+		if str, isStr := v.Right.(*StringNode); isStr && v.Op == "[]" {
+			if path, ok := variablePath(v.Left); ok {
+				return append(path, str.Content()), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (analyzer *dependencyAnalyzer) analyzeScoped(node Node, names ...string) {
+	analyzer.push(names...)
+	analyzer.analyze(node)
+	analyzer.pop()
+}
+
+func (analyzer *dependencyAnalyzer) analyze(node Node) {
+	if path, ok := variablePath(node); ok {
+		// `?` is the input of unary tests
+		if path[0] != "?" && !analyzer.isBound(path[0]) {
+			analyzer.addVariable(path, node.TextRange())
+		}
+		return
+	}
+
+	switch v := node.(type) {
+	case *Binop:
+		analyzer.analyze(v.Left)
+		analyzer.analyze(v.Right)
+	case *DotOp:
+		analyzer.analyze(v.Left)
+	case *FunCall:
+		if ref, ok := v.FunRef.(*Var); ok {
+			if !analyzer.isBound(ref.Name) {
+				analyzer.addFunction(ref.Name, v.TextRange())
+			}
+		} else {
+			analyzer.analyze(v.FunRef)
+		}
+		for _, arg := range v.Args {
+			analyzer.analyze(arg.Arg)
+		}
+	case *FunDef:
+		analyzer.analyzeScoped(v.Body, v.Args...)
+	case *MapNode:
+		// an entry is visible to the entries after it
+		analyzer.push()
+		for _, item := range v.Values {
+			analyzer.analyze(item.Value)
+			analyzer.bound[len(analyzer.bound)-1][item.Name] = true
+		}
+		analyzer.pop()
+	case *RangeNode:
+		analyzer.analyze(v.Start)
+		analyzer.analyze(v.End)
+	case *IfExpr:
+		analyzer.analyze(v.Cond)
+		analyzer.analyze(v.ThenBranch)
+		analyzer.analyze(v.ElseBranch)
+	case *ArrayNode:
+		for _, elem := range v.Elements {
+			analyzer.analyze(elem)
+		}
+	case *MultiTests:
+		for _, elem := range v.Elements {
+			analyzer.analyze(elem)
+		}
+	case *ForExpr:
+		analyzer.analyze(v.ListExpr)
+		analyzer.analyzeScoped(v.ReturnExpr, v.Varname)
+	case *SomeExpr:
+		analyzer.analyze(v.ListExpr)
+		analyzer.analyzeScoped(v.FilterExpr, v.Varname)
+	case *EveryExpr:
+		analyzer.analyze(v.ListExpr)
+		analyzer.analyzeScoped(v.FilterExpr, v.Varname)
+	}
+}
@@ -0,0 +1,47 @@
+package feel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzeDependencies(t *testing.T) {
+	assert := assert.New(t)
+
+	deps, err := AnalyzeDependenciesString(`if applicant.address.zip in zips then count(applicant.loans) < limit else lookup(applicant["name"])`)
+	assert.Nil(err)
+	assert.Equal([]string{"applicant.address.zip", "zips", "applicant.loans", "limit", "applicant.name"}, deps.VariableNames())
+	assert.Equal([]string{"count", "lookup"}, deps.FunctionNames())
+	assert.Equal([]string{"applicant", "address", "zip"}, deps.Variables[0].Path)
+	assert.Equal(ScanPosition{Row: 0, Column: 3}, deps.Variables[0].Ranges[0].Start)
+
+	count, ok := deps.Function("count")
+	assert.True(ok)
+	assert.True(count.Builtin)
+	lookup, ok := deps.Function("lookup")
+	assert.True(ok)
+	assert.False(lookup.Builtin)
+
+	// bound names are excluded
+	deps, err = AnalyzeDependenciesString(`for x in items return every y in x.parts satisfies y.size > min size`)
+	assert.Nil(err)
+	assert.Equal([]string{"items", "min size"}, deps.VariableNames())
+
+	deps, err = AnalyzeDependenciesString(`{double: function(v) v * factor, a: double(base), b: a + 1}`)
+	assert.Nil(err)
+	assert.Equal([]string{"factor", "base"}, deps.VariableNames())
+	// double is bound by the context entry
+	assert.Equal(0, len(deps.FunctionNames()))
+
+	// references are counted
+	deps, err = AnalyzeDependenciesString(`a + a * sum([a, b])`)
+	assert.Nil(err)
+	assert.Equal([]string{"a", "b"}, deps.VariableNames())
+	assert.Equal(3, len(deps.Variables[0].Ranges))
+
+	// the input of unary tests
+	deps, err = AnalyzeDependenciesString(`> limit, 3`)
+	assert.Nil(err)
+	assert.Equal([]string{"limit"}, deps.VariableNames())
+}
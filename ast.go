@@ -2,6 +2,8 @@ package feel
 
 import (
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
 )
 
@@ -9,6 +11,22 @@ type Scope map[string]any
 
 type Interpreter struct {
 	ScopeStack []Scope
+
+	resolvers []*lazyContext
+	// lazy contexts bound in the scopes
+	lazies []*lazyContext
+	// whether a top level evaluation is running
+	evaluating bool
+
+	hooks  []EvalHook
+	tracer *Tracer
+
+	// runtime errors give null with warnings in the null mode
+	nullMode bool
+	warnings []Warning
+
+	// the source of random number() and uuid()
+	random io.Reader
 }
 
 type Node interface {
@@ -22,8 +40,8 @@ type HasAttrs interface {
 }
 
 type TextRange struct {
-	Start ScanPosition
-	End   ScanPosition
+	Start ScanPosition `json:"start"`
+	End   ScanPosition `json:"end"`
 }
 
 // binary operator
@@ -32,11 +50,11 @@ type Binop struct {
 	Left  Node
 	Right Node
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (op Binop) TextRange() TextRange {
-	return op.textRange
+	return op.Range
 }
 func (op Binop) Repr() string {
 	return fmt.Sprintf("(%s %s %s)", op.Op, op.Left.Repr(), op.Right.Repr())
@@ -47,44 +65,45 @@ type DotOp struct {
 	Left Node
 	Attr string
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (op DotOp) TextRange() TextRange {
-	return op.textRange
+	return op.Range
 }
 
 func (op DotOp) Repr() string {
 	return fmt.Sprintf("(. %s %s)", op.Left.Repr(), op.Attr)
 }
 
-// function call
-type funcallArg struct {
-	argName string
-	arg     Node
+// function call argument, Name is empty for positional arguments
+type FunCallArg struct {
+	Name string
+	Arg  Node
 }
 
 type FunCall struct {
-	FunRef      Node
-	Args        []funcallArg
-	keywordArgs bool
+	FunRef Node
+	Args   []FunCallArg
+	// whether the arguments are passed by names
+	KeywordArgs bool
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (fc FunCall) TextRange() TextRange {
-	return fc.textRange
+	return fc.Range
 }
 func (fc FunCall) Repr() string {
 	argReprs := make([]string, 0)
-	if fc.keywordArgs {
+	if fc.KeywordArgs {
 		for _, arg := range fc.Args {
-			s := fmt.Sprintf("(%s %s)", arg.argName, arg.arg.Repr())
+			s := fmt.Sprintf("(%s %s)", arg.Name, arg.Arg.Repr())
 			argReprs = append(argReprs, s)
 		}
 	} else {
 		for _, arg := range fc.Args {
-			argReprs = append(argReprs, arg.arg.Repr())
+			argReprs = append(argReprs, arg.Arg.Repr())
 		}
 	}
 	return fmt.Sprintf("(call %s [%s])", fc.FunRef.Repr(), strings.Join(argReprs, ", "))
@@ -95,11 +114,11 @@ type FunDef struct {
 	Args []string
 	Body Node
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (fdef FunDef) TextRange() TextRange {
-	return fdef.textRange
+	return fdef.Range
 }
 
 func (fdef FunDef) Repr() string {
@@ -108,12 +127,12 @@ func (fdef FunDef) Repr() string {
 
 // variable
 type Var struct {
-	Name      string
-	textRange TextRange
+	Name  string
+	Range TextRange
 }
 
 func (v Var) TextRange() TextRange {
-	return v.textRange
+	return v.Range
 }
 
 func (v Var) Repr() string {
@@ -127,11 +146,11 @@ func (v Var) Repr() string {
 type NumberNode struct {
 	Value string
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (node NumberNode) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 
 func (node NumberNode) Repr() string {
@@ -142,11 +161,11 @@ func (node NumberNode) Repr() string {
 type BoolNode struct {
 	Value bool
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (node BoolNode) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node BoolNode) Repr() string {
 	if node.Value {
@@ -158,7 +177,7 @@ func (node BoolNode) Repr() string {
 
 // null
 type NullNode struct {
-	textRange TextRange
+	Range TextRange
 }
 
 func (node NullNode) Repr() string {
@@ -166,46 +185,81 @@ func (node NullNode) Repr() string {
 }
 
 func (node NullNode) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 
 // string
 type StringNode struct {
 	Value string
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (node StringNode) Repr() string {
 	return node.Value
 }
 func (node StringNode) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node StringNode) Content() string {
 	// trim leading and trailing quotes
-	s := node.Value[1 : len(node.Value)-1]
+	return unescapeString(node.Value[1 : len(node.Value)-1])
+}
 
-	s = strings.ReplaceAll(s, "\\n", "\n")
-	s = strings.ReplaceAll(s, "\\\"", "\"")
-	return s
+// unescapeString resolves the escape sequences of a string literal,
+// unknown escapes are kept as they are
+func unescapeString(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case '"', '\'', '\\':
+			sb.WriteByte(s[i])
+		case 'u':
+			if i+5 <= len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					sb.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			sb.WriteString(s[i-1 : i+1])
+		default:
+			sb.WriteString(s[i-1 : i+1])
+		}
+	}
+	return sb.String()
 }
 
 // Map
 
-type mapItem struct {
+// context entry
+type MapItem struct {
 	Name  string
 	Value Node
 }
 
 type MapNode struct {
-	Values []mapItem
+	Values []MapItem
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (node MapNode) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node MapNode) Repr() string {
 	var ss []string
@@ -218,12 +272,12 @@ func (node MapNode) Repr() string {
 
 // temporal
 type TemporalNode struct {
-	Value     string
-	textRange TextRange
+	Value string
+	Range TextRange
 }
 
 func (node TemporalNode) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node TemporalNode) Repr() string {
 	return node.Value
@@ -241,11 +295,11 @@ type RangeNode struct {
 	EndOpen bool
 	End     Node
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (node RangeNode) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node RangeNode) Repr() string {
 	startQuote := "["
@@ -265,11 +319,11 @@ type IfExpr struct {
 	ThenBranch Node
 	ElseBranch Node
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (node IfExpr) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node IfExpr) Repr() string {
 	return fmt.Sprintf("(if %s %s %s)", node.Cond.Repr(), node.ThenBranch.Repr(), node.ElseBranch.Repr())
@@ -279,11 +333,11 @@ func (node IfExpr) Repr() string {
 type ArrayNode struct {
 	Elements []Node
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (node ArrayNode) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node ArrayNode) Repr() string {
 	s := make([]string, 0)
@@ -295,11 +349,11 @@ func (node ArrayNode) Repr() string {
 
 // Empty node
 type EmptyNode struct {
-	textRange TextRange
+	Range TextRange
 }
 
 func (node EmptyNode) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node EmptyNode) Repr() string {
 	return ""
@@ -307,12 +361,12 @@ func (node EmptyNode) Repr() string {
 
 // MultiTests
 type MultiTests struct {
-	Elements  []Node
-	textRange TextRange
+	Elements []Node
+	Range    TextRange
 }
 
 func (node MultiTests) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node MultiTests) Repr() string {
 	s := make([]string, 0)
@@ -327,11 +381,11 @@ type ForExpr struct {
 	Varname    string
 	ListExpr   Node
 	ReturnExpr Node
-	textRange  TextRange
+	Range      TextRange
 }
 
 func (node ForExpr) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node ForExpr) Repr() string {
 	return fmt.Sprintf("(for %s %s %s)", node.Varname, node.ListExpr.Repr(), node.ReturnExpr.Repr())
@@ -342,11 +396,11 @@ type SomeExpr struct {
 	Varname    string
 	ListExpr   Node
 	FilterExpr Node
-	textRange  TextRange
+	Range      TextRange
 }
 
 func (node SomeExpr) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node SomeExpr) Repr() string {
 	return fmt.Sprintf("(some \"%s\" %s %s)", node.Varname, node.ListExpr.Repr(), node.FilterExpr.Repr())
@@ -358,11 +412,11 @@ type EveryExpr struct {
 	ListExpr   Node
 	FilterExpr Node
 
-	textRange TextRange
+	Range TextRange
 }
 
 func (node EveryExpr) TextRange() TextRange {
-	return node.textRange
+	return node.Range
 }
 func (node EveryExpr) Repr() string {
 	return fmt.Sprintf("(every \"%s\" %s %s)", node.Varname, node.ListExpr.Repr(), node.FilterExpr.Repr())
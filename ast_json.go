@@ -0,0 +1,384 @@
+package feel
+
+// encoding ASTs as JSON
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ASTVersion is the version of the JSON encoding of ASTs, decoders
+// reject documents of newer versions
+const ASTVersion = 1
+
+// astDocument is the envelope of an encoded AST
+type astDocument struct {
+	Version int       `json:"version"`
+	Node    *jsonNode `json:"node"`
+}
+
+// jsonNode is the JSON form of every node type, distinguished by Type,
+// fields not used by the type are omitted
+type jsonNode struct {
+	Type  string     `json:"type"`
+	Range *TextRange `json:"range,omitempty"`
+
+	// binop, dot
+	Op    string    `json:"op,omitempty"`
+	Left  *jsonNode `json:"left,omitempty"`
+	Right *jsonNode `json:"right,omitempty"`
+	Attr  string    `json:"attr,omitempty"`
+
+	// call
+	Fun         *jsonNode `json:"fun,omitempty"`
+	Args        []jsonArg `json:"args,omitempty"`
+	KeywordArgs bool      `json:"keyword_args,omitempty"`
+
+	// function
+	Params []string  `json:"params,omitempty"`
+	Body   *jsonNode `json:"body,omitempty"`
+
+	// var
+	Name string `json:"name,omitempty"`
+
+	// number, string and temporal keep the source text, bool the value
+	// and const the FEEL literal of the value
+	Value json.RawMessage `json:"value,omitempty"`
+	// const, the text of the folded expression
+	Repr string `json:"repr,omitempty"`
+
+	// context
+	Entries []jsonEntry `json:"entries,omitempty"`
+
+	// range
+	StartOpen bool      `json:"start_open,omitempty"`
+	Start     *jsonNode `json:"start,omitempty"`
+	EndOpen   bool      `json:"end_open,omitempty"`
+	End       *jsonNode `json:"end,omitempty"`
+
+	// if
+	Cond *jsonNode `json:"cond,omitempty"`
+	Then *jsonNode `json:"then,omitempty"`
+	Else *jsonNode `json:"else,omitempty"`
+
+	// list, unary tests
+	Elements []*jsonNode `json:"elements,omitempty"`
+
+	// for, some, every
+	Var    string    `json:"var,omitempty"`
+	List   *jsonNode `json:"list,omitempty"`
+	Return *jsonNode `json:"return,omitempty"`
+	Filter *jsonNode `json:"filter,omitempty"`
+}
+
+type jsonArg struct {
+	Name string    `json:"name,omitempty"`
+	Arg  *jsonNode `json:"arg"`
+}
+
+type jsonEntry struct {
+	Name  string    `json:"name"`
+	Value *jsonNode `json:"value"`
+}
+
+// MarshalAST encodes the AST as versioned JSON, which UnmarshalAST
+// decodes back into an equal AST, source ranges included. The
+// constants folded by Optimize are not encodable.
+func MarshalAST(node Node) ([]byte, error) {
+	jn, err := encodeNode(node, "node")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(astDocument{Version: ASTVersion, Node: jn})
+}
+
+// UnmarshalAST decodes the JSON encoded by MarshalAST into an AST
+func UnmarshalAST(data []byte) (Node, error) {
+	var doc astDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version <= 0 || doc.Version > ASTVersion {
+		return nil, fmt.Errorf("ast json: unsupported version %d", doc.Version)
+	}
+	return decodeNode(doc.Node, "node")
+}
+
+func astJSONError(path string, format string, args ...any) error {
+	return fmt.Errorf("ast json %s: %s", path, fmt.Sprintf(format, args...))
+}
+
+func encodeRange(rng TextRange) *TextRange {
+	if rng == (TextRange{}) {
+		return nil
+	}
+	return &rng
+}
+
+func encodeNodes(nodes []Node, path string) ([]*jsonNode, error) {
+	var jns []*jsonNode
+	for i, node := range nodes {
+		jn, err := encodeNode(node, fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		jns = append(jns, jn)
+	}
+	return jns, nil
+}
+
+func encodeNode(node Node, path string) (*jsonNode, error) {
+	if node == nil {
+		return nil, astJSONError(path, "nil node")
+	}
+	jn := &jsonNode{Range: encodeRange(node.TextRange())}
+	var err error
+	// the children are encoded in order and the first error is kept
+	child := func(n Node, name string) *jsonNode {
+		if err != nil {
+			return nil
+		}
+		var c *jsonNode
+		c, err = encodeNode(n, path+"."+name)
+		return c
+	}
+	switch v := node.(type) {
+	case *Binop:
+		jn.Type = "binop"
+		jn.Op = v.Op
+		jn.Left = child(v.Left, "left")
+		jn.Right = child(v.Right, "right")
+	case *DotOp:
+		jn.Type = "dot"
+		jn.Left = child(v.Left, "left")
+		jn.Attr = v.Attr
+	case *FunCall:
+		jn.Type = "call"
+		jn.Fun = child(v.FunRef, "fun")
+		for i, arg := range v.Args {
+			jn.Args = append(jn.Args, jsonArg{Name: arg.Name, Arg: child(arg.Arg, fmt.Sprintf("args[%d]", i))})
+		}
+		jn.KeywordArgs = v.KeywordArgs
+	case *FunDef:
+		jn.Type = "function"
+		jn.Params = v.Args
+		jn.Body = child(v.Body, "body")
+	case *Var:
+		jn.Type = "var"
+		jn.Name = v.Name
+	case *NumberNode:
+		jn.Type = "number"
+		jn.Value, err = json.Marshal(v.Value)
+	case *BoolNode:
+		jn.Type = "bool"
+		jn.Value, err = json.Marshal(v.Value)
+	case *NullNode:
+		jn.Type = "null"
+	case *StringNode:
+		jn.Type = "string"
+		jn.Value, err = json.Marshal(v.Value)
+	case *TemporalNode:
+		jn.Type = "temporal"
+		jn.Value, err = json.Marshal(v.Value)
+	case *MapNode:
+		jn.Type = "context"
+		for i, item := range v.Values {
+			jn.Entries = append(jn.Entries, jsonEntry{Name: item.Name, Value: child(item.Value, fmt.Sprintf("entries[%d]", i))})
+		}
+	case *RangeNode:
+		jn.Type = "range"
+		jn.StartOpen = v.StartOpen
+		jn.Start = child(v.Start, "start")
+		jn.EndOpen = v.EndOpen
+		jn.End = child(v.End, "end")
+	case *IfExpr:
+		jn.Type = "if"
+		jn.Cond = child(v.Cond, "cond")
+		jn.Then = child(v.ThenBranch, "then")
+		jn.Else = child(v.ElseBranch, "else")
+	case *ArrayNode:
+		jn.Type = "list"
+		jn.Elements, err = encodeNodes(v.Elements, path+".elements")
+	case *MultiTests:
+		jn.Type = "unary_tests"
+		jn.Elements, err = encodeNodes(v.Elements, path+".elements")
+	case *EmptyNode:
+		jn.Type = "empty"
+	case *ForExpr:
+		jn.Type = "for"
+		jn.Var = v.Varname
+		jn.List = child(v.ListExpr, "list")
+		jn.Return = child(v.ReturnExpr, "return")
+	case *SomeExpr:
+		jn.Type = "some"
+		jn.Var = v.Varname
+		jn.List = child(v.ListExpr, "list")
+		jn.Filter = child(v.FilterExpr, "filter")
+	case *EveryExpr:
+		jn.Type = "every"
+		jn.Var = v.Varname
+		jn.List = child(v.ListExpr, "list")
+		jn.Filter = child(v.FilterExpr, "filter")
+	case *ConstNode:
+		jn.Type = "const"
+		var literal string
+		if literal, err = FormatValue(v.Value); err == nil {
+			jn.Value, err = json.Marshal(literal)
+		}
+		jn.Repr = v.repr
+	default:
+		return nil, astJSONError(path, "cannot encode %T", node)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jn, nil
+}
+
+func decodeNodes(jns []*jsonNode, path string) ([]Node, error) {
+	var nodes []Node
+	for i, jn := range jns {
+		node, err := decodeNode(jn, fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func decodeNode(jn *jsonNode, path string) (Node, error) {
+	if jn == nil {
+		return nil, astJSONError(path, "missing node")
+	}
+	var rng TextRange
+	if jn.Range != nil {
+		rng = *jn.Range
+	}
+	var err error
+	// the children are decoded in order and the first error is kept
+	child := func(c *jsonNode, name string) Node {
+		if err != nil {
+			return nil
+		}
+		var n Node
+		n, err = decodeNode(c, path+"."+name)
+		return n
+	}
+	// the source text of literals
+	source := func() string {
+		var s string
+		if err == nil {
+			if e := json.Unmarshal(jn.Value, &s); e != nil || s == "" {
+				err = astJSONError(path, "bad value of %s", jn.Type)
+			}
+		}
+		return s
+	}
+
+	var node Node
+	switch jn.Type {
+	case "binop":
+		if binopPrecedence(jn.Op) == precAtom {
+			return nil, astJSONError(path, "unknown operator %q", jn.Op)
+		}
+		node = &Binop{Op: jn.Op, Left: child(jn.Left, "left"), Right: child(jn.Right, "right"), Range: rng}
+	case "dot":
+		node = &DotOp{Left: child(jn.Left, "left"), Attr: jn.Attr, Range: rng}
+	case "call":
+		call := &FunCall{FunRef: child(jn.Fun, "fun"), KeywordArgs: jn.KeywordArgs, Range: rng}
+		for i, arg := range jn.Args {
+			call.Args = append(call.Args, FunCallArg{Name: arg.Name, Arg: child(arg.Arg, fmt.Sprintf("args[%d]", i))})
+		}
+		node = call
+	case "function":
+		node = &FunDef{Args: jn.Params, Body: child(jn.Body, "body"), Range: rng}
+	case "var":
+		if jn.Name == "" {
+			return nil, astJSONError(path, "missing name of var")
+		}
+		node = &Var{Name: jn.Name, Range: rng}
+	case "number":
+		node = &NumberNode{Value: source(), Range: rng}
+	case "bool":
+		var b bool
+		if e := json.Unmarshal(jn.Value, &b); e != nil {
+			err = astJSONError(path, "bad value of bool")
+		}
+		node = &BoolNode{Value: b, Range: rng}
+	case "null":
+		node = &NullNode{Range: rng}
+	case "string":
+		node = &StringNode{Value: source(), Range: rng}
+	case "temporal":
+		node = &TemporalNode{Value: source(), Range: rng}
+	case "context":
+		mapNode := &MapNode{Range: rng}
+		for i, entry := range jn.Entries {
+			mapNode.Values = append(mapNode.Values, MapItem{Name: entry.Name, Value: child(entry.Value, fmt.Sprintf("entries[%d]", i))})
+		}
+		node = mapNode
+	case "range":
+		node = &RangeNode{
+			StartOpen: jn.StartOpen,
+			Start:     child(jn.Start, "start"),
+			EndOpen:   jn.EndOpen,
+			End:       child(jn.End, "end"),
+			Range:     rng,
+		}
+	case "if":
+		node = &IfExpr{
+			Cond:       child(jn.Cond, "cond"),
+			ThenBranch: child(jn.Then, "then"),
+			ElseBranch: child(jn.Else, "else"),
+			Range:      rng,
+		}
+	case "list":
+		var elements []Node
+		elements, err = decodeNodes(jn.Elements, path+".elements")
+		node = &ArrayNode{Elements: elements, Range: rng}
+	case "unary_tests":
+		var elements []Node
+		elements, err = decodeNodes(jn.Elements, path+".elements")
+		node = &MultiTests{Elements: elements, Range: rng}
+	case "empty":
+		node = &EmptyNode{Range: rng}
+	case "for":
+		node = &ForExpr{Varname: jn.Var, ListExpr: child(jn.List, "list"), ReturnExpr: child(jn.Return, "return"), Range: rng}
+	case "some":
+		node = &SomeExpr{Varname: jn.Var, ListExpr: child(jn.List, "list"), FilterExpr: child(jn.Filter, "filter"), Range: rng}
+	case "every":
+		node = &EveryExpr{Varname: jn.Var, ListExpr: child(jn.List, "list"), FilterExpr: child(jn.Filter, "filter"), Range: rng}
+	case "const":
+		var value any
+		if literal := source(); err == nil {
+			value, err = parseConst(literal)
+			if err != nil {
+				err = astJSONError(path, "bad value of const, %s", err)
+			}
+		}
+		node = &ConstNode{Value: value, repr: jn.Repr, Range: rng}
+	default:
+		return nil, astJSONError(path, "unknown node type %q", jn.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// parseConst evaluates the literal of a folded value
+func parseConst(literal string) (any, error) {
+	ast, err := ParseString(literal)
+	if err != nil {
+		return nil, err
+	}
+	v, err := NewIntepreter().EvalNode(ast)
+	if err != nil {
+		return nil, err
+	}
+	if !foldable(v) {
+		return nil, fmt.Errorf("%s is not a constant", literal)
+	}
+	return v, nil
+}
@@ -0,0 +1,131 @@
+package feel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestASTJSONRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	inputs := []string{
+		`> 8, <= 5, "x", [1..3)`,
+		`(1..10]`,
+		``,
+	}
+	for _, p := range evalPairs {
+		inputs = append(inputs, p.input)
+	}
+	for _, input := range inputs {
+		ast, err := ParseString(input)
+		assert.Nil(err, input)
+		data, err := MarshalAST(ast)
+		assert.Nil(err, input)
+		decoded, err := UnmarshalAST(data)
+		assert.Nil(err, input)
+		// the structures and source ranges are equal
+		assert.Equal(ast, decoded, input)
+	}
+
+	// the decoded AST is evaluated like the parsed one
+	for _, p := range evalPairs {
+		ast, err := ParseString(p.input)
+		assert.Nil(err)
+		data, err := MarshalAST(ast)
+		assert.Nil(err)
+		decoded, err := UnmarshalAST(data)
+		assert.Nil(err)
+
+		intp := NewIntepreter()
+		if p.context != "" {
+			assert.Nil(intp.PushVars(p.context))
+		}
+		res, err := intp.EvalNode(decoded)
+		assert.Nil(err, p.input)
+		assert.True(cmp.Equal(p.expect, res), "%s gives %v", p.input, res)
+	}
+}
+
+func TestASTJSONFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	ast, err := ParseString(`f(x: a.b)`)
+	assert.Nil(err)
+	data, err := MarshalAST(ast)
+	assert.Nil(err)
+	expect := `{"version":1,"node":{"type":"call","range":{"start":{"row":0,"column":0},"end":{"row":0,"column":9}},` +
+		`"fun":{"type":"var","range":{"start":{"row":0,"column":0},"end":{"row":0,"column":1}},"name":"f"},` +
+		`"args":[{"name":"x","arg":{"type":"dot","range":{"start":{"row":0,"column":5},"end":{"row":0,"column":8}},` +
+		`"left":{"type":"var","range":{"start":{"row":0,"column":5},"end":{"row":0,"column":6}},"name":"a"},"attr":"b"}}],"keyword_args":true}}`
+	assert.Equal(expect, string(data))
+}
+
+func TestASTJSONErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := UnmarshalAST([]byte(`{"version":2,"node":{"type":"null"}}`))
+	assert.EqualError(err, "ast json: unsupported version 2")
+
+	_, err = UnmarshalAST([]byte(`{"version":1,"node":{"type":"binop","op":"+","left":{"type":"number","value":"1"}}}`))
+	assert.EqualError(err, "ast json node.right: missing node")
+
+	_, err = UnmarshalAST([]byte(`{"version":1,"node":{"type":"list","elements":[{"type":"null"},{"type":"goto"}]}}`))
+	assert.EqualError(err, `ast json node.elements[1]: unknown node type "goto"`)
+
+	_, err = UnmarshalAST([]byte(`{"version":1,"node":{"type":"binop","op":"^","left":{"type":"null"},"right":{"type":"null"}}}`))
+	assert.EqualError(err, `ast json node: unknown operator "^"`)
+
+	_, err = UnmarshalAST([]byte(`{"version":1,"node":{"type":"const","value":"[1, 2]"}}`))
+	assert.EqualError(err, `ast json node: bad value of const, [1, 2] is not a constant`)
+}
+
+func TestASTJSONOptimized(t *testing.T) {
+	assert := assert.New(t)
+
+	inputs := []string{
+		`a + 1 * 2`,
+		`if 1 > 2 then a else "x" + "y"`,
+		`x > @"2024-01-01T10:00:00@Europe/Paris" and false`,
+		`[@"24:00:00", 1 / 3, null, true, @"P1DT2H"]`,
+	}
+	for _, p := range evalPairs {
+		inputs = append(inputs, p.input)
+	}
+	for _, input := range inputs {
+		ast, err := ParseString(input)
+		assert.Nil(err, input)
+		optimized := Optimize(ast)
+		data, err := MarshalAST(optimized)
+		if !assert.Nil(err, input) {
+			continue
+		}
+		decoded, err := UnmarshalAST(data)
+		if !assert.Nil(err, input) {
+			continue
+		}
+		assert.Equal(optimized.Repr(), decoded.Repr(), input)
+		again, err := MarshalAST(decoded)
+		assert.Nil(err, input)
+		assert.Equal(string(data), string(again), input)
+	}
+
+	// the decoded constants evaluate like the folded ones
+	for _, p := range evalPairs {
+		ast, err := ParseString(p.input)
+		assert.Nil(err)
+		data, err := MarshalAST(Optimize(ast))
+		assert.Nil(err)
+		decoded, err := UnmarshalAST(data)
+		assert.Nil(err)
+
+		intp := NewIntepreter()
+		if p.context != "" {
+			assert.Nil(intp.PushVars(p.context))
+		}
+		res, err := intp.EvalNode(decoded)
+		assert.Nil(err, p.input)
+		assert.True(cmp.Equal(p.expect, res), "%s gives %v", p.input, res)
+	}
+}
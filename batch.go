@@ -0,0 +1,279 @@
+package feel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ScopeIterator yields the input scopes of a batch, in the style of
+// bufio.Scanner
+type ScopeIterator interface {
+	// Next advances to the next record, it returns false at the end of
+	// the input or on a failure reported by Err
+	Next() bool
+	// Scope returns the scope of the current record, an error means the
+	// record is malformed and it's reported as the error of the record
+	Scope() (Scope, error)
+	// Err returns the failure stopping the iteration
+	Err() error
+}
+
+type sliceIterator struct {
+	scopes []Scope
+	pos    int
+}
+
+// ScopesOf iterates the scopes in the slice
+func ScopesOf(scopes []Scope) ScopeIterator {
+	return &sliceIterator{scopes: scopes}
+}
+
+func (it *sliceIterator) Next() bool {
+	if it.pos >= len(it.scopes) {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *sliceIterator) Scope() (Scope, error) {
+	return it.scopes[it.pos-1], nil
+}
+
+func (it *sliceIterator) Err() error {
+	return nil
+}
+
+// JSONLinesIterator reads a JSON object per line as scopes, blank lines
+// are skipped and numbers are kept exact
+type JSONLinesIterator struct {
+	scanner *bufio.Scanner
+	line    []byte
+	lineno  int
+}
+
+// NewJSONLinesIterator creates an iterator over the JSON Lines input
+func NewJSONLinesIterator(r io.Reader) *JSONLinesIterator {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	return &JSONLinesIterator{scanner: scanner}
+}
+
+func (it *JSONLinesIterator) Next() bool {
+	for it.scanner.Scan() {
+		it.lineno++
+		line := bytes.TrimSpace(it.scanner.Bytes())
+		if len(line) > 0 {
+			it.line = line
+			return true
+		}
+	}
+	return false
+}
+
+// Line returns the line number of the current record
+func (it *JSONLinesIterator) Line() int {
+	return it.lineno
+}
+
+func (it *JSONLinesIterator) Scope() (Scope, error) {
+	decoder := json.NewDecoder(bytes.NewReader(it.line))
+	decoder.UseNumber()
+	var scope Scope
+	if err := decoder.Decode(&scope); err != nil {
+		return nil, fmt.Errorf("line %d, %w", it.lineno, err)
+	}
+	return scope, nil
+}
+
+func (it *JSONLinesIterator) Err() error {
+	return it.scanner.Err()
+}
+
+// BatchOptions configures the evaluation of a batch
+type BatchOptions struct {
+	// the number of goroutines, runtime.GOMAXPROCS(0) when it's not positive
+	Workers int
+	// whether results are emitted in the order of the inputs
+	Ordered bool
+	// evaluate in the null mode, see Interpreter.EnableNullMode
+	NullMode bool
+}
+
+// BatchResult is the outcome of a record, Index is the position of the
+// record in the input starting from 0
+type BatchResult struct {
+	Index    int
+	Value    any
+	Err      error
+	Warnings []Warning
+	Duration time.Duration
+}
+
+// BatchStats aggregates the outcomes of a batch
+type BatchStats struct {
+	Total     int
+	Succeeded int
+	Failed    int
+	// the failed records whose inputs are malformed
+	Malformed int
+	// the wall time of the batch
+	Elapsed time.Duration
+	// the durations of the evaluations of records
+	TotalDuration time.Duration
+	MinDuration   time.Duration
+	MaxDuration   time.Duration
+}
+
+// MeanDuration returns the average duration of evaluating a record
+func (stats BatchStats) MeanDuration() time.Duration {
+	evaluated := stats.Total - stats.Malformed
+	if evaluated == 0 {
+		return 0
+	}
+	return stats.TotalDuration / time.Duration(evaluated)
+}
+
+// Throughput returns the number of records evaluated per second
+func (stats BatchStats) Throughput() float64 {
+	if stats.Elapsed <= 0 {
+		return 0
+	}
+	return float64(stats.Total) / stats.Elapsed.Seconds()
+}
+
+func (stats BatchStats) String() string {
+	return fmt.Sprintf("%d records, %d succeeded, %d failed, %d malformed in %s, %.1f records/s, evaluation min %s mean %s max %s",
+		stats.Total, stats.Succeeded, stats.Failed, stats.Malformed, stats.Elapsed,
+		stats.Throughput(), stats.MinDuration, stats.MeanDuration(), stats.MaxDuration)
+}
+
+func (stats *BatchStats) add(res batchResult) {
+	stats.Total++
+	if res.Err != nil {
+		stats.Failed++
+	} else {
+		stats.Succeeded++
+	}
+	if res.malformed {
+		stats.Malformed++
+		return
+	}
+	if stats.Total-stats.Malformed == 1 || res.Duration < stats.MinDuration {
+		stats.MinDuration = res.Duration
+	}
+	if res.Duration > stats.MaxDuration {
+		stats.MaxDuration = res.Duration
+	}
+	stats.TotalDuration += res.Duration
+}
+
+type batchJob struct {
+	index int
+	scope Scope
+	err   error
+}
+
+type batchResult struct {
+	BatchResult
+	malformed bool
+}
+
+// evalRecord evaluates the program with the scope of a record, a panic
+// is recovered as the error of the record
+func evalRecord(prog *Program, scope Scope, nullMode bool) (value any, warnings []Warning, err error) {
+	intp := NewIntepreter()
+	if nullMode {
+		intp.EnableNullMode()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, fmt.Errorf("panic: %v", r)
+		}
+		warnings = intp.Warnings()
+	}()
+	if err = intp.pushScope(scope); err != nil {
+		return nil, nil, err
+	}
+	value, err = prog.Run(intp)
+	return value, nil, err
+}
+
+// EvalBatch evaluates the program with every scope of the iterator on
+// a pool of goroutines, emit is called with the result of each record
+// from the calling goroutine. Errors and panics of records don't stop
+// the batch, the returned error is the failure of the iterator.
+func EvalBatch(prog *Program, scopes ScopeIterator, opts BatchOptions, emit func(BatchResult)) (BatchStats, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	start := time.Now()
+
+	// the records in flight are bounded, so that ordered results
+	// waiting for a slow record don't pile up
+	window := make(chan struct{}, workers*4)
+	jobs := make(chan batchJob, workers)
+	results := make(chan batchResult, workers)
+
+	go func() {
+		defer close(jobs)
+		for index := 0; scopes.Next(); index++ {
+			scope, err := scopes.Scope()
+			window <- struct{}{}
+			jobs <- batchJob{index: index, scope: scope, err: err}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				res := batchResult{BatchResult: BatchResult{Index: job.index, Err: job.err}, malformed: job.err != nil}
+				if job.err == nil {
+					startEval := time.Now()
+					res.Value, res.Warnings, res.Err = evalRecord(prog, job.scope, opts.NullMode)
+					res.Duration = time.Since(startEval)
+				}
+				results <- res
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var stats BatchStats
+	pending := make(map[int]BatchResult)
+	next := 0
+	for res := range results {
+		stats.add(res)
+		if !opts.Ordered {
+			emit(res.BatchResult)
+			<-window
+			continue
+		}
+		pending[res.Index] = res.BatchResult
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			emit(r)
+			<-window
+			next++
+		}
+	}
+	stats.Elapsed = time.Since(start)
+	return stats, scopes.Err()
+}
@@ -0,0 +1,129 @@
+package feel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	prog, err := CompileString(`price * quantity`)
+	assert.Nil(err)
+
+	var scopes []Scope
+	for i := 0; i < 200; i++ {
+		if i%10 == 3 {
+			scopes = append(scopes, Scope{"price": "bad", "quantity": i})
+		} else {
+			scopes = append(scopes, Scope{"price": i, "quantity": 2})
+		}
+	}
+
+	for _, ordered := range []bool{true, false} {
+		var results []BatchResult
+		stats, err := EvalBatch(prog, ScopesOf(scopes), BatchOptions{Workers: 4, Ordered: ordered}, func(res BatchResult) {
+			results = append(results, res)
+		})
+		assert.Nil(err)
+		assert.Equal(200, stats.Total)
+		assert.Equal(20, stats.Failed)
+		assert.Equal(180, stats.Succeeded)
+		assert.Equal(0, stats.Malformed)
+		assert.True(stats.MinDuration <= stats.MeanDuration() && stats.MeanDuration() <= stats.MaxDuration)
+
+		assert.Equal(200, len(results))
+		seen := make(map[int]bool)
+		for i, res := range results {
+			if ordered {
+				assert.Equal(i, res.Index)
+			}
+			seen[res.Index] = true
+			if res.Index%10 == 3 {
+				assert.NotNil(res.Err)
+			} else {
+				assert.Nil(res.Err)
+				assert.True(cmp.Equal(N(res.Index*2), res.Value), "%d gives %v", res.Index, res.Value)
+			}
+		}
+		assert.Equal(200, len(seen))
+	}
+}
+
+func TestEvalBatchJSONLines(t *testing.T) {
+	assert := assert.New(t)
+
+	input := `{"a": 0.1, "b": 0.2}
+
+{"a": "x"
+{"a": 12345678901234567890, "b": 1}
+`
+	prog, err := CompileString(`a + b`)
+	assert.Nil(err)
+
+	var results []BatchResult
+	stats, err := EvalBatch(prog, NewJSONLinesIterator(strings.NewReader(input)), BatchOptions{Ordered: true}, func(res BatchResult) {
+		results = append(results, res)
+	})
+	assert.Nil(err)
+	assert.Equal(3, stats.Total)
+	assert.Equal(1, stats.Malformed)
+
+	assert.True(cmp.Equal(N("0.3"), results[0].Value), "gives %v", results[0].Value)
+	assert.Contains(results[1].Err.Error(), "line 3")
+	assert.True(cmp.Equal(N("12345678901234567891"), results[2].Value), "gives %v", results[2].Value)
+}
+
+func TestEvalBatchNullMode(t *testing.T) {
+	assert := assert.New(t)
+
+	prog, err := CompileString(`price * 2`)
+	assert.Nil(err)
+	scopes := []Scope{{"price": 3}, {"price": "x"}}
+	var results []BatchResult
+	stats, err := EvalBatch(prog, ScopesOf(scopes), BatchOptions{Ordered: true, NullMode: true}, func(res BatchResult) {
+		results = append(results, res)
+	})
+	assert.Nil(err)
+	assert.Equal(2, stats.Succeeded)
+	assert.Equal(0, len(results[0].Warnings))
+	assert.Equal(Null, results[1].Value)
+	assert.Equal(1, len(results[1].Warnings))
+	assert.Equal(-3101, results[1].Warnings[0].Code)
+}
+
+func TestEvalBatchPanic(t *testing.T) {
+	assert := assert.New(t)
+
+	prog, err := CompileString(`check(x) + 1`)
+	assert.Nil(err)
+	check := NewNativeFunc(func(args map[string]any) (any, error) {
+		if args["x"].(*Number).Int() == 2 {
+			panic("broken check")
+		}
+		return args["x"], nil
+	}).Required("x")
+
+	var scopes []Scope
+	for i := 0; i < 5; i++ {
+		scopes = append(scopes, Scope{"x": i, "check": check})
+	}
+	var results []BatchResult
+	stats, err := EvalBatch(prog, ScopesOf(scopes), BatchOptions{Workers: 2, Ordered: true}, func(res BatchResult) {
+		results = append(results, res)
+	})
+	assert.Nil(err)
+	assert.Equal(5, stats.Total)
+	assert.Equal(1, stats.Failed)
+	for i, res := range results {
+		if i == 2 {
+			assert.EqualError(res.Err, "panic: broken check")
+		} else {
+			assert.Nil(res.Err)
+			assert.True(cmp.Equal(N(i+1), res.Value))
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/mitchellh/mapstructure"
-	"math"
 	"sort"
 	"strings"
 )
@@ -49,19 +48,230 @@ func extractList(args map[string]any, argName string) ([]any, error) {
 	}
 }
 
+// allOf is false if any item is false, null if any item is not a
+// boolean, and true otherwise
+func allOf(list []any) any {
+	var result any = true
+	for _, v := range list {
+		if v == false {
+			return false
+		} else if v != true {
+			result = Null
+		}
+	}
+	return result
+}
+
+// anyOf is true if any item is true, null if any item is not a
+// boolean, and false otherwise
+func anyOf(list []any) any {
+	var result any = false
+	for _, v := range list {
+		if v == true {
+			return true
+		} else if v != false {
+			result = Null
+		}
+	}
+	return result
+}
+
+// stringValue converts the value to its canonical string, numbers in
+// decimals, temporals in ISO 8601 and other values as FEEL literals,
+// null gives null
+func stringValue(v any) any {
+	switch vv := v.(type) {
+	case nil, *NullValue:
+		return Null
+	case string:
+		return vv
+	case *Number:
+		return vv.String()
+	case *FEELDate, *FEELTime, *FEELDatetime, *FEELDuration:
+		return fmt.Sprintf("%s", vv)
+	default:
+		s, err := FormatValue(vv)
+		if err != nil {
+			return Null
+		}
+		return s
+	}
+}
+
+// separatorArg gets the optional separator argument of number(), ok is
+// false when it's not one of the allowed
+func separatorArg(args map[string]any, name string, allowed ...string) (sep string, ok bool) {
+	switch v := args[name].(type) {
+	case nil, *NullValue:
+		return "", true
+	case string:
+		for _, a := range allowed {
+			if v == a {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// parseNumberWithSeparators parses the number with the grouping
+// separator and the decimal separator, null is returned when it's not
+// a number
+func parseNumberWithSeparators(s string, grouping, decimal string) any {
+	if grouping != "" {
+		s = strings.ReplaceAll(s, grouping, "")
+	}
+	if decimal != "" && decimal != "." {
+		if strings.Contains(s, ".") {
+			return Null
+		}
+		s = strings.Replace(s, decimal, ".", 1)
+	}
+	n, err := parseNumber(s)
+	if err != nil {
+		return Null
+	}
+	return n
+}
+
+// numbersOf gets the numbers of the list, ok is false when an item is
+// not a number
+func numbersOf(v any) ([]*Number, bool) {
+	list, isList := v.([]any)
+	if !isList {
+		return nil, false
+	}
+	numbers := make([]*Number, 0, len(list))
+	for _, item := range list {
+		n, isNumber := item.(*Number)
+		if !isNumber {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, true
+}
+
+// numberListFunc makes a statistical function of either a list of
+// numbers or the numbers as arguments, other items give null
+func numberListFunc(fn func(list []*Number) any) *NativeFun {
+	return NewNativeFunc(func(args map[string]any) (any, error) {
+		list, err := extractList(args, "list")
+		if err != nil {
+			return nil, err
+		}
+		numbers, ok := numbersOf(list)
+		if !ok {
+			return Null, nil
+		}
+		return fn(numbers), nil
+	}).Vararg("list")
+}
+
+// extremeOf finds the least item when sign is -1 and the greatest when
+// it's 1, null is returned for nulls and items not comparable with the
+// others
+func extremeOf(list []any, sign int) any {
+	var extreme any = Null
+	for i, entry := range list {
+		if isNull(entry) {
+			return Null
+		}
+		if i == 0 {
+			extreme = entry
+		} else if cmp, err := compareInterfaces(entry, extreme); err != nil {
+			return Null
+		} else if cmp == sign {
+			extreme = entry
+		}
+	}
+	return extreme
+}
+
+func sortedNumbers(list []*Number) []*Number {
+	sorted := append([]*Number{}, list...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Compare(*sorted[j]) < 0
+	})
+	return sorted
+}
+
+func sumOf(list []*Number) *Number {
+	sum := Zero
+	for _, n := range list {
+		sum = sum.Add(n)
+	}
+	return sum
+}
+
+// meanOf is nil for the empty list
+func meanOf(list []*Number) *Number {
+	return sumOf(list).Div(NewNumberFromInt64(int64(len(list))))
+}
+
+// varianceOf is the sample variance, nil for less than 2 numbers
+func varianceOf(list []*Number) *Number {
+	if len(list) < 2 {
+		return nil
+	}
+	mean := meanOf(list)
+	squares := Zero
+	for _, n := range list {
+		d := n.Sub(mean)
+		squares = squares.Add(d.Mul(d))
+	}
+	return squares.Div(NewNumberFromInt64(int64(len(list) - 1)))
+}
+
+// quantileOf interpolates linearly between the closest ranks of the
+// sorted numbers, nil for the empty list or q out of [0, 1]
+func quantileOf(list []*Number, q *Number) *Number {
+	if len(list) == 0 || q == nil || q.Sign() < 0 || q.Compare(*N(1)) > 0 {
+		return nil
+	}
+	sorted := sortedNumbers(list)
+	// the rank starting from 0
+	rank := q.Mul(NewNumberFromInt64(int64(len(sorted) - 1)))
+	lower := rank.Round(0, RoundFloor)
+	i := lower.Int()
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank.Sub(lower)
+	return sorted[i].Add(sorted[i+1].Sub(sorted[i]).Mul(frac))
+}
+
 func installBuiltinFunctions(prelude *Prelude) {
 	// conversion functions
-	prelude.Bind("string", wrapTyped(func(v any) (string, error) {
-		return fmt.Sprintf("%s", v), nil
+	prelude.Bind("string", wrapTyped(func(v any) (any, error) {
+		return stringValue(v), nil
 	}).Required("from"))
 
-	prelude.Bind("number", wrapTyped(func(v any) (*Number, error) {
-		return ParseNumberWithErr(v)
-	}).Required("from"))
+	prelude.Bind("number", NewNativeFunc(func(args map[string]any) (any, error) {
+		grouping, ok := separatorArg(args, "grouping separator", " ", ",", ".")
+		if !ok {
+			return Null, nil
+		}
+		decimal, ok := separatorArg(args, "decimal separator", ",", ".")
+		if !ok || (grouping != "" && grouping == decimal) {
+			return Null, nil
+		}
+		switch v := args["from"].(type) {
+		case *Number:
+			return v, nil
+		case string:
+			return parseNumberWithSeparators(v, grouping, decimal), nil
+		default:
+			return Null, nil
+		}
+	}).Required("from").Optional("grouping separator", "decimal separator"))
 
 	// boolean functions
-	prelude.Bind("not", wrapTyped(func(v any) (bool, error) {
-		return !boolValue(v), nil
+	prelude.Bind("not", wrapTyped(func(v any) (any, error) {
+		if b, ok := v.(bool); ok {
+			return !b, nil
+		}
+		return Null, nil
 	}).Required("from"))
 
 	prelude.Bind("is defined", NewMacro(func(intp *Interpreter, args map[string]Node, varArgs []Node) (any, error) {
@@ -70,7 +280,11 @@ func installBuiltinFunctions(prelude *Prelude) {
 				return false, nil
 			}
 		} else {
-			_, err := args["value"].Eval(intp)
+			// missing keys and indexes must not turn into null
+			nullMode := intp.nullMode
+			intp.nullMode = false
+			_, err := intp.EvalNode(args["value"])
+			intp.nullMode = nullMode
 			if err != nil {
 				var evalErr *EvalError
 				if errors.As(err, &evalErr) {
@@ -159,16 +373,7 @@ func installBuiltinFunctions(prelude *Prelude) {
 		if err != nil {
 			return nil, err
 		}
-		var minValue any = nil
-		for _, entry := range list {
-			if minValue == nil {
-				minValue = entry
-			} else if cmp, err := compareInterfaces(minValue, entry); err == nil && cmp == 1 {
-				// minValue > entry
-				minValue = entry
-			}
-		}
-		return minValue, nil
+		return extremeOf(list, -1), nil
 	}).Vararg("list"))
 
 	prelude.Bind("max", NewNativeFunc(func(args map[string]any) (any, error) {
@@ -176,135 +381,87 @@ func installBuiltinFunctions(prelude *Prelude) {
 		if err != nil {
 			return nil, err
 		}
-		var maxValue any = nil
-		for _, entry := range list {
-			if maxValue == nil {
-				maxValue = entry
-			} else if cmp, err := compareInterfaces(maxValue, entry); err == nil && cmp == -1 {
-				// maxValue < entry
-				maxValue = entry
-			}
-		}
-		return maxValue, nil
-	}).Vararg("list"))
-
-	prelude.Bind("sum", NewNativeFunc(func(args map[string]any) (any, error) {
-		list, err := extractList(args, "list")
-		if err != nil {
-			return nil, err
-		}
-		sum := Zero
-		for _, entry := range list {
-			if numEntry, ok := entry.(*Number); ok {
-				sum = sum.Add(numEntry)
-			}
-		}
-		return sum, nil
-	}).Vararg("list"))
-
-	prelude.Bind("product", NewNativeFunc(func(args map[string]any) (any, error) {
-		list, err := extractList(args, "list")
-		if err != nil {
-			return nil, err
-		}
-		sum := N(1)
-		for _, entry := range list {
-			if numEntry, ok := entry.(*Number); ok {
-				sum = sum.Mul(numEntry)
-			}
-		}
-		return sum, nil
+		return extremeOf(list, 1), nil
 	}).Vararg("list"))
 
-	prelude.Bind("mean", NewNativeFunc(func(args map[string]any) (any, error) {
-		list, err := extractList(args, "list")
-		if err != nil {
-			return nil, err
-		}
-		sum := Zero
-		cnt := 0
-		for _, entry := range list {
-			if numEntry, ok := entry.(*Number); ok {
-				sum = sum.Add(numEntry)
-				cnt++
-			}
+	prelude.Bind("sum", numberListFunc(func(list []*Number) any {
+		if len(list) == 0 {
+			return Null
 		}
-		r := sum.FloatDiv(N(cnt))
-		return r, nil
-	}).Vararg("list"))
+		return numberOrNull(sumOf(list))
+	}))
 
-	prelude.Bind("stddev", NewNativeFunc(func(args map[string]any) (any, error) {
-		list, err := extractList(args, "list")
-		if err != nil {
-			return nil, err
+	prelude.Bind("product", numberListFunc(func(list []*Number) any {
+		if len(list) == 0 {
+			return Null
 		}
-		sum := 0.0
-		cnt := 0
-		for _, entry := range list {
-			if numEntry, ok := entry.(*Number); ok {
-				//sum = sum.Add(numEntry)
-				sum += numEntry.Float64()
-				cnt++
-			}
-		}
-		if cnt == 0 {
-			return 0, nil
+		product := N(1)
+		for _, n := range list {
+			product = product.Mul(n)
 		}
+		return numberOrNull(product)
+	}))
 
-		avg := sum / float64(cnt)
+	prelude.Bind("mean", numberListFunc(func(list []*Number) any {
+		return numberOrNull(meanOf(list))
+	}))
 
-		dev := 0.0
-		for _, entry := range list {
-			if numEntry, ok := entry.(*Number); ok {
-				n := numEntry.Float64()
-				dev += (n - avg) * (n - avg)
-			}
-		}
+	prelude.Bind("median", numberListFunc(func(list []*Number) any {
+		return numberOrNull(quantileOf(list, N("0.5")))
+	}))
 
-		return math.Sqrt(dev / float64(cnt)), nil
-	}).Vararg("list"))
+	prelude.Bind("variance", numberListFunc(func(list []*Number) any {
+		return numberOrNull(varianceOf(list))
+	}))
 
-	prelude.Bind("median", NewNativeFunc(func(args map[string]any) (any, error) {
-		list, err := extractList(args, "list")
-		if err != nil {
-			return nil, err
-		}
-		var numberList []*Number
+	prelude.Bind("stddev", numberListFunc(func(list []*Number) any {
+		return numberOrNull(varianceOf(list).Sqrt())
+	}))
 
-		for _, entry := range list {
-			if numEntry, ok := entry.(*Number); ok {
-				numberList = append(numberList, numEntry)
+	prelude.Bind("mode", numberListFunc(func(list []*Number) any {
+		sorted := sortedNumbers(list)
+		modes := []any{}
+		best := 0
+		for i := 0; i < len(sorted); {
+			j := i + 1
+			for j < len(sorted) && sorted[j].Equal(*sorted[i]) {
+				j++
 			}
+			if j-i > best {
+				best = j - i
+				modes = []any{sorted[i]}
+			} else if j-i == best {
+				modes = append(modes, sorted[i])
+			}
+			i = j
 		}
-		if len(numberList) == 0 {
-			return nil, nil
-		} else if len(numberList) == 1 {
-			return numberList[0], nil
-		}
+		return modes
+	}))
 
-		sort.Slice(numberList, func(i, j int) bool {
-			return numberList[i].Compare(*numberList[j]) == -1
-		})
+	prelude.Bind("percentile", NewNativeFunc(func(args map[string]any) (any, error) {
+		list, ok := numbersOf(args["list"])
+		p, isNumber := args["p"].(*Number)
+		if !ok || !isNumber {
+			return Null, nil
+		}
+		return numberOrNull(quantileOf(list, p.Div(N(100)))), nil
+	}).Required("list", "p"))
 
-		if len(numberList)%2 == 1 {
-			return numberList[len(numberList)/2], nil
-		} else {
-			medPos := (len(numberList) / 2)
-			return numberList[medPos+1].Add(numberList[medPos]).Mul(N("0.5")), nil
+	prelude.Bind("quantile", NewNativeFunc(func(args map[string]any) (any, error) {
+		list, ok := numbersOf(args["list"])
+		q, isNumber := args["q"].(*Number)
+		if !ok || !isNumber {
+			return Null, nil
 		}
-	}).Vararg("list"))
+		return numberOrNull(quantileOf(list, q)), nil
+	}).Required("list", "q"))
 
 	prelude.Bind("all", NewNativeFunc(func(args map[string]any) (any, error) {
 		list, err := extractList(args, "list")
 		if err != nil {
 			return nil, err
 		}
-		for _, v := range list {
-			if !boolValue(v) {
-				return false, nil
-			}
-		}
-		return true, nil
+		return allOf(list), nil
 	}).Vararg("list"))
 
 	prelude.Bind("and", NewNativeFunc(func(args map[string]any) (any, error) {
@@ -312,12 +469,7 @@ func installBuiltinFunctions(prelude *Prelude) {
 		if err != nil {
 			return nil, err
 		}
-		for _, v := range list {
-			if !boolValue(v) {
-				return false, nil
-			}
-		}
-		return true, nil
+		return allOf(list), nil
 	}).Vararg("list"))
 
 	prelude.Bind("any", NewNativeFunc(func(args map[string]any) (any, error) {
@@ -325,12 +477,7 @@ func installBuiltinFunctions(prelude *Prelude) {
 		if err != nil {
 			return nil, err
 		}
-		for _, v := range list {
-			if boolValue(v) {
-				return true, nil
-			}
-		}
-		return false, nil
+		return anyOf(list), nil
 	}).Vararg("list"))
 
 	prelude.Bind("or", NewNativeFunc(func(args map[string]any) (any, error) {
@@ -338,12 +485,7 @@ func installBuiltinFunctions(prelude *Prelude) {
 		if err != nil {
 			return nil, err
 		}
-		for _, v := range list {
-			if boolValue(v) {
-				return true, nil
-			}
-		}
-		return false, nil
+		return anyOf(list), nil
 	}).Vararg("list"))
 
 	prelude.Bind("sublist", NewNativeFunc(func(kwargs map[string]any) (any, error) {
@@ -509,7 +651,7 @@ func installBuiltinFunctions(prelude *Prelude) {
 	}).Required("list"))
 
 	prelude.Bind("sort", NewMacro(func(intp *Interpreter, args map[string]Node, varargs []Node) (any, error) {
-		vlist, err := args["list"].Eval(intp)
+		vlist, err := intp.EvalNode(args["list"])
 		if err != nil {
 			return nil, err
 		}
@@ -519,7 +661,7 @@ func installBuiltinFunctions(prelude *Prelude) {
 			return nil, NewErrTypeMismatch("list")
 		}
 
-		vpred, err := args["predicates"].Eval(intp)
+		vpred, err := intp.EvalNode(args["predicates"])
 		if err != nil {
 			return nil, err
 		}
@@ -538,7 +680,7 @@ func installBuiltinFunctions(prelude *Prelude) {
 				sortErr = err
 				return false
 			}
-			return boolValue(r)
+			return r == true
 		})
 		if sortErr != nil {
 			return nil, sortErr
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/superisaac/FEEL.go"
+)
+
+// batchLine is a line of the JSON Lines output of feel batch
+type batchLine struct {
+	Index    int            `json:"index"`
+	Result   any            `json:"result"`
+	Error    string         `json:"error,omitempty"`
+	Warnings []feel.Warning `json:"warnings,omitempty"`
+}
+
+func runBatch(args []string) {
+	cliFlags := flag.NewFlagSet("feel batch", flag.ExitOnError)
+	pCmdStr := cliFlags.String("c", "", "feel script as string")
+	pInput := cliFlags.String("i", "-", "JSON Lines input of the records, - for stdin")
+	pOutput := cliFlags.String("o", "-", "JSON Lines output of the results, - for stdout")
+	pWorkers := cliFlags.Int("workers", 0, "number of workers, the number of CPUs by default")
+	pUnordered := cliFlags.Bool("unordered", false, "write the results as soon as they are evaluated")
+	pStats := cliFlags.Bool("stats", false, "print the statistics of the batch to stderr")
+	pNull := cliFlags.Bool("null", false, "spec null semantics, runtime errors give null with warnings")
+	cliFlags.Parse(args)
+
+	if *pCmdStr == "" && cliFlags.NArg() <= 0 {
+		// stdin may carry the records
+		fmt.Fprintln(os.Stderr, "feel batch requires a script by -c or a file")
+		os.Exit(1)
+	}
+	prog, err := feel.CompileString(readInput(*pCmdStr, cliFlags))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error, %s\n", err)
+		os.Exit(1)
+	}
+
+	var input io.Reader = os.Stdin
+	if *pInput != "-" {
+		f, err := os.Open(*pInput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "input error, %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	var output io.Writer = os.Stdout
+	if *pOutput != "-" {
+		f, err := os.Create(*pOutput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "output error, %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		output = f
+	}
+	writer := bufio.NewWriter(output)
+	encoder := json.NewEncoder(writer)
+
+	opts := feel.BatchOptions{Workers: *pWorkers, Ordered: !*pUnordered, NullMode: *pNull}
+	stats, err := feel.EvalBatch(prog, feel.NewJSONLinesIterator(input), opts, func(res feel.BatchResult) {
+		line := batchLine{Index: res.Index, Result: res.Value, Warnings: res.Warnings}
+		if res.Err != nil {
+			line.Error = res.Err.Error()
+		}
+		if err := encoder.Encode(line); err != nil {
+			line = batchLine{Index: res.Index, Error: fmt.Sprintf("dump error, %s", err)}
+			encoder.Encode(line)
+		}
+	})
+	writer.Flush()
+	if *pStats {
+		fmt.Fprintln(os.Stderr, stats)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "input error, %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/superisaac/FEEL.go"
+)
+
+const debugHelp = `commands:
+  c, continue      run until the next breakpoint
+  s, step          step into the next node
+  n, next          step over the current node
+  o, out           step out of the parent node
+  p <expr>         evaluate the expression in the paused scope
+  w <expr>         watch the expression at every pause
+  b <breakpoint>   add breakpoint, as line:column, line or function name
+  d <breakpoint>   delete breakpoint
+  bl               list breakpoints
+  vars             show the bindings of the scope stack
+  q, quit          abort the evaluation
+  h, help          show this help`
+
+// parseBreakpoint parses line:column, line or a function name, lines
+// and columns start from 1
+func parseBreakpoint(spec string) feel.Breakpoint {
+	parts := strings.SplitN(spec, ":", 2)
+	row, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return feel.Breakpoint{FunctionName: spec}
+	}
+	if len(parts) == 1 {
+		return feel.Breakpoint{Row: row - 1, Column: -1}
+	}
+	col, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return feel.Breakpoint{FunctionName: spec}
+	}
+	return feel.Breakpoint{Row: row - 1, Column: col - 1}
+}
+
+func dumpValue(v any) string {
+	if s, err := feel.FormatValue(v); err == nil {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func runDebug(args []string) {
+	cliFlags := flag.NewFlagSet("feel debug", flag.ExitOnError)
+	pCmdStr := cliFlags.String("c", "", "feel script as string")
+	pVarsStr := cliFlags.String("vars", "", "context vars")
+	pBreaks := cliFlags.String("b", "", "comma separated breakpoints, as line:column, line or function name")
+	cliFlags.Parse(args)
+
+	if *pCmdStr == "" && cliFlags.NArg() <= 0 {
+		// stdin is used by the debug commands
+		fmt.Fprintln(os.Stderr, "feel debug requires a script by -c or a file")
+		os.Exit(1)
+	}
+	input := readInput(*pCmdStr, cliFlags)
+	ast, err := feel.ParseString(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error, %s\n", err)
+		os.Exit(1)
+	}
+	intp := newInterpreter(*pVarsStr, false)
+
+	var watches []string
+	stdin := bufio.NewScanner(os.Stdin)
+
+	debugger := feel.NewDebugger(nil)
+	debugger.StopOnEntry = *pBreaks == ""
+	if *pBreaks != "" {
+		for _, spec := range strings.Split(*pBreaks, ",") {
+			debugger.AddBreakpoint(parseBreakpoint(strings.TrimSpace(spec)))
+		}
+	}
+
+	debugger.OnPause = func(frame feel.DebugFrame) feel.DebugAction {
+		pos := frame.Node.TextRange().Start
+		reason := "step"
+		if frame.Breakpoint != nil {
+			reason = "breakpoint " + frame.Breakpoint.String()
+		}
+		fmt.Printf("paused at %d:%d by %s, depth %d\n  %s\n", pos.Row+1, pos.Column+1, reason, frame.Depth, frame.Node.Repr())
+		for _, expr := range watches {
+			if v, err := frame.Watch(expr); err != nil {
+				fmt.Printf("  watch %s: error, %s\n", expr, err)
+			} else {
+				fmt.Printf("  watch %s: %s\n", expr, dumpValue(v))
+			}
+		}
+
+		for {
+			fmt.Print("(feel) ")
+			if !stdin.Scan() {
+				fmt.Println()
+				return feel.DebugContinue
+			}
+			line := strings.TrimSpace(stdin.Text())
+			cmd, arg, _ := strings.Cut(line, " ")
+			arg = strings.TrimSpace(arg)
+			switch cmd {
+			case "c", "continue":
+				return feel.DebugContinue
+			case "s", "step":
+				return feel.DebugStepInto
+			case "n", "next":
+				return feel.DebugStepOver
+			case "o", "out":
+				return feel.DebugStepOut
+			case "q", "quit":
+				return feel.DebugAbort
+			case "p":
+				if v, err := frame.Watch(arg); err != nil {
+					fmt.Printf("error, %s\n", err)
+				} else {
+					fmt.Println(dumpValue(v))
+				}
+			case "w":
+				watches = append(watches, arg)
+			case "b":
+				debugger.AddBreakpoint(parseBreakpoint(arg))
+			case "d":
+				debugger.RemoveBreakpoint(parseBreakpoint(arg))
+			case "bl":
+				for _, bp := range debugger.Breakpoints() {
+					fmt.Println(bp)
+				}
+			case "vars":
+				bindings := frame.Bindings()
+				names := make([]string, 0, len(bindings))
+				for name := range bindings {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					fmt.Printf("%s = %s\n", name, dumpValue(bindings[name]))
+				}
+			case "":
+			default:
+				fmt.Println(debugHelp)
+			}
+		}
+	}
+	intp.AttachDebugger(debugger)
+
+	res, err := intp.EvalNode(ast)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eval error, %s\n", err)
+		os.Exit(1)
+	}
+	printResult(res, false)
+}
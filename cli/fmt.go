@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/superisaac/FEEL.go"
+)
+
+func runFmt(args []string) {
+	cliFlags := flag.NewFlagSet("feel fmt", flag.ExitOnError)
+	cliFlags.Usage = func() {
+		fmt.Fprintln(cliFlags.Output(), "usage: feel fmt [flags] [file ...]")
+		fmt.Fprintln(cliFlags.Output(), "print the scripts in the canonical form, comments are not kept")
+		cliFlags.PrintDefaults()
+	}
+	pCmdStr := cliFlags.String("c", "", "feel script as string")
+	pCheck := cliFlags.Bool("check", false, "list the scripts not in the canonical form and exit with 1 instead of printing them")
+	pIndent := cliFlags.Int("indent", 2, "number of spaces per indentation level")
+	pWidth := cliFlags.Int("width", 80, "line width beyond which expressions are broken into lines")
+	cliFlags.Parse(args)
+
+	opts := feel.FormatOptions{Indent: *pIndent, LineWidth: *pWidth}
+
+	type source struct {
+		name  string
+		input string
+	}
+	var sources []source
+	if *pCmdStr != "" {
+		sources = append(sources, source{name: "-c", input: *pCmdStr})
+	} else if cliFlags.NArg() <= 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "input error, %s\n", err)
+			os.Exit(1)
+		}
+		sources = append(sources, source{name: "<stdin>", input: string(data)})
+	} else {
+		for _, path := range cliFlags.Args() {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "input error, %s\n", err)
+				os.Exit(1)
+			}
+			sources = append(sources, source{name: path, input: string(data)})
+		}
+	}
+
+	failed := false
+	for _, src := range sources {
+		formatted, err := feel.FormatString(src.input, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "parse error, %s: %s\n", src.name, err)
+			failed = true
+			continue
+		}
+		formatted += "\n"
+		if *pCheck {
+			// the script given by -c needs no trailing newline
+			if formatted != src.input && !(src.name == "-c" && formatted == src.input+"\n") {
+				fmt.Println(src.name)
+				failed = true
+			}
+		} else {
+			fmt.Print(formatted)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -11,35 +11,34 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "debug":
+			runDebug(os.Args[2:])
+			return
+		case "batch":
+			runBatch(os.Args[2:])
+			return
+		case "fmt":
+			runFmt(os.Args[2:])
+			return
+		}
+	}
+
 	cliFlags := flag.NewFlagSet("feel", flag.ExitOnError)
 
 	pCmdStr := cliFlags.String("c", "", "feel script as string")
 	pVarsStr := cliFlags.String("vars", "", "context vars")
 	pDumpAST := cliFlags.Bool("ast", false, "dump ast tree only")
+	pExplain := cliFlags.Bool("explain", false, "print the explanation of the evaluation")
+	pProfile := cliFlags.String("profile", "", "write the pprof profile of the evaluation to the file, - for the text report only")
+	pProfileTop := cliFlags.Int("profile-top", 10, "number of entries in the text report of the profile")
+	pNull := cliFlags.Bool("null", false, "spec null semantics, runtime errors give null with warnings printed to stderr")
+	pLiteral := cliFlags.Bool("literal", false, "print the result as FEEL literal instead of JSON")
 
 	cliFlags.Parse(os.Args[1:])
 
-	input := *pCmdStr
-	if input == "" {
-
-		if cliFlags.NArg() <= 0 {
-			// fmt.Fprintln(os.Stderr, "no input file")
-			// os.Exit(1)
-			// read from stdin
-			reader := bufio.NewReader(os.Stdin)
-			data, err := io.ReadAll(reader)
-			if err != nil {
-				panic(err)
-			}
-			input = string(data)
-		} else {
-			data, err := os.ReadFile(cliFlags.Args()[0])
-			if err != nil {
-				panic(err)
-			}
-			input = string(data)
-		}
-	}
+	input := readInput(*pCmdStr, cliFlags)
 	if *pDumpAST {
 		ast, err := feel.ParseString(input)
 		if err != nil {
@@ -48,18 +47,136 @@ func main() {
 			// panic(err)
 		}
 		fmt.Println(ast.Repr())
+	} else if *pExplain {
+		intp := newInterpreter(*pVarsStr, *pNull)
+		ast, err := feel.ParseString(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "parse error, %s\n", err)
+			os.Exit(1)
+		}
+		tracer := intp.EnableTrace()
+		res, err := intp.EvalNode(ast)
+		fmt.Print(tracer.Explain())
+		printWarnings(intp)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "eval error, %s\n", err)
+			os.Exit(1)
+		}
+		printResult(res, *pLiteral)
+	} else if *pProfile != "" {
+		intp := newInterpreter(*pVarsStr, *pNull)
+		ast, err := feel.ParseString(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "parse error, %s\n", err)
+			os.Exit(1)
+		}
+		profiler := intp.EnableProfile()
+		res, err := intp.EvalNode(ast)
+		fmt.Fprint(os.Stderr, profiler.Top(*pProfileTop))
+		printWarnings(intp)
+		if *pProfile != "-" {
+			writeProfile(profiler, *pProfile)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "eval error, %s\n", err)
+			os.Exit(1)
+		}
+		printResult(res, *pLiteral)
+	} else if *pNull {
+		intp := newInterpreter(*pVarsStr, true)
+		ast, err := feel.ParseString(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "parse error, %s\n", err)
+			os.Exit(1)
+		}
+		res, err := intp.EvalNode(ast)
+		printWarnings(intp)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "eval error, %s\n", err)
+			os.Exit(1)
+		}
+		printResult(res, *pLiteral)
 	} else {
 		res, err := feel.EvalString(input, *pVarsStr)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "eval error, %s\n", err)
 			os.Exit(1)
 		}
-		bytes, err := json.MarshalIndent(res, "", "  ")
+		printResult(res, *pLiteral)
+	}
+}
+
+// readInput reads the script from the -c flag, the file given as
+// the first argument or stdin
+func readInput(cmdStr string, cliFlags *flag.FlagSet) string {
+	if cmdStr != "" {
+		return cmdStr
+	}
+	if cliFlags.NArg() <= 0 {
+		// read from stdin
+		reader := bufio.NewReader(os.Stdin)
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			panic(err)
+		}
+		return string(data)
+	} else {
+		data, err := os.ReadFile(cliFlags.Args()[0])
+		if err != nil {
+			panic(err)
+		}
+		return string(data)
+	}
+}
+
+func newInterpreter(vars string, nullMode bool) *feel.Interpreter {
+	intp := feel.NewIntepreter()
+	if nullMode {
+		intp.EnableNullMode()
+	}
+	if vars != "" {
+		if err := intp.PushVars(vars); err != nil {
+			fmt.Fprintf(os.Stderr, "vars error, %s\n", err)
+			os.Exit(1)
+		}
+	}
+	return intp
+}
+
+func printWarnings(intp *feel.Interpreter) {
+	for _, warning := range intp.Warnings() {
+		fmt.Fprintf(os.Stderr, "warning %s\n", warning)
+	}
+}
+
+func writeProfile(profiler *feel.Profiler, path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "profile error, %s\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	if err := profiler.WritePprof(f); err != nil {
+		fmt.Fprintf(os.Stderr, "profile error, %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func printResult(res any, literal bool) {
+	if literal {
+		s, err := feel.FormatValue(res)
 		if err != nil {
-			//panic(err)
 			fmt.Fprintf(os.Stderr, "dump error, %s\n", err)
 			os.Exit(1)
 		}
-		fmt.Println(string(bytes))
+		fmt.Println(s)
+		return
+	}
+	bytes, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		//panic(err)
+		fmt.Fprintf(os.Stderr, "dump error, %s\n", err)
+		os.Exit(1)
 	}
+	fmt.Println(string(bytes))
 }
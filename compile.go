@@ -0,0 +1,474 @@
+package feel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// opcode is the operation of a VM instruction
+type opcode uint8
+
+const (
+	// push consts[arg]
+	opConst opcode = iota
+	// push locals[arg]
+	opLoadLocal
+	// set locals[arg] to the top of the stack, the value is kept
+	opSetLocal
+	// push the value of names[arg], the name is resolved once per run
+	opLoadGlobal
+	// pop the right and left operands, push binops[arg](left, right)
+	opBinary
+	// pop a value, push its attribute names[arg]
+	opDot
+	// jump to arg if the left operand of and on the top is false
+	opAnd
+	// jump to arg if the left operand of or on the top is true
+	opOr
+	// jump to arg
+	opJump
+	// pop a value, jump to arg unless it's true
+	opJumpUnlessTrue
+	// pop the result of a unary test, the result of the unary tests on
+	// the top becomes true and jumps to arg when the test is true, or
+	// null when the test is null
+	opTest
+	// pop arg values, push the list of them
+	opList
+	// pop the end and start, push a range, arg holds the open flags
+	opRange
+	// pop the entry values of contexts[arg], push the context
+	opContext
+	// pop a list, push an iterator over it, on failures jump to arg2
+	opIter
+	// set locals[arg] to the next item of the iterator, jump to arg2 at the end
+	opNext
+	// pop a value and append it to the results of the iterator
+	opCollect
+	// pop a value, append the current item of the iterator if it's truthy
+	opFilter
+	// pop a value, if it's truthy replace the iterator by the current
+	// item and jump to arg2
+	opFind
+	// replace the iterator by its results
+	opEndIter
+	// replace the iterator by nil, as some expressions find nothing
+	opEndFind
+	// push a function defined by fundefs[arg]
+	opFunDef
+	// check the callee of calls[arg], macros are expanded by the tree
+	// walker and jump to arg2
+	opPrepareCall
+	// pop the arguments and the callee of calls[arg], push the result
+	opCall
+	// evaluate nodes[arg] by the tree walker
+	opEval
+)
+
+var opcodeNames = map[opcode]string{
+	opConst:          "const",
+	opLoadLocal:      "load_local",
+	opSetLocal:       "set_local",
+	opLoadGlobal:     "load_global",
+	opBinary:         "binary",
+	opDot:            "dot",
+	opAnd:            "and",
+	opOr:             "or",
+	opJump:           "jump",
+	opJumpUnlessTrue: "jump_unless_true",
+	opTest:           "test",
+	opList:           "list",
+	opRange:          "range",
+	opContext:        "context",
+	opIter:           "iter",
+	opNext:           "next",
+	opCollect:        "collect",
+	opFilter:         "filter",
+	opFind:           "find",
+	opEndIter:        "end_iter",
+	opEndFind:        "end_find",
+	opFunDef:         "fundef",
+	opPrepareCall:    "prepare_call",
+	opCall:           "call",
+	opEval:           "eval",
+}
+
+func (op opcode) String() string {
+	return opcodeNames[op]
+}
+
+type instruction struct {
+	op   opcode
+	arg  int
+	arg2 int
+	// the node of instructions which may fail, for the warnings of
+	// the null mode
+	node Node
+}
+
+const (
+	rangeStartOpen = 1 << iota
+	rangeEndOpen
+)
+
+// localVar is a name bound inside the expression and its slot
+type localVar struct {
+	name string
+	slot int
+}
+
+type binarySite struct {
+	op string
+	fn binaryFunc
+	// whether null operands give null in the null mode
+	nullPropagates bool
+}
+
+type callSite struct {
+	node *FunCall
+	argc int
+	// keyword argument names, nil for positional arguments
+	argNames []string
+	// the locals visible to functions and macros evaluated by the tree walker
+	visible []localVar
+}
+
+type evalSite struct {
+	node    Node
+	visible []localVar
+}
+
+// Program is an expression compiled into instructions of a stack
+// based VM, the names bound inside the expression are kept in slots
+// and the other names are resolved once per run. A program is
+// immutable and can be run concurrently by different interpreters.
+type Program struct {
+	node  Node
+	code  []instruction
+	nLocs int
+
+	consts   []any
+	names    []string
+	binops   []binarySite
+	contexts [][]string
+	fundefs  []*FunDef
+	calls    []*callSite
+	nodes    []*evalSite
+}
+
+// Compile compiles the AST into a program giving the same results as
+// evaluating the AST, parts which cannot be compiled such as the
+// macros and the bodies of functions are evaluated by the tree walker
+func Compile(node Node) *Program {
+	c := &compiler{prog: &Program{node: node}, globals: make(map[string]int)}
+	c.compile(node)
+	return c.prog
+}
+
+// CompileString parses and compiles the input
+func CompileString(input string) (*Program, error) {
+	ast, err := ParseString(input)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(ast), nil
+}
+
+// Node returns the AST the program is compiled from
+func (prog *Program) Node() Node {
+	return prog.node
+}
+
+// String disassembles the program
+func (prog *Program) String() string {
+	var sb strings.Builder
+	for pc, inst := range prog.code {
+		fmt.Fprintf(&sb, "%4d %-13s", pc, inst.op)
+		switch inst.op {
+		case opConst:
+			fmt.Fprintf(&sb, " %s", constRepr(prog.consts[inst.arg]))
+		case opLoadLocal, opSetLocal:
+			fmt.Fprintf(&sb, " %d", inst.arg)
+		case opLoadGlobal, opDot:
+			fmt.Fprintf(&sb, " %s", prog.names[inst.arg])
+		case opBinary:
+			fmt.Fprintf(&sb, " %s", prog.binops[inst.arg].op)
+		case opAnd, opOr, opJump, opJumpUnlessTrue, opTest:
+			fmt.Fprintf(&sb, " -> %d", inst.arg)
+		case opList, opRange:
+			fmt.Fprintf(&sb, " %d", inst.arg)
+		case opContext:
+			fmt.Fprintf(&sb, " [%s]", strings.Join(prog.contexts[inst.arg], ", "))
+		case opNext:
+			fmt.Fprintf(&sb, " %d -> %d", inst.arg, inst.arg2)
+		case opFind:
+			fmt.Fprintf(&sb, " -> %d", inst.arg2)
+		case opFunDef:
+			fmt.Fprintf(&sb, " %s", prog.fundefs[inst.arg].Repr())
+		case opPrepareCall:
+			fmt.Fprintf(&sb, " %s -> %d", prog.calls[inst.arg].node.FunRef.Repr(), inst.arg2)
+		case opCall:
+			fmt.Fprintf(&sb, " %s %d", prog.calls[inst.arg].node.FunRef.Repr(), prog.calls[inst.arg].argc)
+		case opEval:
+			fmt.Fprintf(&sb, " %s", prog.nodes[inst.arg].node.Repr())
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+type compiler struct {
+	prog    *Program
+	scopes  []map[string]int
+	globals map[string]int
+}
+
+func (c *compiler) emit(op opcode, arg int) int {
+	c.prog.code = append(c.prog.code, instruction{op: op, arg: arg})
+	return len(c.prog.code) - 1
+}
+
+// emitFor emits an instruction which may fail evaluating the node
+func (c *compiler) emitFor(node Node, op opcode, arg int) int {
+	c.prog.code = append(c.prog.code, instruction{op: op, arg: arg, node: node})
+	return len(c.prog.code) - 1
+}
+
+// patch sets the jump target of the instruction at pc to the next instruction
+func (c *compiler) patch(pc int) {
+	switch c.prog.code[pc].op {
+	case opIter, opNext, opFind, opPrepareCall:
+		c.prog.code[pc].arg2 = len(c.prog.code)
+	default:
+		c.prog.code[pc].arg = len(c.prog.code)
+	}
+}
+
+func (c *compiler) emitConst(v any) {
+	c.prog.consts = append(c.prog.consts, v)
+	c.emit(opConst, len(c.prog.consts)-1)
+}
+
+func (c *compiler) nameIndex(name string) int {
+	c.prog.names = append(c.prog.names, name)
+	return len(c.prog.names) - 1
+}
+
+func (c *compiler) pushScope() {
+	c.scopes = append(c.scopes, make(map[string]int))
+}
+
+func (c *compiler) popScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+// bind allocates a slot for the name in the innermost scope
+func (c *compiler) bind(name string) int {
+	slot := c.prog.nLocs
+	c.prog.nLocs++
+	c.scopes[len(c.scopes)-1][name] = slot
+	return slot
+}
+
+func (c *compiler) resolveLocal(name string) (int, bool) {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if slot, ok := c.scopes[i][name]; ok {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+// visible returns the locals in scope, the inner ones shadow the outer ones
+func (c *compiler) visible() []localVar {
+	var vars []localVar
+	seen := make(map[string]bool)
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		for name, slot := range c.scopes[i] {
+			if !seen[name] {
+				seen[name] = true
+				vars = append(vars, localVar{name: name, slot: slot})
+			}
+		}
+	}
+	return vars
+}
+
+// fallback evaluates the node by the tree walker
+func (c *compiler) fallback(node Node) {
+	c.prog.nodes = append(c.prog.nodes, &evalSite{node: node, visible: c.visible()})
+	c.emit(opEval, len(c.prog.nodes)-1)
+}
+
+func (c *compiler) compile(node Node) {
+	switch v := node.(type) {
+	case *NumberNode:
+		c.emitConst(NewNumber(v.Value))
+	case *StringNode:
+		c.emitConst(v.Content())
+	case *BoolNode:
+		c.emitConst(v.Value)
+	case *NullNode:
+		c.emitConst(Null)
+	case *EmptyNode:
+		c.emitConst(nil)
+	case *ConstNode:
+		c.emitConst(v.Value)
+	case *TemporalNode:
+		if value, err := ParseTemporalValue(v.Content()); err == nil {
+			c.emitConst(value)
+		} else {
+			// the error is raised at runtime
+			c.fallback(v)
+		}
+	case *Var:
+		if slot, ok := c.resolveLocal(v.Name); ok {
+			c.emit(opLoadLocal, slot)
+		} else {
+			idx, ok := c.globals[v.Name]
+			if !ok {
+				idx = c.nameIndex(v.Name)
+				c.globals[v.Name] = idx
+			}
+			c.emit(opLoadGlobal, idx)
+		}
+	case *Binop:
+		c.compileBinop(v)
+	case *DotOp:
+		c.compile(v.Left)
+		c.emitFor(v, opDot, c.nameIndex(v.Attr))
+	case *IfExpr:
+		c.compile(v.Cond)
+		elseJump := c.emit(opJumpUnlessTrue, 0)
+		c.compile(v.ThenBranch)
+		endJump := c.emit(opJump, 0)
+		c.patch(elseJump)
+		c.compile(v.ElseBranch)
+		c.patch(endJump)
+	case *ArrayNode:
+		for _, elem := range v.Elements {
+			c.compile(elem)
+		}
+		c.emit(opList, len(v.Elements))
+	case *RangeNode:
+		c.compile(v.Start)
+		c.compile(v.End)
+		flags := 0
+		if v.StartOpen {
+			flags |= rangeStartOpen
+		}
+		if v.EndOpen {
+			flags |= rangeEndOpen
+		}
+		c.emit(opRange, flags)
+	case *MultiTests:
+		c.emitConst(false)
+		var tests []int
+		for _, elem := range v.Elements {
+			c.compile(elem)
+			tests = append(tests, c.emitFor(elem, opTest, 0))
+		}
+		for _, pc := range tests {
+			c.patch(pc)
+		}
+	case *MapNode:
+		// an entry is visible to the entries after it
+		c.pushScope()
+		names := make([]string, 0, len(v.Values))
+		for _, item := range v.Values {
+			c.compile(item.Value)
+			c.emit(opSetLocal, c.bind(item.Name))
+			names = append(names, item.Name)
+		}
+		c.popScope()
+		c.prog.contexts = append(c.prog.contexts, names)
+		c.emit(opContext, len(c.prog.contexts)-1)
+	case *ForExpr:
+		c.compileLoop(v, v.Varname, v.ListExpr, v.ReturnExpr, opCollect)
+	case *SomeExpr:
+		c.compileLoop(v, v.Varname, v.ListExpr, v.FilterExpr, opFind)
+	case *EveryExpr:
+		c.compileLoop(v, v.Varname, v.ListExpr, v.FilterExpr, opFilter)
+	case *FunDef:
+		c.prog.fundefs = append(c.prog.fundefs, v)
+		c.emit(opFunDef, len(c.prog.fundefs)-1)
+	case *FunCall:
+		c.compileCall(v)
+	default:
+		c.fallback(node)
+	}
+}
+
+func (c *compiler) compileBinop(binop *Binop) {
+	switch binop.Op {
+	case "and", "or":
+		c.compile(binop.Left)
+		op, fn := opAnd, logicalAnd
+		if binop.Op == "or" {
+			op, fn = opOr, logicalOr
+		}
+		jump := c.emit(op, 0)
+		c.compile(binop.Right)
+		c.prog.binops = append(c.prog.binops, binarySite{op: binop.Op, fn: fn})
+		c.emit(opBinary, len(c.prog.binops)-1)
+		c.patch(jump)
+		return
+	}
+	fn, ok := binaryFuncs[binop.Op]
+	if !ok {
+		// the error is raised at runtime
+		c.fallback(binop)
+		return
+	}
+	c.compile(binop.Left)
+	c.compile(binop.Right)
+	c.prog.binops = append(c.prog.binops, binarySite{op: binop.Op, fn: fn, nullPropagates: nullPropagates(binop.Op)})
+	c.emitFor(binop, opBinary, len(c.prog.binops)-1)
+}
+
+// compileLoop compiles for, some and every expressions, which differ
+// in how the value of the body is handled by the op
+func (c *compiler) compileLoop(node Node, varname string, listExpr, body Node, op opcode) {
+	c.compile(listExpr)
+	iter := c.emitFor(node, opIter, 0)
+	c.pushScope()
+	slot := c.bind(varname)
+	loop := c.emit(opNext, slot)
+	c.compile(body)
+	var found int
+	if op == opFind {
+		found = c.emit(opFind, 0)
+	} else {
+		c.emit(op, 0)
+	}
+	c.emit(opJump, loop)
+	c.popScope()
+	c.patch(loop)
+	if op == opFind {
+		c.emit(opEndFind, 0)
+		c.patch(found)
+	} else {
+		c.emit(opEndIter, 0)
+	}
+	c.patch(iter)
+}
+
+func (c *compiler) compileCall(node *FunCall) {
+	site := &callSite{node: node, argc: len(node.Args), visible: c.visible()}
+	if node.KeywordArgs {
+		site.argNames = make([]string, len(node.Args))
+		for i, arg := range node.Args {
+			site.argNames[i] = arg.Name
+		}
+	}
+	c.prog.calls = append(c.prog.calls, site)
+	idx := len(c.prog.calls) - 1
+
+	c.compile(node.FunRef)
+	prepare := c.emitFor(node, opPrepareCall, idx)
+	for _, arg := range node.Args {
+		c.compile(arg.Arg)
+	}
+	c.emitFor(node, opCall, idx)
+	c.patch(prepare)
+}
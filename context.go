@@ -1,23 +1,29 @@
 package feel
 
-func contextGetByKeys(ctx map[string]any, keys []string) (any, bool) {
+// contextGetByKeys gets the value by the path of keys, lazy contexts
+// on the path resolve the keys
+func contextGetByKeys(ctx any, keys []string) (any, bool, error) {
 	for i, key := range keys {
-		if i == len(keys)-1 {
-			v, ok := ctx[key]
-			return v, ok
-		} else {
-			v, ok := ctx[key]
-			if !ok {
-				return nil, false
-			}
-			if subctx, ok := v.(map[string]any); ok {
-				ctx = subctx
-			} else {
-				return nil, false
+		var v any
+		var ok bool
+		switch c := ctx.(type) {
+		case map[string]any:
+			v, ok = c[key]
+		case *lazyContext:
+			var err error
+			if v, ok, err = c.Resolve(key); err != nil {
+				return nil, false, err
 			}
 		}
+		if !ok {
+			return nil, false, nil
+		}
+		if i == len(keys)-1 {
+			return v, true, nil
+		}
+		ctx = v
 	}
-	return nil, false
+	return nil, false, nil
 }
 
 func contextProbePut(ctx map[string]any, keys []string) bool {
@@ -81,39 +87,42 @@ func contextPutKeys(ctx map[string]any, keys []string, value any) (map[string]an
 func installContextFunctions(prelude *Prelude) {
 	// context/map functions
 	prelude.Bind("get value", NewNativeFunc(func(kwargs map[string]any) (any, error) {
+		switch kwargs["context"].(type) {
+		case map[string]any, *lazyContext:
+		default:
+			return nil, NewErrTypeMismatch("context")
+		}
+
 		type getvalueByKey struct {
-			Context map[string]any `json:"context"`
-			Key     string         `json:"key"`
+			Key string `json:"key"`
 		}
 
 		type getvalueByKeys struct {
-			Context map[string]any `json:"context"`
-			Keys    []string       `json:"key"`
+			Keys []string `json:"key"`
 		}
 
+		var keys []string
 		argsByKey := getvalueByKey{}
-
 		if err := decodeKWArgs(kwargs, &argsByKey); err != nil {
 			argsByKeys := getvalueByKeys{}
 			if err := decodeKWArgs(kwargs, &argsByKeys); err != nil {
 				return nil, err
 			}
+			keys = argsByKeys.Keys
+		} else {
+			keys = []string{argsByKey.Key}
+		}
 
-			if v, ok := contextGetByKeys(argsByKeys.Context, argsByKeys.Keys); ok {
-				return v, nil
-			} else {
-				return Null, nil
-			}
+		if v, ok, err := contextGetByKeys(kwargs["context"], keys); err != nil {
+			return nil, err
+		} else if ok {
+			return v, nil
 		} else {
-			if v, ok := argsByKey.Context[argsByKey.Key]; ok {
-				return v, nil
-			} else {
-				return Null, nil
-			}
+			return Null, nil
 		}
 	}).Required("context", "key"))
 
-	prelude.Bind("get entries", wrapTyped(func(ctx map[string]any) ([](map[string]any), error) {
+	prelude.Bind("get entries", materializing(wrapTyped(func(ctx map[string]any) ([](map[string]any), error) {
 		entries := make([](map[string]any), 0)
 		for k, v := range ctx {
 			ent := map[string]any{
@@ -123,9 +132,9 @@ func installContextFunctions(prelude *Prelude) {
 			entries = append(entries, ent)
 		}
 		return entries, nil
-	}).Required("context"))
+	}).Required("context")))
 
-	prelude.Bind("context put", NewNativeFunc(func(kwargs map[string]any) (any, error) {
+	prelude.Bind("context put", materializing(NewNativeFunc(func(kwargs map[string]any) (any, error) {
 		type putByKey struct {
 			Context map[string]any `json:"context"`
 			Key     string         `json:"key"`
@@ -151,9 +160,9 @@ func installContextFunctions(prelude *Prelude) {
 			ctx, _ := contextPutKeys(argsByKey.Context, []string{argsByKey.Key}, argsByKey.Value)
 			return ctx, nil
 		}
-	}).Required("context", "key", "value"))
+	}).Required("context", "key", "value")))
 
-	prelude.Bind("context merge", wrapTyped(func(contexts []map[string]any) (map[string]any, error) {
+	prelude.Bind("context merge", materializing(wrapTyped(func(contexts []map[string]any) (map[string]any, error) {
 		merged := make(map[string]any)
 		for _, ctx := range contexts {
 			for k, v := range ctx {
@@ -161,6 +170,48 @@ func installContextFunctions(prelude *Prelude) {
 			}
 		}
 		return merged, nil
-	}).Required("contextx"))
+	}).Required("contextx")))
+}
 
+// materializing replaces the lazy contexts in the arguments, also
+// those in list arguments, with all their entries
+func materializing(nfun *NativeFun) *NativeFun {
+	fn := nfun.fn
+	nfun.fn = func(args map[string]any) (any, error) {
+		for name, arg := range args {
+			v, err := materialize(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[name] = v
+		}
+		return fn(args)
+	}
+	return nfun
+}
+
+func materialize(v any) (any, error) {
+	switch vv := v.(type) {
+	case *lazyContext:
+		return vv.materialize()
+	case []any:
+		var list []any
+		for i, elem := range vv {
+			if lazy, ok := elem.(*lazyContext); ok {
+				if list == nil {
+					list = make([]any, len(vv))
+					copy(list, vv)
+				}
+				ctx, err := lazy.materialize()
+				if err != nil {
+					return nil, err
+				}
+				list[i] = ctx
+			}
+		}
+		if list != nil {
+			return list, nil
+		}
+	}
+	return v, nil
 }
@@ -0,0 +1,407 @@
+package feel
+
+// conversion from arbitrary go values into FEEL values
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	durationType     = reflect.TypeOf(time.Duration(0))
+	jsonNumberType   = reflect.TypeOf(json.Number(""))
+	bigFloatType     = reflect.TypeOf(big.Float{})
+	bigIntType       = reflect.TypeOf(big.Int{})
+	textMarshalerTyp = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	hasAttrsType     = reflect.TypeOf((*HasAttrs)(nil)).Elem()
+)
+
+// ErrReferenceCycle is returned converting go values which contain
+// themselves, such as a map holding itself as a value
+var ErrReferenceCycle = errors.New("reference cycle in value")
+
+// ToFEELValue converts a go value into the value types FEEL
+// evaluates with, structs (honouring `feel` and `json` tags) and
+// maps become contexts, slices and arrays become lists, all numeric
+// kinds become *Number, time.Time and time.Duration become temporal
+// values, nil and nil pointers become null. Reference cycles are cut
+// with null, ConvertValue reports them as errors.
+func ToFEELValue(v any) any {
+	r, _ := ConvertValue(v)
+	return r
+}
+
+// ConvertValue converts a go value like ToFEELValue, values containing
+// themselves fail with ErrReferenceCycle
+func ConvertValue(v any) (any, error) {
+	conv := &converter{seen: make(map[refKey]bool)}
+	r, _ := conv.convert(v)
+	return r, conv.err
+}
+
+// ParseJSON decodes the JSON document into FEEL values, numbers are
+// decoded exactly as decimals without going through float64
+func ParseJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after the JSON document")
+	}
+	return ToFEELValue(v), nil
+}
+
+type converter struct {
+	// pointers, maps and slices on the current path, to break
+	// reference cycles
+	seen map[refKey]bool
+	err  error
+	// the lazy contexts made of resolvers
+	lazies []*lazyContext
+}
+
+// refKey identifies a pointer, a map or a slice, slices of the same
+// array differ by their lengths
+type refKey struct {
+	kind reflect.Kind
+	ptr  uintptr
+	len  int
+}
+
+// enter marks the reference as on the current path, false if it's
+// already on it
+func (conv *converter) enter(rv reflect.Value) (refKey, bool) {
+	key := refKey{kind: rv.Kind(), ptr: rv.Pointer()}
+	if rv.Kind() == reflect.Slice {
+		key.len = rv.Len()
+	}
+	if conv.seen[key] {
+		if conv.err == nil {
+			conv.err = fmt.Errorf("%w, %s", ErrReferenceCycle, rv.Type())
+		}
+		return key, false
+	}
+	conv.seen[key] = true
+	return key, true
+}
+
+// convert returns the converted value and whether it differs from the input
+func (conv *converter) convert(v any) (any, bool) {
+	switch vv := v.(type) {
+	case nil:
+		return Null, true
+	case *Number, *NullValue, bool, string,
+		*FEELDate, *FEELTime, *FEELDatetime, *FEELDuration,
+		*RangeValue, *NativeFun, *Macro, *FunDef:
+		if rv := reflect.ValueOf(vv); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return Null, true
+		}
+		return vv, false
+	case int:
+		return NewNumberFromInt64(int64(vv)), true
+	case int64:
+		return NewNumberFromInt64(vv), true
+	case float64:
+		return conv.convertFloat(vv, 64), true
+	case []any:
+		return conv.convertList(vv)
+	case map[string]any:
+		return conv.convertMap(vv)
+	case Scope:
+		r, _ := conv.convertMap(map[string]any(vv))
+		return r, true
+	case *lazyContext:
+		return vv, false
+	case Resolver:
+		// lazy contexts cache what they resolve
+		lazy := newLazyContext(vv)
+		conv.lazies = append(conv.lazies, lazy)
+		return lazy, true
+	case Number:
+		return &vv, true
+	case NullValue:
+		return Null, true
+	case FEELDate:
+		return &vv, true
+	case FEELTime:
+		return &vv, true
+	case FEELDatetime:
+		return &vv, true
+	case FEELDuration:
+		return &vv, true
+	case RangeValue:
+		return &vv, true
+	}
+	return conv.convertReflect(reflect.ValueOf(v))
+}
+
+func (conv *converter) convertList(list []any) (any, bool) {
+	if len(list) == 0 {
+		return list, false
+	}
+	key, ok := conv.enter(reflect.ValueOf(list))
+	if !ok {
+		return Null, true
+	}
+	defer delete(conv.seen, key)
+	var converted []any
+	for i, elem := range list {
+		r, changed := conv.convert(elem)
+		if changed && converted == nil {
+			converted = make([]any, len(list))
+			copy(converted, list[:i])
+		}
+		if converted != nil {
+			converted[i] = r
+		}
+	}
+	if converted == nil {
+		return list, false
+	}
+	return converted, true
+}
+
+func (conv *converter) convertMap(ctx map[string]any) (any, bool) {
+	if len(ctx) == 0 {
+		return ctx, false
+	}
+	key, ok := conv.enter(reflect.ValueOf(ctx))
+	if !ok {
+		return Null, true
+	}
+	defer delete(conv.seen, key)
+	var converted map[string]any
+	for k, elem := range ctx {
+		r, changed := conv.convert(elem)
+		if changed {
+			if converted == nil {
+				converted = contextCopy(ctx)
+			}
+			converted[k] = r
+		}
+	}
+	if converted == nil {
+		return ctx, false
+	}
+	return converted, true
+}
+
+func (conv *converter) convertFloat(f float64, bitSize int) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Null
+	}
+	if bitSize == 32 {
+		// format with float32 precision so 0.1 stays 0.1
+		return NewNumber(strconv.FormatFloat(f, 'g', -1, 32))
+	}
+	return NewNumberFromFloat(f)
+}
+
+func (conv *converter) convertReflect(rv reflect.Value) (any, bool) {
+	tp := rv.Type()
+
+	// types with special meanings, checked before their kinds
+	switch tp {
+	case timeType:
+		return &FEELDatetime{t: rv.Interface().(time.Time)}, true
+	case durationType:
+		d := time.Duration(rv.Int())
+		if d < 0 {
+			return NewFEELDuration(-d).Negative(), true
+		}
+		return NewFEELDuration(d), true
+	case jsonNumberType:
+		n, err := ParseNumberWithErr(rv.String())
+		if err != nil {
+			return Null, true
+		}
+		return n, true
+	case bigFloatType:
+		f := rv.Interface().(big.Float)
+		return NewNumber(f.Text('g', -1)), true
+	case bigIntType:
+		i := rv.Interface().(big.Int)
+		return NewNumber(i.String()), true
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return Null, true
+		}
+		if rv.Kind() == reflect.Ptr {
+			key, ok := conv.enter(rv)
+			if !ok {
+				return Null, true
+			}
+			defer delete(conv.seen, key)
+			if tp.Implements(hasAttrsType) {
+				return rv.Interface(), false
+			}
+		}
+		r, _ := conv.convert(rv.Elem().Interface())
+		return r, true
+	}
+
+	if tp.Implements(hasAttrsType) {
+		// objects serving attributes by themselves
+		return rv.Interface(), false
+	}
+	if tp.Implements(textMarshalerTyp) {
+		text, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return Null, true
+		}
+		return string(text), true
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), true
+	case reflect.String:
+		return rv.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewNumberFromInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewNumber(strconv.FormatUint(rv.Uint(), 10)), true
+	case reflect.Float32:
+		return conv.convertFloat(rv.Float(), 32), true
+	case reflect.Float64:
+		return conv.convertFloat(rv.Float(), 64), true
+	case reflect.Slice:
+		if rv.IsNil() {
+			return Null, true
+		}
+		key, ok := conv.enter(rv)
+		if !ok {
+			return Null, true
+		}
+		defer delete(conv.seen, key)
+		return conv.convertSequence(rv), true
+	case reflect.Array:
+		return conv.convertSequence(rv), true
+	case reflect.Map:
+		if rv.IsNil() {
+			return Null, true
+		}
+		key, ok := conv.enter(rv)
+		if !ok {
+			return Null, true
+		}
+		defer delete(conv.seen, key)
+		return conv.convertReflectMap(rv), true
+	case reflect.Struct:
+		ctx := make(map[string]any)
+		conv.collectFields(rv, ctx)
+		return ctx, true
+	default:
+		// functions, channels and complex numbers are kept as they are
+		return rv.Interface(), false
+	}
+}
+
+func (conv *converter) convertSequence(rv reflect.Value) []any {
+	list := make([]any, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		list[i], _ = conv.convert(rv.Index(i).Interface())
+	}
+	return list
+}
+
+func (conv *converter) convertReflectMap(rv reflect.Value) map[string]any {
+	ctx := make(map[string]any)
+	iter := rv.MapRange()
+	for iter.Next() {
+		key := mapKeyString(iter.Key())
+		ctx[key], _ = conv.convert(iter.Value().Interface())
+	}
+	return ctx
+}
+
+func mapKeyString(key reflect.Value) string {
+	if key.Kind() == reflect.Interface && !key.IsNil() {
+		key = key.Elem()
+	}
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	if key.Type().Implements(textMarshalerTyp) {
+		if text, err := key.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(key.Interface())
+}
+
+// fieldName returns the context key of a struct field, tags `feel`
+// take precedence over `json`
+func fieldName(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag, ok := field.Tag.Lookup("feel")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+func (conv *converter) collectFields(rv reflect.Value, ctx map[string]any) {
+	tp := rv.Type()
+	for i := 0; i < tp.NumField(); i++ {
+		field := tp.Field(i)
+		name, omitEmpty, skip := fieldName(field)
+		if skip {
+			continue
+		}
+		fv := rv.Field(i)
+		if field.Anonymous && name == "" {
+			// embedded structs are flattened
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && !fv.Type().Implements(hasAttrsType) {
+				conv.collectFields(fv, ctx)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if !fv.CanInterface() {
+			// exported fields promoted from unexported embedded structs
+			continue
+		}
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		ctx[name], _ = conv.convert(fv.Interface())
+	}
+}
@@ -0,0 +1,152 @@
+package feel
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAddress struct {
+	Zip    string `json:"zip"`
+	Street string `feel:"street" json:"streetName"`
+}
+
+type testApplicant struct {
+	Name     string            `json:"name"`
+	Age      int32             `json:"age"`
+	Income   float32           `json:"income"`
+	Score    uint64            `json:"score"`
+	Address  *testAddress      `json:"address"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Birthday time.Time         `json:"birthday"`
+	Tenure   time.Duration     `json:"tenure"`
+	Secret   string            `json:"-"`
+	Note     string            `json:"note,omitempty"`
+	internal int
+}
+
+type testNode struct {
+	Name string    `json:"name"`
+	Next *testNode `json:"next"`
+}
+
+func TestConvertGoValues(t *testing.T) {
+	assert := assert.New(t)
+
+	applicant := testApplicant{
+		Name:     "alice",
+		Age:      30,
+		Income:   1234.5,
+		Score:    18446744073709551615,
+		Address:  &testAddress{Zip: "10001", Street: "5th Ave"},
+		Tags:     []string{"gold", "vip"},
+		Labels:   map[string]string{"region": "east"},
+		Birthday: time.Date(1993, 4, 5, 0, 0, 0, 0, time.UTC),
+		Tenure:   36 * time.Hour,
+		Secret:   "hidden",
+	}
+
+	v := ToFEELValue(applicant)
+	ctx, ok := v.(map[string]any)
+	assert.True(ok)
+	assert.Equal("alice", ctx["name"])
+	assert.True(N(30).Equal(*ctx["age"].(*Number)))
+	assert.True(N("1234.5").Equal(*ctx["income"].(*Number)))
+	assert.Equal(N("18446744073709551615").String(), ctx["score"].(*Number).String())
+	assert.Equal([]any{"gold", "vip"}, ctx["tags"])
+	assert.Equal(map[string]any{"region": "east"}, ctx["labels"])
+	assert.Equal(map[string]any{"zip": "10001", "street": "5th Ave"}, ctx["address"])
+	assert.Equal(36*time.Hour, ctx["tenure"].(*FEELDuration).Duration())
+	assert.Equal(1993, ctx["birthday"].(*FEELDatetime).Time().Year())
+	_, hasSecret := ctx["Secret"]
+	assert.False(hasSecret)
+	_, hasNote := ctx["note"]
+	assert.False(hasNote)
+	_, hasInternal := ctx["internal"]
+	assert.False(hasInternal)
+
+	res, err := EvalStringWithScope(`applicant.address.zip = "10001" and applicant.age > 18 and applicant.tags[1] = "gold"`, Scope{"applicant": &applicant})
+	assert.Nil(err)
+	assert.Equal(true, res)
+
+	res, err = EvalStringWithScope(`a + b`, Scope{"a": json.Number("0.25"), "b": int8(2)})
+	assert.Nil(err)
+	assert.True(N("2.25").Equal(*res.(*Number)))
+
+	var nilPtr *testAddress
+	assert.Equal(Null, ToFEELValue(nilPtr))
+	assert.Equal(Null, ToFEELValue(nil))
+
+	// reference cycles are cut with null
+	loop := &testNode{Name: "a"}
+	loop.Next = loop
+	lv := ToFEELValue(loop).(map[string]any)
+	assert.Equal("a", lv["name"])
+	assert.Equal(Null, lv["next"])
+	_, err = ConvertValue(loop)
+	assert.ErrorIs(err, ErrReferenceCycle)
+
+	// maps and slices containing themselves fail instead of
+	// recursing without end
+	selfMap := map[string]any{"a": 1}
+	selfMap["self"] = selfMap
+	_, err = ConvertValue(selfMap)
+	assert.ErrorIs(err, ErrReferenceCycle)
+	assert.Equal(Null, ToFEELValue(selfMap).(map[string]any)["self"])
+
+	selfList := []any{1, nil}
+	selfList[1] = selfList
+	_, err = ConvertValue(selfList)
+	assert.ErrorIs(err, ErrReferenceCycle)
+
+	type tree map[string]tree
+	selfTree := tree{}
+	selfTree["child"] = selfTree
+	_, err = EvalStringWithScope(`t.child`, Scope{"t": selfTree})
+	assert.ErrorIs(err, ErrReferenceCycle)
+
+	// values shared without cycles are converted
+	shared := []any{1}
+	v, err = ConvertValue(map[string]any{"a": shared, "b": shared})
+	assert.Nil(err)
+	assert.Equal(2, len(v.(map[string]any)))
+
+	// values already in FEEL form are kept as they are
+	list := []any{N(1), "x"}
+	converted := ToFEELValue(list).([]any)
+	assert.Same(list[0], converted[0])
+}
+
+func TestParseJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	v, err := ParseJSON([]byte(`{"amount": 12345678901234567.89, "rate": 0.1, "items": [1e3, -2.50, null], "ok": true}`))
+	assert.Nil(err)
+	s, err := FormatValue(v)
+	assert.Nil(err)
+	assert.Equal(`{amount: 12345678901234567.89, items: [1000, -2.5, null], ok: true, rate: 0.1}`, s)
+
+	res, err := EvalStringWithScope(`amount * 100`, v.(map[string]any))
+	assert.Nil(err)
+	data, err := json.Marshal(res)
+	assert.Nil(err)
+	assert.Equal(`1234567890123456789`, string(data))
+
+	_, err = ParseJSON([]byte(`{"a": 1} {"b": 2}`))
+	assert.NotNil(err)
+	_, err = ParseJSON([]byte(`{"a": `))
+	assert.NotNil(err)
+
+	// numbers in structs are decoded exactly as well
+	var payment struct {
+		Amount *Number `json:"amount"`
+		Fee    Number  `json:"fee"`
+	}
+	err = json.Unmarshal([]byte(`{"amount": 0.30000000000000000001, "fee": "1.25"}`), &payment)
+	assert.Nil(err)
+	assert.Equal("0.30000000000000000001", payment.Amount.String())
+	assert.Equal("1.25", payment.Fee.String())
+}
@@ -0,0 +1,235 @@
+package feel
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrDebugAbort = errors.New("evaluation aborted by debugger")
+
+// DebugAction tells the debugger how to resume from a pause
+type DebugAction int
+
+const (
+	// run until the next breakpoint
+	DebugContinue DebugAction = iota
+	// pause at the next node
+	DebugStepInto
+	// pause at the next node not nested in the current one
+	DebugStepOver
+	// pause at the next node outside of the parent of the current one
+	DebugStepOut
+	// abort the evaluation with ErrDebugAbort
+	DebugAbort
+)
+
+// Breakpoint pauses the evaluation at nodes starting at a source
+// position, or at calls of a function when FunctionName is set.
+// Row and Column start from 0, a negative Column matches the whole row
+type Breakpoint struct {
+	Row          int
+	Column       int
+	FunctionName string
+}
+
+func (bp Breakpoint) String() string {
+	if bp.FunctionName != "" {
+		return fmt.Sprintf("function %s", bp.FunctionName)
+	}
+	if bp.Column < 0 {
+		return fmt.Sprintf("line %d", bp.Row+1)
+	}
+	return fmt.Sprintf("%d:%d", bp.Row+1, bp.Column+1)
+}
+
+func (bp Breakpoint) match(node Node) bool {
+	if bp.FunctionName != "" {
+		if call, ok := node.(*FunCall); ok {
+			if ref, ok := call.FunRef.(*Var); ok {
+				return ref.Name == bp.FunctionName
+			}
+			return call.FunRef.Repr() == bp.FunctionName
+		}
+		return false
+	}
+	start := node.TextRange().Start
+	return start.Row == bp.Row && (bp.Column < 0 || start.Column == bp.Column)
+}
+
+// DebugFrame is the paused state handed to the pause handler
+type DebugFrame struct {
+	// the node about to be evaluated
+	Node Node
+	// nesting depth of the node, the root node is at 1
+	Depth int
+	// the breakpoint hit, nil when paused by stepping
+	Breakpoint *Breakpoint
+
+	intp *Interpreter
+}
+
+// Scopes returns the scope stack at the pause, from bottom to top
+func (frame DebugFrame) Scopes() []Scope {
+	return frame.intp.ScopeStack
+}
+
+// Bindings merges the scope stack, names in upper scopes shadow lower ones
+func (frame DebugFrame) Bindings() Scope {
+	merged := make(Scope)
+	for _, scope := range frame.intp.ScopeStack {
+		for k, v := range scope {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// Watch evaluates a watch expression in the paused scope, the
+// evaluation is neither observed by hooks nor changes the scopes
+func (frame DebugFrame) Watch(expr string) (any, error) {
+	ast, err := ParseString(expr)
+	if err != nil {
+		return nil, err
+	}
+	// the watch runs within the paused evaluation with the same
+	// settings, such as the null mode, the resolvers and the random
+	// source, but without hooks and apart from its warnings
+	watchIntp := *frame.intp
+	watchIntp.hooks = nil
+	watchIntp.tracer = nil
+	watchIntp.warnings = nil
+	watchIntp.ScopeStack = nil
+	for _, scope := range frame.intp.ScopeStack {
+		watchIntp.ScopeStack = append(watchIntp.ScopeStack, contextCopy(scope))
+	}
+	return watchIntp.EvalNode(ast)
+}
+
+// Debugger is an EvalHook pausing the evaluation at breakpoints and
+// steps, OnPause is called synchronously on every pause
+type Debugger struct {
+	OnPause     func(frame DebugFrame) DebugAction
+	StopOnEntry bool
+
+	breakpoints []Breakpoint
+	nodeStack   []Node
+	action      DebugAction
+	actionDepth int
+	started     bool
+}
+
+func NewDebugger(onPause func(frame DebugFrame) DebugAction) *Debugger {
+	return &Debugger{OnPause: onPause}
+}
+
+// AttachDebugger makes the debugger observe the evaluations of intp
+func (intp *Interpreter) AttachDebugger(debugger *Debugger) {
+	intp.AddHook(debugger)
+}
+
+func (debugger *Debugger) AddBreakpoint(bp Breakpoint) {
+	debugger.breakpoints = append(debugger.breakpoints, bp)
+}
+
+// BreakAt adds a breakpoint at the source position, row and column start from 0
+func (debugger *Debugger) BreakAt(row, column int) {
+	debugger.AddBreakpoint(Breakpoint{Row: row, Column: column})
+}
+
+// BreakOnFunction adds a breakpoint at the calls of a function
+func (debugger *Debugger) BreakOnFunction(name string) {
+	debugger.AddBreakpoint(Breakpoint{FunctionName: name})
+}
+
+func (debugger *Debugger) RemoveBreakpoint(bp Breakpoint) {
+	var kept []Breakpoint
+	for _, b := range debugger.breakpoints {
+		if b != bp {
+			kept = append(kept, b)
+		}
+	}
+	debugger.breakpoints = kept
+}
+
+func (debugger Debugger) Breakpoints() []Breakpoint {
+	return debugger.breakpoints
+}
+
+func (debugger *Debugger) hitBreakpoint(node Node) *Breakpoint {
+	for i, bp := range debugger.breakpoints {
+		if !bp.match(node) {
+			continue
+		}
+		// nodes sharing the start position with their parent, such
+		// as the left operand of a binop, don't hit again
+		if bp.FunctionName == "" && len(debugger.nodeStack) > 1 {
+			parent := debugger.nodeStack[len(debugger.nodeStack)-2]
+			if bp.match(parent) {
+				continue
+			}
+		}
+		return &debugger.breakpoints[i]
+	}
+	return nil
+}
+
+func (debugger *Debugger) shouldStep(depth int) bool {
+	switch debugger.action {
+	case DebugStepInto:
+		return true
+	case DebugStepOver:
+		return depth <= debugger.actionDepth
+	case DebugStepOut:
+		return depth < debugger.actionDepth
+	default:
+		return false
+	}
+}
+
+// StartEval drops the state left by the previous evaluation, which may
+// have been aborted, so that every evaluation starts from the entry
+func (debugger *Debugger) StartEval(intp *Interpreter) {
+	debugger.nodeStack = nil
+	debugger.action = DebugContinue
+	debugger.actionDepth = 0
+	debugger.started = false
+}
+
+func (debugger *Debugger) BeforeEval(intp *Interpreter, node Node) error {
+	debugger.nodeStack = append(debugger.nodeStack, node)
+	depth := len(debugger.nodeStack)
+
+	if !debugger.started {
+		debugger.started = true
+		if debugger.StopOnEntry {
+			debugger.action = DebugStepInto
+		}
+	}
+
+	bp := debugger.hitBreakpoint(node)
+	if bp == nil && !debugger.shouldStep(depth) {
+		return nil
+	}
+	if debugger.OnPause == nil {
+		return nil
+	}
+
+	action := debugger.OnPause(DebugFrame{
+		Node:       node,
+		Depth:      depth,
+		Breakpoint: bp,
+		intp:       intp,
+	})
+	if action == DebugAbort {
+		return ErrDebugAbort
+	}
+	debugger.action = action
+	debugger.actionDepth = depth
+	return nil
+}
+
+func (debugger *Debugger) AfterEval(intp *Interpreter, node Node, value any, err error) {
+	if len(debugger.nodeStack) > 0 {
+		debugger.nodeStack = debugger.nodeStack[:len(debugger.nodeStack)-1]
+	}
+}
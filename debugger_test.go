@@ -0,0 +1,205 @@
+package feel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func debugEval(t *testing.T, input string, vars string, debugger *Debugger) (any, error) {
+	intp := NewIntepreter()
+	assert.Nil(t, intp.PushVars(vars))
+	intp.AttachDebugger(debugger)
+	ast, err := ParseString(input)
+	assert.Nil(t, err)
+	return intp.EvalNode(ast)
+}
+
+func TestDebuggerBreakpoints(t *testing.T) {
+	assert := assert.New(t)
+
+	var paused []string
+	var watched []any
+	debugger := NewDebugger(func(frame DebugFrame) DebugAction {
+		paused = append(paused, frame.Node.Repr())
+		v, err := frame.Watch("x * 10")
+		assert.Nil(err)
+		watched = append(watched, v)
+		return DebugContinue
+	})
+	// the position of `x * a`
+	debugger.BreakAt(0, 23)
+
+	res, err := debugEval(t, `for x in [1, 2] return x * a`, `{a: 5}`, debugger)
+	assert.Nil(err)
+	assert.True(cmp.Equal([]any{N(5), N(10)}, res))
+	assert.Equal([]string{"(* x a)", "(* x a)"}, paused)
+	assert.True(cmp.Equal([]any{N(10), N(20)}, watched))
+
+	// breakpoints on function calls
+	var bindings []Scope
+	debugger = NewDebugger(func(frame DebugFrame) DebugAction {
+		assert.Equal("function sum", frame.Breakpoint.String())
+		bindings = append(bindings, frame.Bindings())
+		return DebugContinue
+	})
+	debugger.BreakOnFunction("sum")
+	res, err = debugEval(t, `sum([a, 2])`, `{a: 5}`, debugger)
+	assert.Nil(err)
+	assert.True(cmp.Equal(N(7), res))
+	assert.Equal(1, len(bindings))
+	assert.True(cmp.Equal(N(5), bindings[0]["a"]))
+
+	debugger.RemoveBreakpoint(Breakpoint{FunctionName: "sum"})
+	assert.Equal(0, len(debugger.Breakpoints()))
+
+	// names of functions with spaces
+	var calls int
+	debugger = NewDebugger(func(frame DebugFrame) DebugAction {
+		calls++
+		return DebugContinue
+	})
+	debugger.BreakOnFunction("string length")
+	res, err = debugEval(t, `string length("abc")`, `{}`, debugger)
+	assert.Nil(err)
+	assert.True(cmp.Equal(N(3), res))
+	assert.Equal(1, calls)
+}
+
+func TestDebuggerStepping(t *testing.T) {
+	assert := assert.New(t)
+
+	input := `if a > 3 then a * 2 else 0`
+
+	// step into visits every node
+	var paused []string
+	debugger := NewDebugger(func(frame DebugFrame) DebugAction {
+		paused = append(paused, frame.Node.Repr())
+		return DebugStepInto
+	})
+	debugger.StopOnEntry = true
+	res, err := debugEval(t, input, `{a: 5}`, debugger)
+	assert.Nil(err)
+	assert.True(cmp.Equal(N(10), res))
+	assert.Equal([]string{
+		"(if (> a 3) (* a 2) 0)",
+		"(> a 3)", "a", "3",
+		"(* a 2)", "a", "2",
+	}, paused)
+
+	// step over skips the children of the condition
+	actions := []DebugAction{DebugStepInto, DebugStepOver, DebugContinue}
+	paused = nil
+	debugger = NewDebugger(func(frame DebugFrame) DebugAction {
+		paused = append(paused, frame.Node.Repr())
+		action := actions[0]
+		actions = actions[1:]
+		return action
+	})
+	debugger.StopOnEntry = true
+	_, err = debugEval(t, input, `{a: 5}`, debugger)
+	assert.Nil(err)
+	assert.Equal([]string{"(if (> a 3) (* a 2) 0)", "(> a 3)", "(* a 2)"}, paused)
+
+	// step out leaves the condition
+	actions = []DebugAction{DebugStepInto, DebugStepInto, DebugStepOut, DebugContinue}
+	paused = nil
+	debugger = NewDebugger(debugger.OnPause)
+	debugger.StopOnEntry = true
+	_, err = debugEval(t, input, `{a: 5}`, debugger)
+	assert.Nil(err)
+	assert.Equal([]string{"(if (> a 3) (* a 2) 0)", "(> a 3)", "a", "(* a 2)"}, paused)
+
+	// abort stops the evaluation
+	debugger = NewDebugger(func(frame DebugFrame) DebugAction {
+		return DebugAbort
+	})
+	debugger.StopOnEntry = true
+	_, err = debugEval(t, input, `{a: 5}`, debugger)
+	assert.ErrorIs(err, ErrDebugAbort)
+}
+
+func TestDebuggerRerun(t *testing.T) {
+	assert := assert.New(t)
+
+	// abort in the middle, the next evaluation stops on entry again
+	// with the depths counted from the root
+	var paused []string
+	var depths []int
+	abort := true
+	debugger := NewDebugger(func(frame DebugFrame) DebugAction {
+		paused = append(paused, frame.Node.Repr())
+		depths = append(depths, frame.Depth)
+		if abort && frame.Depth == 2 {
+			return DebugAbort
+		}
+		return DebugStepInto
+	})
+	debugger.StopOnEntry = true
+
+	intp := NewIntepreter()
+	assert.Nil(intp.PushVars(`{a: 5}`))
+	intp.AttachDebugger(debugger)
+	ast, err := ParseString(`if a > 3 then a * 2 else 0`)
+	assert.Nil(err)
+
+	_, err = intp.EvalNode(ast)
+	assert.ErrorIs(err, ErrDebugAbort)
+	assert.Equal([]string{"(if (> a 3) (* a 2) 0)", "(> a 3)"}, paused)
+	assert.Equal([]int{1, 2}, depths)
+
+	abort = false
+	paused, depths = nil, nil
+	res, err := intp.EvalNode(ast)
+	assert.Nil(err)
+	assert.True(cmp.Equal(N(10), res))
+	assert.Equal("(if (> a 3) (* a 2) 0)", paused[0])
+	assert.Equal([]int{1, 2, 3, 3, 2, 3, 3}, depths)
+
+	// a step out left by the previous evaluation doesn't leak either
+	paused, depths = nil, nil
+	debugger.OnPause = func(frame DebugFrame) DebugAction {
+		paused = append(paused, frame.Node.Repr())
+		return DebugStepOut
+	}
+	_, err = intp.EvalNode(ast)
+	assert.Nil(err)
+	_, err = intp.EvalNode(ast)
+	assert.Nil(err)
+	assert.Equal([]string{"(if (> a 3) (* a 2) 0)", "(if (> a 3) (* a 2) 0)"}, paused)
+}
+
+func TestDebuggerWatchSettings(t *testing.T) {
+	assert := assert.New(t)
+
+	intp := NewIntepreter()
+	intp.EnableNullMode()
+	intp.SetRandomSource(bytes.NewReader(make([]byte, 64)))
+	intp.AddResolver(ResolverFunc(func(name string) (any, bool, error) {
+		if name == "rate" {
+			return 7, true, nil
+		}
+		return nil, false, nil
+	}))
+	var watched []any
+	debugger := NewDebugger(func(frame DebugFrame) DebugAction {
+		for _, expr := range []string{`{a: 1}.b`, `rate * 2`, `uuid()`} {
+			v, err := frame.Watch(expr)
+			assert.Nil(err, expr)
+			watched = append(watched, v)
+		}
+		return DebugContinue
+	})
+	debugger.StopOnEntry = true
+	intp.AttachDebugger(debugger)
+	ast, err := ParseString(`rate + 1`)
+	assert.Nil(err)
+	res, err := intp.EvalNode(ast)
+	assert.Nil(err)
+	assert.True(cmp.Equal(N(8), res))
+	assert.True(cmp.Equal([]any{Null, N(14), "00000000-0000-4000-8000-000000000000"}, watched))
+	// warnings of watches are kept apart
+	assert.Equal(0, len(intp.Warnings()))
+}
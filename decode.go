@@ -0,0 +1,336 @@
+package feel
+
+// decoding FEEL values into go values
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	bigFloatPtrType     = reflect.TypeOf((*big.Float)(nil))
+	bigIntPtrType       = reflect.TypeOf((*big.Int)(nil))
+)
+
+// DecodeError is returned when a FEEL value cannot be decoded into
+// the go type, Path locates the failed field from the root value
+type DecodeError struct {
+	Path    string
+	Message string
+}
+
+func (err DecodeError) Error() string {
+	path := err.Path
+	if path == "" {
+		path = "value"
+	}
+	return fmt.Sprintf("decode %s: %s", path, err.Message)
+}
+
+func newDecodeError(path string, format string, args ...any) *DecodeError {
+	return &DecodeError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// Decode decodes a FEEL value, usually an evaluation result, into
+// the go value pointed by target. numbers decode into any go numeric
+// kind or *big.Float, temporal values into time.Time and
+// time.Duration, contexts into structs and maps, lists into slices
+// and null into nil pointers or zero values.
+func Decode(value any, target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return newDecodeError("", "target must be a non-nil pointer, got %T", target)
+	}
+	return decodeValue(value, rv.Elem(), "")
+}
+
+// DecodeAs decodes a FEEL value into a new value of type T
+func DecodeAs[T any](value any) (T, error) {
+	var target T
+	err := Decode(value, &target)
+	return target, err
+}
+
+// EvalAs evaluates the input like EvalString and decodes the result into T
+func EvalAs[T any](input string, varsList ...string) (T, error) {
+	res, err := EvalString(input, varsList...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return DecodeAs[T](res)
+}
+
+// EvalWithScopeAs evaluates the input like EvalStringWithScope and
+// decodes the result into T
+func EvalWithScopeAs[T any](input string, scope Scope) (T, error) {
+	res, err := EvalStringWithScope(input, scope)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return DecodeAs[T](res)
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func decodeValue(value any, rv reflect.Value, path string) error {
+	tp := rv.Type()
+
+	if _, isNull := value.(*NullValue); isNull || value == nil {
+		rv.Set(reflect.Zero(tp))
+		return nil
+	}
+
+	// FEEL values assigned directly, e.g. *Number into *Number or any
+	if reflect.TypeOf(value).AssignableTo(tp) {
+		rv.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	switch tp {
+	case timeType:
+		if v, ok := value.(HasTime); ok {
+			rv.Set(reflect.ValueOf(v.Time()))
+			return nil
+		} else if v, ok := value.(HasDate); ok {
+			rv.Set(reflect.ValueOf(v.Date()))
+			return nil
+		}
+		return newDecodeError(path, "cannot decode %s into time.Time", typeName(value))
+	case durationType:
+		if v, ok := value.(*FEELDuration); ok {
+			if v.Years != 0 || v.Months != 0 {
+				return newDecodeError(path, "cannot decode years and months duration %s into time.Duration", v)
+			}
+			rv.SetInt(int64(v.Duration()))
+			return nil
+		}
+		return newDecodeError(path, "cannot decode %s into time.Duration", typeName(value))
+	case jsonNumberType:
+		if v, ok := value.(*Number); ok {
+			rv.SetString(v.String())
+			return nil
+		}
+		return newDecodeError(path, "cannot decode %s into json.Number", typeName(value))
+	case bigFloatPtrType:
+		if v, ok := value.(*Number); ok {
+			rv.Set(reflect.ValueOf(v.BigFloat()))
+			return nil
+		}
+		return newDecodeError(path, "cannot decode %s into *big.Float", typeName(value))
+	case bigIntPtrType:
+		if v, ok := value.(*Number); ok {
+			if !v.IsInteger() {
+				return newDecodeError(path, "number %s is not an integer", v)
+			}
+			i, _ := v.BigFloat().Int(nil)
+			rv.Set(reflect.ValueOf(i))
+			return nil
+		}
+		return newDecodeError(path, "cannot decode %s into *big.Int", typeName(value))
+	}
+
+	if rv.Kind() == reflect.Ptr {
+		elem := reflect.New(tp.Elem())
+		if err := decodeValue(value, elem.Elem(), path); err != nil {
+			return err
+		}
+		rv.Set(elem)
+		return nil
+	}
+
+	if s, ok := value.(string); ok && reflect.PtrTo(tp).Implements(textUnmarshalerType) {
+		if err := rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return newDecodeError(path, "%s", err)
+		}
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		if tp.NumMethod() == 0 {
+			rv.Set(reflect.ValueOf(value))
+			return nil
+		}
+	case reflect.Bool:
+		if v, ok := value.(bool); ok {
+			rv.SetBool(v)
+			return nil
+		}
+	case reflect.String:
+		if v, ok := value.(string); ok {
+			rv.SetString(v)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, ok := value.(*Number); ok {
+			return decodeInt(v, rv, path)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v, ok := value.(*Number); ok {
+			return decodeUint(v, rv, path)
+		}
+	case reflect.Float32, reflect.Float64:
+		if v, ok := value.(*Number); ok {
+			return decodeFloat(v, rv, path)
+		}
+	case reflect.Slice:
+		if v, ok := value.([]any); ok {
+			slice := reflect.MakeSlice(tp, len(v), len(v))
+			for i, elem := range v {
+				if err := decodeValue(elem, slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			rv.Set(slice)
+			return nil
+		}
+	case reflect.Array:
+		if v, ok := value.([]any); ok {
+			if len(v) > rv.Len() {
+				return newDecodeError(path, "list of %d elements overflows array of %d", len(v), rv.Len())
+			}
+			rv.Set(reflect.Zero(tp))
+			for i, elem := range v {
+				if err := decodeValue(elem, rv.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		if v, ok := value.(map[string]any); ok {
+			return decodeMap(v, rv, path)
+		}
+	case reflect.Struct:
+		if v, ok := value.(map[string]any); ok {
+			return decodeStruct(v, rv, path)
+		}
+	}
+	return newDecodeError(path, "cannot decode %s into %s", typeName(value), tp)
+}
+
+func decodeInt(n *Number, rv reflect.Value, path string) error {
+	if !n.IsInteger() {
+		return newDecodeError(path, "number %s loses precision as %s", n, rv.Type())
+	}
+	i, acc := n.BigFloat().Int64()
+	if acc != big.Exact || rv.OverflowInt(i) {
+		return newDecodeError(path, "number %s overflows %s", n, rv.Type())
+	}
+	rv.SetInt(i)
+	return nil
+}
+
+func decodeUint(n *Number, rv reflect.Value, path string) error {
+	if !n.IsInteger() {
+		return newDecodeError(path, "number %s loses precision as %s", n, rv.Type())
+	}
+	u, acc := n.BigFloat().Uint64()
+	if acc != big.Exact || rv.OverflowUint(u) {
+		return newDecodeError(path, "number %s overflows %s", n, rv.Type())
+	}
+	rv.SetUint(u)
+	return nil
+}
+
+// decodeFloat accepts the numbers whose shortest decimal form as float
+// is the number itself, such as 0.1, and rejects the ones rounded to
+// another float, such as 0.1000000000000000000001
+func decodeFloat(n *Number, rv reflect.Value, path string) error {
+	var f float64
+	bitSize := 64
+	if rv.Kind() == reflect.Float32 {
+		f32, _ := n.BigFloat().Float32()
+		f = float64(f32)
+		bitSize = 32
+	} else {
+		f, _ = n.BigFloat().Float64()
+	}
+	if math.IsInf(f, 0) {
+		return newDecodeError(path, "number %s overflows %s", n, rv.Type())
+	}
+	if back, err := parseNumber(strconv.FormatFloat(f, 'g', -1, bitSize)); err != nil || back.Compare(*n) != 0 {
+		return newDecodeError(path, "number %s loses precision as %s", n, rv.Type())
+	}
+	rv.SetFloat(f)
+	return nil
+}
+
+func decodeMap(ctx map[string]any, rv reflect.Value, path string) error {
+	tp := rv.Type()
+	if tp.Key().Kind() != reflect.String {
+		return newDecodeError(path, "map key type %s is not string", tp.Key())
+	}
+	m := reflect.MakeMapWithSize(tp, len(ctx))
+	for k, v := range ctx {
+		elem := reflect.New(tp.Elem()).Elem()
+		if err := decodeValue(v, elem, joinPath(path, k)); err != nil {
+			return err
+		}
+		m.SetMapIndex(reflect.ValueOf(k).Convert(tp.Key()), elem)
+	}
+	rv.Set(m)
+	return nil
+}
+
+func decodeStruct(ctx map[string]any, rv reflect.Value, path string) error {
+	tp := rv.Type()
+	for i := 0; i < tp.NumField(); i++ {
+		field := tp.Field(i)
+		name, _, skip := fieldName(field)
+		if skip {
+			continue
+		}
+		fv := rv.Field(i)
+		if field.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			// embedded structs are flattened
+			if err := decodeStruct(ctx, fv, path); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		v, ok := ctx[name]
+		if !ok {
+			// fall back to case insensitive match like encoding/json
+			var matches []string
+			for k := range ctx {
+				if strings.EqualFold(k, name) {
+					matches = append(matches, k)
+				}
+			}
+			if len(matches) > 1 {
+				sort.Strings(matches)
+				return newDecodeError(joinPath(path, name), "ambiguous keys %s", strings.Join(matches, ", "))
+			} else if len(matches) == 1 {
+				v, ok = ctx[matches[0]], true
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := decodeValue(v, fv, joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package feel
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testQuote struct {
+	Product  string        `json:"product"`
+	Amount   int64         `json:"amount"`
+	Rate     float64       `json:"rate"`
+	Start    time.Time     `json:"start"`
+	Period   time.Duration `json:"period"`
+	Discount *int          `json:"discount"`
+	Items    []struct {
+		Name  string `json:"name"`
+		Count uint8  `json:"count"`
+	} `json:"items"`
+}
+
+func TestDecodeValues(t *testing.T) {
+	assert := assert.New(t)
+
+	n, err := EvalAs[int]("3 * 7")
+	assert.Nil(err)
+	assert.Equal(21, n)
+
+	s, err := EvalAs[[]string](`["a", "b"]`)
+	assert.Nil(err)
+	assert.Equal([]string{"a", "b"}, s)
+
+	m, err := EvalAs[map[string]int](`{x: 1, y: 2}`)
+	assert.Nil(err)
+	assert.Equal(map[string]int{"x": 1, "y": 2}, m)
+
+	bf, err := EvalAs[*big.Float]("2.5")
+	assert.Nil(err)
+	assert.Equal("2.5", bf.Text('g', -1))
+
+	p, err := EvalAs[*int]("null")
+	assert.Nil(err)
+	assert.Nil(p)
+
+	d, err := EvalAs[time.Duration](`@"PT1H30M"`)
+	assert.Nil(err)
+	assert.Equal(90*time.Minute, d)
+
+	quote, err := EvalWithScopeAs[testQuote](`{
+		product: "loan",
+		amount: base * 2,
+		rate: 0.25,
+		start: @"2023-06-01T10:00:00",
+		period: @"P2DT1H",
+		discount: null,
+		items: [{name: "fee", count: 3}]
+	}`, Scope{"base": 500})
+	assert.Nil(err)
+	assert.Equal("loan", quote.Product)
+	assert.Equal(int64(1000), quote.Amount)
+	assert.Equal(0.25, quote.Rate)
+	assert.Equal(2023, quote.Start.Year())
+	assert.Equal(49*time.Hour, quote.Period)
+	assert.Nil(quote.Discount)
+	assert.Equal(uint8(3), quote.Items[0].Count)
+
+	// precision, overflow and type errors carry the path
+	_, err = EvalAs[int]("2.5")
+	var decErr *DecodeError
+	assert.True(errors.As(err, &decErr))
+	assert.Equal("", decErr.Path)
+
+	_, err = EvalAs[testQuote](`{items: [{name: "fee", count: 300}]}`)
+	assert.True(errors.As(err, &decErr))
+	assert.Equal("items[0].count", decErr.Path)
+	assert.Contains(err.Error(), "overflows")
+
+	_, err = EvalAs[testQuote](`{product: 5}`)
+	assert.True(errors.As(err, &decErr))
+	assert.Equal("product", decErr.Path)
+}
+
+func TestDecodeFloats(t *testing.T) {
+	assert := assert.New(t)
+
+	// decimals reading back from the float are accepted
+	f, err := EvalAs[float64]("0.1")
+	assert.Nil(err)
+	assert.Equal(0.1, f)
+
+	assert.Nil(Decode(NewNumber("1.5e300"), &f))
+	assert.Equal(1.5e300, f)
+
+	var f32 float32
+	assert.Nil(Decode(NewNumber("0.1"), &f32))
+	assert.Equal(float32(0.1), f32)
+
+	// the rounded ones are not
+	for _, s := range []string{"0.1000000000000000000001", "12345678901234567", "1e-400"} {
+		err = Decode(NewNumber(s), &f)
+		assert.ErrorContains(err, "loses precision as float64", s)
+	}
+	assert.ErrorContains(Decode(NewNumber("0.123456789"), &f32), "loses precision as float32")
+	assert.ErrorContains(Decode(NewNumber("1e39"), &f32), "overflows float32")
+}
+
+func TestDecodeFieldNames(t *testing.T) {
+	assert := assert.New(t)
+
+	type account struct {
+		Name    string
+		Balance int
+	}
+
+	// exact matches win over case insensitive ones
+	acc, err := EvalAs[account](`{Name: "exact", name: "other", balance: 5}`)
+	assert.Nil(err)
+	assert.Equal(account{Name: "exact", Balance: 5}, acc)
+
+	// keys differing only in case are ambiguous for the other fields
+	for i := 0; i < 10; i++ {
+		_, err = EvalAs[account](`{name: "a", NAME: "b"}`)
+		var decErr *DecodeError
+		assert.True(errors.As(err, &decErr))
+		assert.Equal("Name", decErr.Path)
+		assert.ErrorContains(err, "ambiguous keys NAME, name")
+	}
+}
@@ -56,3 +56,8 @@ func NewErrTooManyArguments() *EvalError {
 func NewErrBadOp(leftType, op, rightType string) *EvalError {
 	return NewEvalError(-5001, "type mismatch in op", "bad types in op, ", leftType, op, rightType)
 }
+
+// NewErrFunction wraps the failure of a native function
+func NewErrFunction(funcName string, err error) *EvalError {
+	return NewEvalError(-4020, "function error", fmt.Sprintf("%s: %s", funcName, err))
+}
@@ -21,27 +21,6 @@ func (v NullValue) MarshalJSON() ([]byte, error) {
 
 var Null = &NullValue{}
 
-func boolValue(condVal any) bool {
-	switch v := condVal.(type) {
-	case int64:
-		return v != 0
-	case float64:
-		return v != 0.0
-	case *Number:
-		return !v.Equal(*Zero)
-	case bool:
-		return v
-	case string:
-		return v != ""
-	case []any:
-		return len(v) > 0
-	case map[string]any:
-		return len(v) > 0
-	default:
-		return v != nil
-	}
-}
-
 func typeName(a any) string {
 	switch a.(type) {
 	case int64:
@@ -58,6 +37,8 @@ func typeName(a any) string {
 		return "list"
 	case map[string]any:
 		return "context"
+	case *lazyContext:
+		return "context"
 	case *NullValue:
 		return "null"
 	case *FEELDate:
@@ -82,24 +63,7 @@ func typeName(a any) string {
 }
 
 func normalizeValue(v any) any {
-	switch vv := v.(type) {
-	case int:
-		return NewNumberFromInt64(int64(vv))
-	case int64:
-		return NewNumberFromInt64(vv)
-	case float64:
-		return NewNumberFromFloat(vv)
-	default:
-		return vv
-	}
-}
-
-func (scope Scope) normalizeScope() Scope {
-	newScp := make(Scope)
-	for key, value := range scope {
-		newScp[key] = normalizeValue(value)
-	}
-	return newScp
+	return ToFEELValue(v)
 }
 
 // intepreter
@@ -118,7 +82,34 @@ func (intp Interpreter) Len() int {
 }
 
 func (intp *Interpreter) Push(scp Scope) {
-	intp.ScopeStack = append(intp.ScopeStack, scp.normalizeScope())
+	newScp := make(Scope)
+	for key, value := range scp {
+		newScp[key], _ = intp.convert(value)
+	}
+	intp.ScopeStack = append(intp.ScopeStack, newScp)
+}
+
+// convert converts the value bound in the scopes, the lazy contexts
+// it makes are kept to be reset by evaluations
+func (intp *Interpreter) convert(v any) (any, error) {
+	conv := &converter{seen: make(map[refKey]bool)}
+	r, _ := conv.convert(v)
+	intp.lazies = append(intp.lazies, conv.lazies...)
+	return r, conv.err
+}
+
+// pushScope pushes the scope given to evaluations, failing when it
+// contains reference cycles
+func (intp *Interpreter) pushScope(scp Scope) error {
+	if scp == nil {
+		return nil
+	}
+	converted, err := intp.convert(map[string]any(scp))
+	if err != nil {
+		return err
+	}
+	intp.ScopeStack = append(intp.ScopeStack, Scope(converted.(map[string]any)))
+	return nil
 }
 
 func (intp *Interpreter) PushEmpty() {
@@ -137,15 +128,30 @@ func (intp *Interpreter) Pop() Scope {
 
 // resolve a name from the top of scopestack to bottom
 func (intp Interpreter) Resolve(name string) (any, bool) {
+	v, ok, _ := intp.lookup(name)
+	return v, ok
+}
+
+// lookup a name from the scopestack, then the resolvers and then the
+// prelude, errors of resolvers are returned
+func (intp Interpreter) lookup(name string) (any, bool, error) {
 	for at := len(intp.ScopeStack) - 1; at >= 0; at-- {
 		if v, ok := intp.ScopeStack[at][name]; ok {
-			return v, true
+			return v, true, nil
+		}
+	}
+	for _, resolver := range intp.resolvers {
+		v, ok, err := resolver.Resolve(name)
+		if err != nil {
+			return nil, false, err
+		} else if ok {
+			return v, true, nil
 		}
 	}
 	if prelude, ok := GetPrelude().Resolve(name); ok {
-		return prelude, ok
+		return prelude, ok, nil
 	}
-	return nil, false
+	return nil, false, nil
 }
 
 // resolve the name and set to new value
@@ -162,12 +168,113 @@ func (intp Interpreter) Set(name string, value any) bool {
 // bind the value to the name of current scope
 func (intp *Interpreter) Bind(name string, value any) {
 	if intp.Len() > 0 {
-		intp.ScopeStack[intp.Len()-1][name] = normalizeValue(value)
+		intp.ScopeStack[intp.Len()-1][name], _ = intp.convert(value)
 	} else {
 		panic("empty stack")
 	}
 }
 
+// EvalHook observes the evaluation of every node, it's the base of
+// tracing, debugging and profiling. An error returned by BeforeEval
+// aborts the evaluation
+type EvalHook interface {
+	BeforeEval(intp *Interpreter, node Node) error
+	AfterEval(intp *Interpreter, node Node, value any, err error)
+}
+
+// CallHook is optionally implemented by an EvalHook to observe the
+// invocations of functions, the args of a macro are the argument nodes
+type CallHook interface {
+	BeforeCall(intp *Interpreter, funcName string, fn any, args map[string]any)
+	AfterCall(intp *Interpreter, funcName string, fn any, result any, err error)
+}
+
+// StartHook is optionally implemented by an EvalHook to reset its
+// state at the start of every top level evaluation
+type StartHook interface {
+	StartEval(intp *Interpreter)
+}
+
+// AddHook appends a hook observing the evaluation
+func (intp *Interpreter) AddHook(hook EvalHook) {
+	intp.hooks = append(intp.hooks, hook)
+}
+
+// startEval begins a top level evaluation, the values cached by lazy
+// contexts are resolved afresh, the warnings of the previous
+// evaluation are dropped and the hooks are restarted. It returns false
+// within an evaluation.
+func (intp *Interpreter) startEval() bool {
+	if intp.evaluating {
+		return false
+	}
+	intp.evaluating = true
+	intp.warnings = nil
+	for _, lazy := range intp.resolvers {
+		lazy.reset()
+	}
+	for _, lazy := range intp.lazies {
+		lazy.reset()
+	}
+	for _, hook := range intp.hooks {
+		if startHook, ok := hook.(StartHook); ok {
+			startHook.StartEval(intp)
+		}
+	}
+	return true
+}
+
+func (intp *Interpreter) endEval() {
+	intp.evaluating = false
+}
+
+// EvalNode evaluates a node and notifies the hooks around it, child
+// nodes must be evaluated through it instead of calling Node.Eval
+func (intp *Interpreter) EvalNode(node Node) (any, error) {
+	if intp.startEval() {
+		defer intp.endEval()
+	}
+	if len(intp.hooks) == 0 {
+		v, err := node.Eval(intp)
+		if err != nil {
+			return intp.nullOnError(node, err)
+		}
+		return v, nil
+	}
+	for i, hook := range intp.hooks {
+		if err := hook.BeforeEval(intp, node); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				intp.hooks[j].AfterEval(intp, node, nil, err)
+			}
+			return nil, err
+		}
+	}
+	v, err := node.Eval(intp)
+	if err != nil {
+		v, err = intp.nullOnError(node, err)
+	}
+	for i := len(intp.hooks) - 1; i >= 0; i-- {
+		intp.hooks[i].AfterEval(intp, node, v, err)
+	}
+	return v, err
+}
+
+func (intp *Interpreter) beforeCall(funcName string, fn any, args map[string]any) {
+	for _, hook := range intp.hooks {
+		if callHook, ok := hook.(CallHook); ok {
+			callHook.BeforeCall(intp, funcName, fn, args)
+		}
+	}
+}
+
+func (intp *Interpreter) afterCall(funcName string, fn any, result any, err error) {
+	for i := len(intp.hooks) - 1; i >= 0; i-- {
+		if callHook, ok := intp.hooks[i].(CallHook); ok {
+			callHook.AfterCall(intp, funcName, fn, result, err)
+		}
+	}
+}
+
 // Node's eval functions
 
 // Evaluate Number node
@@ -193,7 +300,9 @@ func (node TemporalNode) Eval(intp *Interpreter) (any, error) {
 }
 
 func (node Var) Eval(intp *Interpreter) (any, error) {
-	if v, ok := intp.Resolve(node.Name); ok {
+	if v, ok, err := intp.lookup(node.Name); err != nil {
+		return nil, err
+	} else if ok {
 		return v, nil
 	} else {
 		//return nil, NewErrKeyNotFound(node.Name)
@@ -202,11 +311,11 @@ func (node Var) Eval(intp *Interpreter) (any, error) {
 }
 
 func (node RangeNode) Eval(intp *Interpreter) (any, error) {
-	startVal, err := node.Start.Eval(intp)
+	startVal, err := intp.EvalNode(node.Start)
 	if err != nil {
 		return nil, err
 	}
-	endVal, err := node.End.Eval(intp)
+	endVal, err := intp.EvalNode(node.End)
 	if err != nil {
 		return nil, err
 	}
@@ -221,7 +330,7 @@ func (node RangeNode) Eval(intp *Interpreter) (any, error) {
 func (node ArrayNode) Eval(intp *Interpreter) (any, error) {
 	var arr []any
 	for _, elem := range node.Elements {
-		v, err := elem.Eval(intp)
+		v, err := intp.EvalNode(elem)
 		if err != nil {
 			return nil, err
 		}
@@ -233,49 +342,125 @@ func (node EmptyNode) Eval(intp *Interpreter) (any, error) {
 	return nil, nil
 }
 
+// MultiTests is true if any test is true, null if no test is true but
+// some are null, and false otherwise
 func (node MultiTests) Eval(intp *Interpreter) (any, error) {
+	var result any = false
 	for _, elem := range node.Elements {
-		v, err := elem.Eval(intp)
+		v, err := intp.EvalNode(elem)
 		if err != nil {
 			return nil, err
 		}
-		if boolValue(v) {
+		t, err := intp.unaryTest(elem, v)
+		if err != nil {
+			return nil, err
+		}
+		if t == true {
 			return true, nil
+		} else if t != false {
+			result = Null
 		}
 	}
-	return false, nil
+	return result, nil
+}
+
+// unaryTest gives the result of a test of unary tests from the value of
+// its node, true, false or null
+func (intp *Interpreter) unaryTest(elem Node, v any) (any, error) {
+	if _, ok := v.(bool); ok {
+		return v, nil
+	} else if producesBool(elem) {
+		// a comparison such as < 5 with a null operand
+		return Null, nil
+	}
+	input, hasInput, err := intp.lookup("?")
+	if err != nil {
+		return nil, err
+	}
+	return unaryTestValue(v, input, hasInput), nil
+}
+
+func unaryTestValue(v any, input any, hasInput bool) any {
+	if b, ok := v.(bool); ok {
+		return b
+	} else if !hasInput {
+		return Null
+	}
+	switch vv := v.(type) {
+	case *RangeValue:
+		if isNull(input) {
+			return Null
+		}
+		in, err := vv.Contains(input)
+		if err != nil {
+			return Null
+		}
+		return in
+	case []any:
+		for _, elem := range vv {
+			if valuesEqual(input, elem) {
+				return true
+			}
+		}
+		return false
+	default:
+		r, err := equalValues(input, v)
+		if err != nil {
+			return Null
+		}
+		return r
+	}
 }
 
 func (node MapNode) Eval(intp *Interpreter) (any, error) {
 	mapVal := make(map[string]any)
+	// an entry is visible to the entries after it
+	intp.PushEmpty()
+	defer intp.Pop()
 	for _, item := range node.Values {
-
-		v, err := item.Value.Eval(intp)
+		v, err := intp.EvalNode(item.Value)
 		if err != nil {
 			return nil, err
 		}
 		mapVal[item.Name] = v
+		intp.Bind(item.Name, v)
 	}
 	return mapVal, nil
 }
 
 func (node DotOp) Eval(intp *Interpreter) (any, error) {
-	leftVal, err := node.Left.Eval(intp)
+	leftVal, err := intp.EvalNode(node.Left)
 	if err != nil {
 		return nil, err
 	}
+	if intp.nullMode && isNull(leftVal) {
+		return Null, nil
+	}
+	return attrValue(leftVal, node.Attr)
+}
+
+// attrValue gets the attribute of a context or a value having attrs
+func attrValue(leftVal any, attr string) (any, error) {
 	if mapVal, ok := leftVal.(map[string]any); ok {
-		if val, found := mapVal[node.Attr]; found {
+		if val, found := mapVal[attr]; found {
+			return val, nil
+		} else {
+			return nil, NewErrKeyNotFound(attr)
+		}
+	} else if lazy, ok := leftVal.(*lazyContext); ok {
+		if val, found, err := lazy.Resolve(attr); err != nil {
+			return nil, err
+		} else if found {
 			return val, nil
 		} else {
-			return nil, NewErrKeyNotFound(node.Attr)
+			return nil, NewErrKeyNotFound(attr)
 		}
 	} else if obj, ok := leftVal.(HasAttrs); ok {
-		if v, found := obj.GetAttr(node.Attr); found {
+		if v, found := obj.GetAttr(attr); found {
 			return normalizeValue(v), nil
 		} else {
-			//return nil, NewEvalError(-4001, "attr error", fmt.Sprintf("cannot get attr %s", node.Attr))
-			return nil, NewErrKeyNotFound(node.Attr)
+			//return nil, NewEvalError(-4001, "attr error", fmt.Sprintf("cannot get attr %s", attr))
+			return nil, NewErrKeyNotFound(attr)
 
 		}
 	} else {
@@ -285,19 +470,22 @@ func (node DotOp) Eval(intp *Interpreter) (any, error) {
 }
 
 func (node IfExpr) Eval(intp *Interpreter) (any, error) {
-	condVal, err := node.Cond.Eval(intp)
+	condVal, err := intp.EvalNode(node.Cond)
 	if err != nil {
 		return nil, err
 	}
 
-	if boolValue(condVal) {
-		brVal, err := node.ThenBranch.Eval(intp)
+	// conditions which are not true, including null, take the else branch
+	if condVal == true {
+		intp.traceBranch("then")
+		brVal, err := intp.EvalNode(node.ThenBranch)
 		if err != nil {
 			return nil, err
 		}
 		return brVal, nil
 	} else {
-		brVal, err := node.ElseBranch.Eval(intp)
+		intp.traceBranch("else")
+		brVal, err := intp.EvalNode(node.ElseBranch)
 		if err != nil {
 			return nil, err
 		}
@@ -306,7 +494,7 @@ func (node IfExpr) Eval(intp *Interpreter) (any, error) {
 }
 
 func (node ForExpr) Eval(intp *Interpreter) (any, error) {
-	listLike, err := node.ListExpr.Eval(intp)
+	listLike, err := intp.EvalNode(node.ListExpr)
 	if err != nil {
 		return nil, err
 	}
@@ -317,11 +505,12 @@ func (node ForExpr) Eval(intp *Interpreter) (any, error) {
 		for _, val := range aList {
 			intp.Bind(node.Varname, val)
 
-			res, err := node.ReturnExpr.Eval(intp)
+			res, err := intp.EvalNode(node.ReturnExpr)
 			if err != nil {
 				intp.Pop()
 				return nil, err
 			}
+			intp.traceIteration(val, res)
 			results = append(results, res)
 		}
 		intp.Pop()
@@ -332,7 +521,7 @@ func (node ForExpr) Eval(intp *Interpreter) (any, error) {
 }
 
 func (node SomeExpr) Eval(intp *Interpreter) (any, error) {
-	listLike, err := node.ListExpr.Eval(intp)
+	listLike, err := intp.EvalNode(node.ListExpr)
 	if err != nil {
 		return nil, err
 	}
@@ -342,12 +531,14 @@ func (node SomeExpr) Eval(intp *Interpreter) (any, error) {
 		for _, val := range aList {
 			intp.Bind(node.Varname, val)
 
-			res, err := node.FilterExpr.Eval(intp)
+			res, err := intp.EvalNode(node.FilterExpr)
 			if err != nil {
 				intp.Pop()
 				return nil, err
 			}
-			if boolValue(res) {
+			intp.traceIteration(val, res)
+			if res == true {
+				intp.Pop()
 				return val, nil
 			}
 		}
@@ -359,7 +550,7 @@ func (node SomeExpr) Eval(intp *Interpreter) (any, error) {
 }
 
 func (node EveryExpr) Eval(intp *Interpreter) (any, error) {
-	listLike, err := node.ListExpr.Eval(intp)
+	listLike, err := intp.EvalNode(node.ListExpr)
 	if err != nil {
 		return nil, err
 	}
@@ -370,13 +561,14 @@ func (node EveryExpr) Eval(intp *Interpreter) (any, error) {
 		for _, val := range aList {
 			intp.Bind(node.Varname, val)
 
-			res, err := node.FilterExpr.Eval(intp)
+			res, err := intp.EvalNode(node.FilterExpr)
 			if err != nil {
 				intp.Pop()
 				return nil, err
 			}
+			intp.traceIteration(val, res)
 
-			if boolValue(res) {
+			if res == true {
 				chooses = append(chooses, val)
 			}
 		}
@@ -400,14 +592,19 @@ func (node FunDef) EvalCall(intp *Interpreter, args []any) (any, error) {
 	}
 	intp.PushEmpty()
 	defer intp.Pop()
+	argVals := make(map[string]any)
 	for i, argName := range node.Args {
 		intp.Bind(argName, args[i])
+		argVals[argName] = args[i]
 	}
-	return node.Body.Eval(intp)
+	intp.beforeCall("function", &node, argVals)
+	r, err := intp.EvalNode(node.Body)
+	intp.afterCall("function", &node, r, err)
+	return r, err
 }
 
 func (node FunCall) Eval(intp *Interpreter) (any, error) {
-	v, err := node.FunRef.Eval(intp)
+	v, err := intp.EvalNode(node.FunRef)
 	if err != nil {
 		return nil, err
 	}
@@ -424,69 +621,50 @@ func (node FunCall) Eval(intp *Interpreter) (any, error) {
 }
 
 func (node FunCall) EvalNativeFun(intp *Interpreter, funDef *NativeFun) (any, error) {
-	argVals := make(map[string]any)
-	if node.keywordArgs {
-		kwArgMap, err := node.evalArgsToMap(intp)
+	if !node.KeywordArgs && len(node.Args) < len(funDef.requiredArgNames) {
+		required := funDef.requiredArgNames[len(node.Args):len(funDef.requiredArgNames)]
+		return nil, NewErrTooFewArguments(required)
+	}
+	args := make([]any, len(node.Args))
+	var argNames []string
+	if node.KeywordArgs {
+		argNames = make([]string, len(node.Args))
+	}
+	for i, argNode := range node.Args {
+		a, err := intp.EvalNode(argNode.Arg)
 		if err != nil {
 			return nil, err
 		}
-
-		for _, argName := range funDef.requiredArgNames {
-			if v, ok := kwArgMap[argName]; ok {
-				argVals[argName] = v
-			} else {
-				//return nil, NewEvalError(-5001, "no keyword argument", fmt.Sprintf("no keyword argument %s", argName))
-				return nil, NewErrKeywordArgument(argName)
-			}
-		}
-
-		for _, argName := range funDef.optionalArgNames {
-			if v, ok := kwArgMap[argName]; ok {
-				argVals[argName] = v
-			}
-		}
-	} else {
-		if len(node.Args) < len(funDef.requiredArgNames) {
-			required := funDef.requiredArgNames[len(node.Args):len(funDef.requiredArgNames)]
-			return nil, NewErrTooFewArguments(required)
-		}
-		for i, argNode := range node.Args {
-			a, err := argNode.arg.Eval(intp)
-			if err != nil {
-				return nil, err
-			}
-			if i < len(funDef.requiredArgNames) {
-				argVals[funDef.requiredArgNames[i]] = a
-			} else if i < len(funDef.requiredArgNames)+len(funDef.optionalArgNames) {
-				argVals[funDef.optionalArgNames[i-len(funDef.requiredArgNames)]] = a
-			} else if funDef.varArgName != "" {
-				if vars, ok := argVals[funDef.varArgName]; ok {
-					varargs := vars.([]any)
-					varargs = append(varargs, a)
-					argVals[funDef.varArgName] = varargs
-				} else {
-					argVals[funDef.varArgName] = []any{a}
-				}
-			} else {
-				//return nil, NewEvalError(-5002, "too many arguments")
-				return nil, NewErrTooManyArguments()
-			}
+		args[i] = a
+		if node.KeywordArgs {
+			argNames[i] = argNode.Name
 		}
 	}
-	return funDef.Call(intp, argVals)
+	argVals, err := funDef.argMap(args, argNames)
+	if err != nil {
+		return nil, err
+	}
+	funcName := node.FunRef.Repr()
+	intp.beforeCall(funcName, funDef, argVals)
+	r, err := funDef.Call(intp, argVals)
+	intp.afterCall(funcName, funDef, r, err)
+	if err != nil && intp.nullMode {
+		err = functionError(funcName, err)
+	}
+	return r, err
 }
 
 func (node FunCall) evalArgsToMap(intp *Interpreter) (map[string]any, error) {
-	if !node.keywordArgs {
+	if !node.KeywordArgs {
 		return nil, errors.New("funcall has no keyword args")
 	}
 	kwArgMap := make(map[string]any)
 	for _, argNode := range node.Args {
-		a, err := argNode.arg.Eval(intp)
+		a, err := intp.EvalNode(argNode.Arg)
 		if err != nil {
 			return nil, err
 		}
-		kwArgMap[argNode.argName] = a
+		kwArgMap[argNode.Name] = a
 	}
 	return kwArgMap, nil
 }
@@ -499,10 +677,10 @@ func (node FunCall) EvalMacro(intp *Interpreter, macro *Macro) (any, error) {
 
 	argNodes := make(map[string]Node)
 	var varArgs []Node
-	if node.keywordArgs {
+	if node.KeywordArgs {
 		kwArgMap := make(map[string]Node)
 		for _, argNode := range node.Args {
-			kwArgMap[argNode.argName] = argNode.arg
+			kwArgMap[argNode.Name] = argNode.Arg
 		}
 
 		for _, argName := range macro.requiredArgNames {
@@ -527,18 +705,33 @@ func (node FunCall) EvalMacro(intp *Interpreter, macro *Macro) (any, error) {
 		}
 		for i, argNode := range node.Args {
 			if i < len(macro.requiredArgNames) {
-				argNodes[macro.requiredArgNames[i]] = argNode.arg
+				argNodes[macro.requiredArgNames[i]] = argNode.Arg
 			} else if i < len(macro.requiredArgNames)+len(macro.optionalArgNames) {
-				argNodes[macro.optionalArgNames[i-len(macro.requiredArgNames)]] = argNode.arg
+				argNodes[macro.optionalArgNames[i-len(macro.requiredArgNames)]] = argNode.Arg
 			} else if macro.varArgName != "" {
-				varArgs = append(varArgs, argNode.arg)
+				varArgs = append(varArgs, argNode.Arg)
 			} else {
 				//return nil, NewEvalError(-5002, "too many arguments")
 				return nil, NewErrTooManyArguments()
 			}
 		}
 	}
-	return macro.fn(intp, argNodes, varArgs)
+	funcName := node.FunRef.Repr()
+	if len(intp.hooks) > 0 {
+		macroArgs := make(map[string]any)
+		for name, argNode := range argNodes {
+			macroArgs[name] = argNode
+		}
+		if len(varArgs) > 0 {
+			macroArgs[macro.varArgName] = varArgs
+		}
+		intp.beforeCall(funcName, macro, macroArgs)
+	}
+	r, err := macro.fn(intp, argNodes, varArgs)
+	if len(intp.hooks) > 0 {
+		intp.afterCall(funcName, macro, r, err)
+	}
+	return r, err
 }
 
 func (node FunCall) EvalFunDef(intp *Interpreter, funDef *FunDef) (any, error) {
@@ -552,7 +745,7 @@ func (node FunCall) EvalFunDef(intp *Interpreter, funDef *FunDef) (any, error) {
 	intp.PushEmpty()
 	defer intp.Pop()
 
-	if node.keywordArgs {
+	if node.KeywordArgs {
 		kwArgMap, err := node.evalArgsToMap(intp)
 		if err != nil {
 			return nil, err
@@ -569,42 +762,57 @@ func (node FunCall) EvalFunDef(intp *Interpreter, funDef *FunDef) (any, error) {
 		}
 	} else {
 		for i, argNode := range node.Args {
-			a, err := argNode.arg.Eval(intp)
+			a, err := intp.EvalNode(argNode.Arg)
 			if err != nil {
 				return nil, err
 			}
 			intp.Bind(funDef.Args[i], a)
 		}
 	}
-	ret, err := funDef.Body.Eval(intp)
+	funcName := node.FunRef.Repr()
+	intp.beforeCall(funcName, funDef, intp.ScopeStack[intp.Len()-1])
+	ret, err := intp.EvalNode(funDef.Body)
+	intp.afterCall(funcName, funDef, ret, err)
 	return ret, err
 }
 
+var errScopeNotMap = errors.New("scope should be map")
+
+// PushVars evaluates vars, a context expression such as `{a: 5}`, and
+// pushes the result as a new scope
+func (intp *Interpreter) PushVars(vars string) error {
+	scopeAst, err := ParseString(vars)
+	if err != nil {
+		return err
+	}
+	r, err := intp.EvalNode(scopeAst)
+	if err != nil {
+		return err
+	}
+	if scope, ok := r.(map[string]any); ok {
+		intp.Push(scope)
+		return nil
+	}
+	return errScopeNotMap
+}
+
 func EvalString(input string, varsList ...string) (any, error) {
 	intp := NewIntepreter()
 	for i, vars := range varsList {
 		if vars == "" {
 			continue
 		}
-		scopeAst, err := ParseString(vars)
-		if err != nil {
-			return nil, err
-		}
-		r, err := scopeAst.Eval(intp)
-		if err != nil {
-			return nil, err
-		}
-		if scope, ok := r.(map[string]any); ok {
-			intp.Push(scope)
-		} else {
+		if err := intp.PushVars(vars); errors.Is(err, errScopeNotMap) {
 			return nil, fmt.Errorf("the NO. %d scope should be map", i+1)
+		} else if err != nil {
+			return nil, err
 		}
 	}
 	ast, err := ParseString(input)
 	if err != nil {
 		return nil, err
 	}
-	r, err := ast.Eval(intp)
+	r, err := intp.EvalNode(ast)
 	return r, err
 }
 
@@ -614,9 +822,9 @@ func EvalStringWithScope(input string, scope Scope) (any, error) {
 		return nil, err
 	}
 	intp := NewIntepreter()
-	if scope != nil {
-		intp.Push(scope)
+	if err := intp.pushScope(scope); err != nil {
+		return nil, err
 	}
-	r, err := ast.Eval(intp)
+	r, err := intp.EvalNode(ast)
 	return r, err
 }
@@ -14,49 +14,50 @@ func (binop Binop) Eval(intp *Interpreter) (any, error) {
 		return binop.andOp(intp)
 	case "or":
 		return binop.orOp(intp)
-	case "+":
-		return binop.addOp(intp)
-	case "-":
-		return binop.subOp(intp)
-	case "*":
-		return binop.mulOp(intp)
-	case "/":
-		return binop.divOp(intp)
-	case "%":
-		return binop.modOp(intp)
-	case ">":
-		return binop.compareGTOp(intp)
-	case ">=":
-		return binop.compareGEOp(intp)
-	case "<":
-		return binop.compareLTOp(intp)
-	case "<=":
-		return binop.compareLEOp(intp)
-	case "!=":
-		return binop.notEqalOp(intp)
-	case "[]":
-		return binop.indexAtOp(intp)
-	case "=":
-		return binop.equalOp(intp)
-	case "in":
-		return binop.inOp(intp)
-	default:
+	}
+	fn, ok := binaryFuncs[binop.Op]
+	if !ok {
 		return nil, NewEvalError(-3000, "no such binary op", fmt.Sprintf("Binary op %s not exist or not supported", binop.Op))
 	}
-}
-
-type evalNumbers func(a, b *Number) any
-type evalStrings func(a, b string) any
-
-func (binop Binop) numberOp(intp *Interpreter, en evalNumbers, op string) (any, error) {
-	leftVal, err := binop.Left.Eval(intp)
+	leftVal, err := intp.EvalNode(binop.Left)
 	if err != nil {
 		return nil, err
 	}
-	rightVal, err := binop.Right.Eval(intp)
+	rightVal, err := intp.EvalNode(binop.Right)
 	if err != nil {
 		return nil, err
 	}
+	if intp.nullMode && nullPropagates(binop.Op) && (isNull(leftVal) || isNull(rightVal)) {
+		return Null, nil
+	}
+	return fn(leftVal, rightVal)
+}
+
+// binaryFunc applies a binary operator to the evaluated operands, the
+// functions are shared by the tree walker and the VM
+type binaryFunc func(leftVal, rightVal any) (any, error)
+
+var binaryFuncs = map[string]binaryFunc{
+	"+":  addValues,
+	"-":  subValues,
+	"*":  mulValues,
+	"/":  divValues,
+	"%":  modValues,
+	"**": powValues,
+	">":  compareGT,
+	">=": compareGE,
+	"<":  compareLT,
+	"<=": compareLE,
+	"=":  equalValues,
+	"!=": notEqualValues,
+	"[]": indexAt,
+	"in": inValues,
+}
+
+type evalNumbers func(a, b *Number) any
+type evalStrings func(a, b string) any
+
+func numberOp(leftVal, rightVal any, en evalNumbers, op string) (any, error) {
 	if leftNumber, ok := leftVal.(*Number); ok {
 		if rightNumber, ok := rightVal.(*Number); ok {
 			return en(leftNumber, rightNumber), nil
@@ -108,6 +109,22 @@ func compareInterfaces(leftVal, rightVal any) (int, error) {
 				return 1, nil
 			}
 		}
+	case *FEELDate:
+		if rightDate, ok := rightVal.(*FEELDate); ok {
+			if v.Date().Equal(rightDate.Date()) {
+				return 0, nil
+			} else if v.Date().Before(rightDate.Date()) {
+				return -1, nil
+			} else {
+				return 1, nil
+			}
+		}
+	case *FEELDuration:
+		if rightDur, ok := rightVal.(*FEELDuration); ok {
+			if r, ok := compareDurations(v, rightDur); ok {
+				return r, nil
+			}
+		}
 	case []any:
 		if rightArr, ok := rightVal.([]any); ok {
 			return compareArrays(v, rightArr)
@@ -120,6 +137,29 @@ func compareInterfaces(leftVal, rightVal any) (int, error) {
 	return 0, NewEvalError(-3106, "invalid types", fmt.Sprintf("bad type in comparation, %T vs. %T", leftVal, rightVal))
 }
 
+// compareDurations compares the months of years-months durations and
+// the spans of days-time durations, a month has no fixed number of
+// days so durations differing in both are not ordered
+func compareDurations(a, b *FEELDuration) (int, bool) {
+	ma, mb := a.TotalMonths(), b.TotalMonths()
+	da, db := a.Duration(), b.Duration()
+	if ma == mb {
+		return sign(int64(da - db)), true
+	} else if da == db {
+		return sign(int64(ma - mb)), true
+	}
+	return 0, false
+}
+
+func sign(n int64) int {
+	if n < 0 {
+		return -1
+	} else if n > 0 {
+		return 1
+	}
+	return 0
+}
+
 func compareArrays(a, b []any) (int, error) {
 	minSize := len(a)
 	if minSize > len(b) {
@@ -165,28 +205,7 @@ func compareMaps(a, b map[string]any) (int, error) {
 	return 0, nil
 }
 
-func (binop Binop) compareValues(intp *Interpreter) (int, error) {
-	leftVal, err := binop.Left.Eval(intp)
-	if err != nil {
-		return 0, err
-	}
-	rightVal, err := binop.Right.Eval(intp)
-	if err != nil {
-		return 0, err
-	}
-	return compareInterfaces(leftVal, rightVal)
-}
-
-func (binop Binop) typedOp(intp *Interpreter, es evalStrings, en evalNumbers, op string) (any, error) {
-	leftVal, err := binop.Left.Eval(intp)
-	if err != nil {
-		return nil, err
-	}
-	rightVal, err := binop.Right.Eval(intp)
-	if err != nil {
-		return nil, err
-	}
-
+func typedOp(leftVal, rightVal any, es evalStrings, en evalNumbers, op string) (any, error) {
 	switch v := leftVal.(type) {
 	case string:
 		if es != nil {
@@ -212,152 +231,204 @@ func (binop Binop) typedOp(intp *Interpreter, es evalStrings, en evalNumbers, op
 				return v.Sub(rightTime), nil
 			}
 		}
+	case *FEELDate:
+		if rightDur, ok := rightVal.(*FEELDuration); ok {
+			if op == "+" {
+				return v.Add(rightDur), nil
+			} else if op == "-" {
+				return v.Add(rightDur.Negative()), nil
+			}
+		} else if rightDate, ok := rightVal.(*FEELDate); ok && op == "-" {
+			return v.Sub(rightDate), nil
+		}
+	case *FEELTime:
+		// times move by days-time durations only
+		if rightDur, ok := rightVal.(*FEELDuration); ok && rightDur.TotalMonths() == 0 {
+			if op == "+" {
+				return v.Add(rightDur), nil
+			} else if op == "-" {
+				return v.Add(rightDur.Negative()), nil
+			}
+		} else if rightTime, ok := rightVal.(*FEELTime); ok && op == "-" {
+			return v.Sub(rightTime), nil
+		}
+	case *FEELDuration:
+		switch rv := rightVal.(type) {
+		case *FEELDuration:
+			if op == "-" {
+				rv = rv.Negative()
+			}
+			if op == "+" || op == "-" {
+				if sum, ok := v.Add(rv); ok {
+					return sum, nil
+				}
+			}
+		case *FEELDate, *FEELTime, *FEELDatetime:
+			// the addition of a duration is commutative
+			if op == "+" {
+				return typedOp(rightVal, leftVal, nil, nil, op)
+			}
+		}
 	}
 	//return nil, NewEvalError(-3101, "invalid types", fmt.Sprintf("bad types in op, %s %s %s", typeName(leftVal), op, typeName(rightVal)))
 	return nil, NewErrBadOp(typeName(leftVal), op, typeName(rightVal))
 }
 
-func (binop Binop) addOp(intp *Interpreter) (any, error) {
-	return binop.typedOp(
-		intp,
+func addValues(leftVal, rightVal any) (any, error) {
+	return typedOp(
+		leftVal, rightVal,
 		func(a, b string) any { return a + b },
-		func(a, b *Number) any { return a.Add(b) },
+		func(a, b *Number) any { return numberOrNull(a.Add(b)) },
 		"+",
 	)
 }
 
-func (binop Binop) subOp(intp *Interpreter) (any, error) {
-	return binop.typedOp(
-		intp,
+func subValues(leftVal, rightVal any) (any, error) {
+	return typedOp(
+		leftVal, rightVal,
 		nil,
-		func(a, b *Number) any { return a.Sub(b) },
+		func(a, b *Number) any { return numberOrNull(a.Sub(b)) },
 		"-")
 }
 
-func (binop Binop) mulOp(intp *Interpreter) (any, error) {
-	return binop.numberOp(
-		intp,
-		func(a, b *Number) any { return a.Mul(b) },
+func mulValues(leftVal, rightVal any) (any, error) {
+	return numberOp(
+		leftVal, rightVal,
+		func(a, b *Number) any { return numberOrNull(a.Mul(b)) },
 		"*")
 }
 
-func (binop Binop) divOp(intp *Interpreter) (any, error) {
-	return binop.numberOp(
-		intp,
-		func(a, b *Number) any { return a.IntDiv(b) },
+// numberOrNull gives null for the nil results of number arithmetic
+func numberOrNull(n *Number) any {
+	if n == nil {
+		return Null
+	}
+	return n
+}
+
+func divValues(leftVal, rightVal any) (any, error) {
+	return numberOp(
+		leftVal, rightVal,
+		func(a, b *Number) any { return numberOrNull(a.Div(b)) },
 		"/")
 }
 
-func (binop Binop) compareGTOp(intp *Interpreter) (any, error) {
-	r, err := binop.compareValues(intp)
-	if err != nil {
-		return false, err
-	} else {
-		return r > 0, nil
-	}
+func modValues(leftVal, rightVal any) (any, error) {
+	return numberOp(
+		leftVal, rightVal,
+		func(a, b *Number) any { return numberOrNull(a.Mod(b)) },
+		"%")
 }
 
-func (binop Binop) compareGEOp(intp *Interpreter) (any, error) {
-	r, err := binop.compareValues(intp)
-	if err != nil {
-		return false, err
-	} else {
-		return r >= 0, nil
-	}
+func powValues(leftVal, rightVal any) (any, error) {
+	return numberOp(
+		leftVal, rightVal,
+		func(a, b *Number) any { return numberOrNull(a.Pow(b)) },
+		"**")
 }
 
-func (binop Binop) compareLTOp(intp *Interpreter) (any, error) {
-	r, err := binop.compareValues(intp)
+// orderValues compares the operands by the test, null operands give null
+func orderValues(leftVal, rightVal any, test func(r int) bool) (any, error) {
+	if isNull(leftVal) || isNull(rightVal) {
+		return Null, nil
+	}
+	r, err := compareInterfaces(leftVal, rightVal)
 	if err != nil {
+		var evalError *EvalError
+		if errors.As(err, &evalError) && evalError.Code == -3106 {
+			// values of different types are not ordered
+			return Null, nil
+		}
 		return false, err
-	} else {
-		return r < 0, nil
 	}
+	return test(r), nil
 }
 
-func (binop Binop) compareLEOp(intp *Interpreter) (any, error) {
-	r, err := binop.compareValues(intp)
-	if err != nil {
-		return false, err
-	} else {
-		return r <= 0, nil
+func compareGT(leftVal, rightVal any) (any, error) {
+	return orderValues(leftVal, rightVal, func(r int) bool { return r > 0 })
+}
+
+func compareGE(leftVal, rightVal any) (any, error) {
+	return orderValues(leftVal, rightVal, func(r int) bool { return r >= 0 })
+}
+
+func compareLT(leftVal, rightVal any) (any, error) {
+	return orderValues(leftVal, rightVal, func(r int) bool { return r < 0 })
+}
+
+func compareLE(leftVal, rightVal any) (any, error) {
+	return orderValues(leftVal, rightVal, func(r int) bool { return r <= 0 })
+}
+
+// null only equals null, values of different types give null like
+// they do in ordering
+func equalValues(leftVal, rightVal any) (any, error) {
+	if isNull(leftVal) || isNull(rightVal) {
+		return isNull(leftVal) && isNull(rightVal), nil
 	}
+	return orderValues(leftVal, rightVal, func(r int) bool { return r == 0 })
 }
 
-func (binop Binop) equalOp(intp *Interpreter) (any, error) {
-	r, err := binop.compareValues(intp)
-	if err != nil {
-		return false, err
-	} else {
-		return r == 0, nil
+func notEqualValues(leftVal, rightVal any) (any, error) {
+	if isNull(leftVal) || isNull(rightVal) {
+		return !(isNull(leftVal) && isNull(rightVal)), nil
 	}
+	return orderValues(leftVal, rightVal, func(r int) bool { return r != 0 })
 }
 
-func (binop Binop) notEqalOp(intp *Interpreter) (any, error) {
-	r, err := binop.compareValues(intp)
-	if err != nil {
-		var evalError *EvalError
-		if errors.As(err, &evalError) && evalError.Code == -3106 {
-			// type mismatch
-			return false, nil
-		}
-		return false, err
-	} else {
-		return r != 0, nil
+// logicalAnd and logicalOr follow the ternary logic of FEEL, operands
+// which are not booleans are taken as null
+func logicalAnd(leftVal, rightVal any) (any, error) {
+	if leftVal == false || rightVal == false {
+		return false, nil
+	} else if leftVal == true && rightVal == true {
+		return true, nil
 	}
+	return Null, nil
 }
 
-func (binop Binop) modOp(intp *Interpreter) (any, error) {
-	return binop.numberOp(
-		intp,
-		func(a, b *Number) any { return a.IntMod(b) },
-		"%")
+func logicalOr(leftVal, rightVal any) (any, error) {
+	if leftVal == true || rightVal == true {
+		return true, nil
+	} else if leftVal == false && rightVal == false {
+		return false, nil
+	}
+	return Null, nil
 }
 
-// circuit break operators
+// circuit break operators, the right operand is skipped when the left
+// one decides the result
 func (binop Binop) andOp(intp *Interpreter) (any, error) {
-	leftVal, err := binop.Left.Eval(intp)
+	leftVal, err := intp.EvalNode(binop.Left)
 	if err != nil {
 		return nil, err
 	}
-	leftBool := boolValue(leftVal)
-	if !leftBool {
+	if leftVal == false {
 		return false, nil
 	}
-	rightVal, err := binop.Right.Eval(intp)
+	rightVal, err := intp.EvalNode(binop.Right)
 	if err != nil {
 		return nil, err
 	}
-	rightBool := boolValue(rightVal)
-	return rightBool, nil
+	return logicalAnd(leftVal, rightVal)
 }
 
 func (binop Binop) orOp(intp *Interpreter) (any, error) {
-	leftVal, err := binop.Left.Eval(intp)
+	leftVal, err := intp.EvalNode(binop.Left)
 	if err != nil {
 		return nil, err
 	}
-	leftBool := boolValue(leftVal)
-	if leftBool {
+	if leftVal == true {
 		return true, nil
 	}
-	rightVal, err := binop.Right.Eval(intp)
+	rightVal, err := intp.EvalNode(binop.Right)
 	if err != nil {
 		return nil, err
 	}
-	rightBool := boolValue(rightVal)
-	return rightBool, nil
+	return logicalOr(leftVal, rightVal)
 }
 
-func (binop Binop) indexAtOp(intp *Interpreter) (any, error) {
-	leftVal, err := binop.Left.Eval(intp)
-	if err != nil {
-		return nil, err
-	}
-	rightVal, err := binop.Right.Eval(intp)
-	if err != nil {
-		return nil, err
-	}
+func indexAt(leftVal, rightVal any) (any, error) {
 	switch v := leftVal.(type) {
 	case []any:
 		if nRight, ok := rightVal.(*Number); ok {
@@ -382,27 +453,43 @@ func (binop Binop) indexAtOp(intp *Interpreter) (any, error) {
 			//return nil, NewEvalError(-3200, "non string index")
 			return nil, NewErrIndex("non string index")
 		}
+	case *lazyContext:
+		if strRight, ok := rightVal.(string); ok {
+			if elem, found, err := v.Resolve(strRight); err != nil {
+				return nil, err
+			} else if found {
+				return elem, nil
+			} else {
+				return nil, NewErrKeyNotFound(strRight)
+			}
+		} else {
+			return nil, NewErrIndex("non string index")
+		}
 	default:
 		//return nil, NewEvalError(-3202, "non indexable value")
 		return nil, NewErrIndex("non-indexable value")
 	}
 }
 
-func (binop Binop) inOp(intp *Interpreter) (any, error) {
-	leftVal, err := binop.Left.Eval(intp)
-	if err != nil {
-		return nil, err
-	}
-	rightVal, err := binop.Right.Eval(intp)
-	if err != nil {
-		return nil, err
-	}
+func inValues(leftVal, rightVal any) (any, error) {
 	switch rv := rightVal.(type) {
 	case *RangeValue:
-		return rv.Contains(leftVal), nil
+		if isNull(leftVal) {
+			return nil, undefined(NewEvalError(-3106, "invalid types", "null in range"))
+		}
+		in, err := rv.Contains(leftVal)
+		if err != nil {
+			// values of other types are not known to be in the range
+			var evalErr *EvalError
+			if errors.As(err, &evalErr) {
+				return nil, undefined(evalErr)
+			}
+			return nil, err
+		}
+		return in, nil
 	case []any:
 		for _, kv := range rv {
-			if cmp.Equal(leftVal, kv) {
+			if valuesEqual(leftVal, kv) {
 				return true, nil
 			}
 		}
@@ -411,3 +498,23 @@ func (binop Binop) inOp(intp *Interpreter) (any, error) {
 		return nil, NewErrBadOp(typeName(leftVal), "in", typeName(rightVal))
 	}
 }
+
+// valuesEqual compares scalars directly and other values deeply
+func valuesEqual(a, b any) bool {
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+		return ok && av == bv
+	case bool:
+		bv, ok := b.(bool)
+		return ok && av == bv
+	case *Number:
+		if bv, ok := b.(*Number); ok {
+			return av.Equal(*bv)
+		}
+	case *FEELDate, *FEELTime, *FEELDatetime, *FEELDuration:
+		r, err := compareInterfaces(a, b)
+		return err == nil && r == 0
+	}
+	return cmp.Equal(a, b)
+}
@@ -0,0 +1,426 @@
+package feel
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FormatOptions configures the printer of FEEL source
+type FormatOptions struct {
+	// the number of spaces per indentation level, 2 when it's not positive
+	Indent int
+	// the width beyond which contexts, lists, calls and if expressions
+	// are broken into lines, 80 when it's not positive
+	LineWidth int
+}
+
+// Format prints the node as canonical FEEL source, which parses to an
+// equal AST. Operands are parenthesized only when the precedence
+// requires it and constructs which don't fit in the line width are
+// broken into lines.
+func Format(node Node, opts FormatOptions) string {
+	if opts.Indent <= 0 {
+		opts.Indent = 2
+	}
+	if opts.LineWidth <= 0 {
+		opts.LineWidth = 80
+	}
+	f := &formatter{opts: opts}
+	return f.format(node, 0, 0, true)
+}
+
+// FormatString parses the input and prints it in the canonical form,
+// comments are not kept
+func FormatString(input string, opts FormatOptions) (string, error) {
+	parser := NewParser(NewScanner(input))
+	node, err := parser.Parse()
+	if err != nil {
+		return "", err
+	}
+	// the trailing tokens would be lost
+	if !parser.CurrentToken().Expect(TokenEOF) {
+		return "", parser.Unexpected(TokenEOF)
+	}
+	return Format(node, opts), nil
+}
+
+// operator precedences from the loosest to the tightest
+const (
+	// if, for, some, every and function extend to the end of the input
+	precOpenEnded = iota
+	precIn
+	precOr
+	precAnd
+	precCompare
+	precAddSub
+	precMulDiv
+	precPow
+	precPostfix
+	precAtom
+)
+
+func binopPrecedence(op string) int {
+	switch op {
+	case "in":
+		return precIn
+	case "or":
+		return precOr
+	case "and":
+		return precAnd
+	case ">", ">=", "<", "<=", "!=", "=":
+		return precCompare
+	case "+", "-":
+		return precAddSub
+	case "*", "/", "%":
+		return precMulDiv
+	case "**":
+		return precPow
+	case "[]":
+		return precPostfix
+	}
+	return precAtom
+}
+
+func precedence(node Node) int {
+	switch v := node.(type) {
+	case *Binop:
+		return binopPrecedence(v.Op)
+	case *DotOp, *FunCall:
+		return precPostfix
+	case *IfExpr, *ForExpr, *SomeExpr, *EveryExpr, *FunDef, *MultiTests:
+		return precOpenEnded
+	}
+	return precAtom
+}
+
+var nameWordPattern = regexp.MustCompile(`^[a-zA-Z_\$\p{Han}\p{Greek}\p{Bopomofo}\p{Hangul}][a-zA-Z_\$0-9\p{Han}\p{Greek}\p{Bopomofo}\p{Hangul}]*$`)
+
+var keywordPattern = regexp.MustCompile(`^(true|false|and|or|null|function|if|then|else|loop|for|some|every|in|return|satisfies)$`)
+
+// isNameKey tells whether the context key can be written as a name
+func isNameKey(key string) bool {
+	words := strings.Split(key, " ")
+	for _, word := range words {
+		if !nameWordPattern.MatchString(word) {
+			return false
+		}
+	}
+	return !keywordPattern.MatchString(words[0])
+}
+
+// quoteString writes the string as a FEEL string literal, which
+// StringNode.Content reads back
+func quoteString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+type formatter struct {
+	opts FormatOptions
+}
+
+func (f *formatter) fits(col int, s string) bool {
+	return col+len(s) <= f.opts.LineWidth && !strings.Contains(s, "\n")
+}
+
+// format prints the node starting at the column col of a line indented
+// by ind, top tells whether the node is the whole input, where unary
+// tests are allowed
+func (f *formatter) format(node Node, ind, col int, top bool) string {
+	if flat := f.flat(node, top); f.fits(col, flat) {
+		return flat
+	}
+	return f.broken(node, ind, col, top)
+}
+
+// flat prints the node in a single line
+func (f *formatter) flat(node Node, top bool) string {
+	switch v := node.(type) {
+	case *Binop:
+		return f.binop(v, 0, 0, top, f.flatOperand)
+	case *DotOp:
+		return f.postfixBase(v.Left, 0, 0, f.flatOperand) + "." + v.Attr
+	case *FunCall:
+		args := make([]string, len(v.Args))
+		for i, arg := range v.Args {
+			args[i] = f.callArg(v, arg, 0, 0, f.flatOperand)
+		}
+		return f.postfixBase(v.FunRef, 0, 0, f.flatOperand) + "(" + strings.Join(args, ", ") + ")"
+	case *MapNode:
+		entries := make([]string, len(v.Values))
+		for i, item := range v.Values {
+			entries[i] = mapKey(item.Name) + ": " + f.flat(item.Value, false)
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case *ArrayNode:
+		elems := make([]string, len(v.Elements))
+		for i, elem := range v.Elements {
+			elems[i] = f.flat(elem, false)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case *MultiTests:
+		elems := make([]string, len(v.Elements))
+		for i, elem := range v.Elements {
+			elems[i] = f.flat(elem, true)
+		}
+		return strings.Join(elems, ", ")
+	case *RangeNode:
+		return f.rangeNode(v, 0, 0, f.flatOperand)
+	case *IfExpr:
+		return "if " + f.flat(v.Cond, false) + " then " + f.flat(v.ThenBranch, false) + " else " + f.flat(v.ElseBranch, false)
+	case *ForExpr:
+		return "for " + v.Varname + " in " + f.flat(v.ListExpr, false) + " return " + f.flat(v.ReturnExpr, false)
+	case *SomeExpr:
+		return "some " + v.Varname + " in " + f.flat(v.ListExpr, false) + " satisfies " + f.flat(v.FilterExpr, false)
+	case *EveryExpr:
+		return "every " + v.Varname + " in " + f.flat(v.ListExpr, false) + " satisfies " + f.flat(v.FilterExpr, false)
+	case *FunDef:
+		return "function(" + strings.Join(v.Args, ", ") + ") " + f.flat(v.Body, false)
+	case *Var:
+		return v.Name
+	case *NumberNode:
+		return v.Value
+	case *StringNode:
+		return v.Value
+	case *TemporalNode:
+		return v.Value
+	case *EmptyNode:
+		return ""
+	}
+	return node.Repr()
+}
+
+// broken prints the node in lines as it doesn't fit in the line
+func (f *formatter) broken(node Node, ind, col int, top bool) string {
+	inner := ind + f.opts.Indent
+	innerPad := strings.Repeat(" ", inner)
+	pad := strings.Repeat(" ", ind)
+	switch v := node.(type) {
+	case *Binop:
+		return f.binop(v, ind, col, top, f.operand)
+	case *DotOp:
+		return f.postfixBase(v.Left, ind, col, f.operand) + "." + v.Attr
+	case *FunCall:
+		ref := f.postfixBase(v.FunRef, ind, col, f.operand)
+		if len(v.Args) == 0 {
+			return ref + "()"
+		} else if len(v.Args) == 1 && !v.KeywordArgs {
+			// a single list or context hugs the parentheses
+			switch v.Args[0].Arg.(type) {
+			case *ArrayNode, *MapNode:
+				ref += "("
+				return ref + f.format(v.Args[0].Arg, lineIndent(ind, ref), column(col, ref), false) + ")"
+			}
+		}
+		args := make([]string, len(v.Args))
+		for i, arg := range v.Args {
+			args[i] = innerPad + f.callArg(v, arg, inner, inner, f.operand)
+		}
+		return ref + "(\n" + strings.Join(args, ",\n") + "\n" + pad + ")"
+	case *MapNode:
+		if len(v.Values) == 0 {
+			return "{}"
+		}
+		entries := make([]string, len(v.Values))
+		for i, item := range v.Values {
+			key := mapKey(item.Name) + ": "
+			entries[i] = innerPad + key + f.format(item.Value, inner, inner+len(key), false)
+		}
+		return "{\n" + strings.Join(entries, ",\n") + "\n" + pad + "}"
+	case *ArrayNode:
+		if len(v.Elements) == 0 {
+			return "[]"
+		}
+		elems := make([]string, len(v.Elements))
+		for i, elem := range v.Elements {
+			elems[i] = innerPad + f.format(elem, inner, inner, false)
+		}
+		return "[\n" + strings.Join(elems, ",\n") + "\n" + pad + "]"
+	case *MultiTests:
+		elems := make([]string, len(v.Elements))
+		for i, elem := range v.Elements {
+			elems[i] = f.format(elem, ind, ind, true)
+		}
+		return strings.Join(elems, ",\n"+pad)
+	case *RangeNode:
+		return f.rangeNode(v, ind, col, f.operand)
+	case *IfExpr:
+		// else if chains are kept at the same level
+		var sb strings.Builder
+		for {
+			sb.WriteString("if " + f.format(v.Cond, ind, col+3, false) + " then\n")
+			sb.WriteString(innerPad + f.format(v.ThenBranch, inner, inner, false) + "\n" + pad + "else")
+			if elseIf, ok := v.ElseBranch.(*IfExpr); ok {
+				v = elseIf
+				col = ind + 5
+				sb.WriteString(" ")
+				continue
+			}
+			sb.WriteString("\n" + innerPad + f.format(v.ElseBranch, inner, inner, false))
+			return sb.String()
+		}
+	case *ForExpr:
+		head := "for " + v.Varname + " in "
+		head += f.format(v.ListExpr, ind, col+len(head), false)
+		return head + "\n" + pad + "return " + f.format(v.ReturnExpr, ind, ind+7, false)
+	case *SomeExpr:
+		head := "some " + v.Varname + " in "
+		head += f.format(v.ListExpr, ind, col+len(head), false)
+		return head + "\n" + pad + "satisfies " + f.format(v.FilterExpr, ind, ind+10, false)
+	case *EveryExpr:
+		head := "every " + v.Varname + " in "
+		head += f.format(v.ListExpr, ind, col+len(head), false)
+		return head + "\n" + pad + "satisfies " + f.format(v.FilterExpr, ind, ind+10, false)
+	case *FunDef:
+		return "function(" + strings.Join(v.Args, ", ") + ")\n" + innerPad + f.format(v.Body, inner, inner, false)
+	}
+	return f.flat(node, top)
+}
+
+// operandFunc prints an operand at the position
+type operandFunc func(node Node, ind, col int) string
+
+func (f *formatter) flatOperand(node Node, ind, col int) string {
+	return f.flat(node, false)
+}
+
+func (f *formatter) operand(node Node, ind, col int) string {
+	return f.format(node, ind, col, false)
+}
+
+// parens prints the node with parentheses when its precedence is lower
+// than the required one
+func (f *formatter) parens(node Node, prec int, ind, col int, print operandFunc) string {
+	if precedence(node) < prec {
+		return "(" + print(node, ind, col+1) + ")"
+	}
+	return print(node, ind, col)
+}
+
+func (f *formatter) postfixBase(node Node, ind, col int, print operandFunc) string {
+	return f.parens(node, precPostfix, ind, col, print)
+}
+
+// column returns the column following the printed text
+func column(col int, s string) int {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return len(s) - i - 1
+	}
+	return col + len(s)
+}
+
+// lineIndent returns the indentation of the line following the printed
+// text
+func lineIndent(ind int, s string) int {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		line := s[i+1:]
+		return len(line) - len(strings.TrimLeft(line, " "))
+	}
+	return ind
+}
+
+func (f *formatter) binop(v *Binop, ind, col int, top bool, print operandFunc) string {
+	if v.Op == "[]" {
+		base := f.postfixBase(v.Left, ind, col, print) + "["
+		return base + print(v.Right, lineIndent(ind, base), column(col, base)) + "]"
+	}
+	if top && isUnaryTest(v) {
+		return v.Op + " " + print(v.Right, ind, col+len(v.Op)+1)
+	}
+
+	prec := binopPrecedence(v.Op)
+	var left string
+	if name, ok := trailingName(v.Left, prec); ok && continuesPreludeName(strings.Fields(name), v.Op) {
+		// the trailing name would take the keyword as a part of it
+		left = "(" + print(v.Left, ind, col+1) + ")"
+	} else {
+		left = f.parens(v.Left, prec, ind, col, print)
+	}
+	left += " " + v.Op + " "
+	return left + f.parens(v.Right, prec+1, lineIndent(ind, left), column(col, left), print)
+}
+
+// isUnaryTest tells whether the binop is a test such as > 5 on the
+// input value ?
+func isUnaryTest(v *Binop) bool {
+	if left, ok := v.Left.(*Var); !ok || left.Name != "?" {
+		return false
+	}
+	switch v.Op {
+	case ">", ">=", "<", "<=", "!=", "=":
+	default:
+		return false
+	}
+	switch v.Right.(type) {
+	case *Var, *NumberNode, *StringNode, *TemporalNode:
+		return true
+	}
+	return false
+}
+
+// trailingName returns the name the printed node ends with, prec is the
+// precedence of the enclosing operator
+func trailingName(node Node, prec int) (string, bool) {
+	switch v := node.(type) {
+	case *Var:
+		return v.Name, precedence(node) >= prec
+	case *DotOp:
+		return v.Attr, precedence(node) >= prec
+	case *Binop:
+		if v.Op == "[]" || precedence(node) < prec {
+			return "", false
+		}
+		return trailingName(v.Right, binopPrecedence(v.Op)+1)
+	}
+	return "", false
+}
+
+func (f *formatter) callArg(call *FunCall, arg FunCallArg, ind, col int, print operandFunc) string {
+	if call.KeywordArgs {
+		return arg.Name + ": " + print(arg.Arg, ind, col+len(arg.Name)+2)
+	}
+	return print(arg.Arg, ind, col)
+}
+
+// mapKey writes the context key as a name when possible
+func mapKey(key string) string {
+	if isNameKey(key) {
+		return key
+	}
+	return quoteString(key)
+}
+
+func (f *formatter) rangeNode(v *RangeNode, ind, col int, print operandFunc) string {
+	startQuote, endQuote := "[", "]"
+	if v.StartOpen {
+		startQuote = "("
+	}
+	if v.EndOpen {
+		endQuote = ")"
+	}
+	start := startQuote + print(v.Start, ind, col+1) + ".."
+	return start + print(v.End, lineIndent(ind, start), column(col, start)) + endQuote
+}
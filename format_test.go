@@ -0,0 +1,155 @@
+package feel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertRoundTrip(assert *assert.Assertions, input string, opts FormatOptions) string {
+	ast, err := ParseString(input)
+	if !assert.Nil(err, input) {
+		return ""
+	}
+	formatted := Format(ast, opts)
+	reparsed, err := ParseString(formatted)
+	assert.Nil(err, "%s formatted as\n%s", input, formatted)
+	if err == nil {
+		assert.Equal(ast.Repr(), reparsed.Repr(), "%s formatted as\n%s", input, formatted)
+		// formatting is idempotent
+		assert.Equal(formatted, Format(reparsed, opts), input)
+	}
+	return formatted
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, p := range evalPairs {
+		assertRoundTrip(assert, p.input, FormatOptions{})
+		// every construct is broken into lines in a narrow width
+		assertRoundTrip(assert, p.input, FormatOptions{LineWidth: 10})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{`(1+2)*3`, `(1 + 2) * 3`},
+		{`1+(2*3)`, `1 + 2 * 3`},
+		{`(1 - 2) - 3`, `1 - 2 - 3`},
+		{`1 - (2 - 3)`, `1 - (2 - 3)`},
+		{`a - -1`, `a - -1`},
+		{`(2 ** 3) ** 2 * 4`, `2 ** 3 ** 2 * 4`},
+		{`2 ** (3 ** 2)`, `2 ** (3 ** 2)`},
+		{`(a > b) and c`, `a > b and c`},
+		{`(a) or (b.c)`, `a or b.c`},
+		{`f(x) and ((y) or z)`, `f(x) and (y or z)`},
+		// names continuing into prelude names take the keyword
+		{`(date) and time`, `(date) and time`},
+		{`date and time("2024-01-01T10:00:00")`, `date and time("2024-01-01T10:00:00")`},
+		{`(a + b).c[1]`, `(a + b).c[1]`},
+		{`(function(x) x * 2)(3)`, `(function(x) x * 2)(3)`},
+		{`(if a then 1 else 2) + 3`, `(if a then 1 else 2) + 3`},
+		{`{"a b": 1, "x-y": 2, "if": 3, c: [1..5)}`, `{a b: 1, "x-y": 2, "if": 3, c: [1..5)}`},
+		{`substring(string:"abc",start position:2)`, `substring(string: "abc", start position: 2)`},
+		{`>8,<=  5`, `> 8, <= 5`},
+		{`for x in [1,2], y in [3] return x*y`, `for x in [1, 2] return for y in [3] return x * y`},
+		{`some x in l satisfies x>1`, `some x in l satisfies x > 1`},
+	}
+	for _, c := range cases {
+		assert.Equal(c.expect, assertRoundTrip(assert, c.input, FormatOptions{}), c.input)
+	}
+}
+
+func TestFormatLineBreaks(t *testing.T) {
+	assert := assert.New(t)
+
+	input := `if score > 700 then {level: "gold", limit: 50000, rate: 0.05} else if score > 600 then {level: "silver", limit: 20000, rate: 0.07} else null`
+	expect := `if score > 700 then
+  {level: "gold", limit: 50000, rate: 0.05}
+else if score > 600 then
+  {level: "silver", limit: 20000, rate: 0.07}
+else
+  null`
+	assert.Equal(expect, assertRoundTrip(assert, input, FormatOptions{}))
+
+	expect4 := `if score > 700 then
+    {
+        level: "gold",
+        limit: 50000,
+        rate: 0.05
+    }
+else if score > 600 then
+    {
+        level: "silver",
+        limit: 20000,
+        rate: 0.07
+    }
+else
+    null`
+	assert.Equal(expect4, assertRoundTrip(assert, input, FormatOptions{Indent: 4, LineWidth: 40}))
+
+	input = `{applicant: {name: "Alice Smith", age: 35, loans: [12000, 35000, 800]}, approved: sum(applicant.loans) < 50000}`
+	expect = `{
+  applicant: {name: "Alice Smith", age: 35, loans: [12000, 35000, 800]},
+  approved: sum(applicant.loans) < 50000
+}`
+	assert.Equal(expect, assertRoundTrip(assert, input, FormatOptions{}))
+
+	expect = `{
+  applicant: {
+    name: "Alice Smith",
+    age: 35,
+    loans: [
+      12000,
+      35000,
+      800
+    ]
+  },
+  approved: sum(
+    applicant.loans
+  ) < 50000
+}`
+	assert.Equal(expect, assertRoundTrip(assert, input, FormatOptions{LineWidth: 20}))
+
+	// a single list argument hugs the parentheses
+	input = `count([1000, 2000, 3000]) > 2`
+	expect = `count([
+  1000,
+  2000,
+  3000
+]) > 2`
+	assert.Equal(expect, assertRoundTrip(assert, input, FormatOptions{LineWidth: 20}))
+}
+
+func TestFormatRewritten(t *testing.T) {
+	assert := assert.New(t)
+
+	ast, err := ParseString(`a + b`)
+	assert.Nil(err)
+	// the operands are parenthesized by the precedence in the rewritten AST
+	rewritten := Rewrite(ast, func(node Node) Node {
+		if v, ok := node.(*Var); ok && v.Name == "b" {
+			return &Binop{Op: "*", Left: &Binop{Op: "-", Left: v, Right: &NumberNode{Value: "1"}}, Right: &Var{Name: "c"}}
+		}
+		return node
+	})
+	assert.Equal(`a + (b - 1) * c`, Format(rewritten, FormatOptions{}))
+}
+
+func TestFormatString(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := FormatString("if a>1 then\n\"x\" // comment\nelse \"y\"", FormatOptions{})
+	assert.Nil(err)
+	assert.Equal(`if a > 1 then "x" else "y"`, s)
+
+	// the input must be parsed entirely
+	_, err = FormatString(`a -1`, FormatOptions{})
+	assert.NotNil(err)
+}
@@ -0,0 +1,106 @@
+package feel
+
+// printing runtime values as FEEL literals
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// FormatValue prints the value as FEEL source, evaluating which gives
+// an equal value. Go values are normalized by ToFEELValue first.
+// Contexts are printed in the order of keys, native functions by their
+// names in the prelude, and lazy contexts, anonymous native functions
+// and values of other types are not printable.
+func FormatValue(v any) (string, error) {
+	var sb strings.Builder
+	if err := writeValue(&sb, ToFEELValue(v)); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func writeValue(sb *strings.Builder, v any) error {
+	switch vv := v.(type) {
+	case *NullValue:
+		sb.WriteString("null")
+	case bool:
+		if vv {
+			sb.WriteString("true")
+		} else {
+			sb.WriteString("false")
+		}
+	case *Number:
+		sb.WriteString(vv.String())
+	case string:
+		sb.WriteString(quoteString(vv))
+	case *FEELDate, *FEELTime, *FEELDatetime, *FEELDuration:
+		fmt.Fprintf(sb, "@\"%s\"", vv)
+	case []any:
+		sb.WriteString("[")
+		for i, elem := range vv {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if err := writeValue(sb, elem); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("]")
+	case map[string]any:
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sb.WriteString("{")
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(mapKey(k))
+			sb.WriteString(": ")
+			if err := writeValue(sb, vv[k]); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("}")
+	case *lazyContext:
+		ctx, err := vv.materialize()
+		if err != nil {
+			return err
+		}
+		return writeValue(sb, ctx)
+	case *RangeValue:
+		if vv.StartOpen {
+			sb.WriteString("(")
+		} else {
+			sb.WriteString("[")
+		}
+		if err := writeValue(sb, ToFEELValue(vv.Start)); err != nil {
+			return err
+		}
+		sb.WriteString("..")
+		if err := writeValue(sb, ToFEELValue(vv.End)); err != nil {
+			return err
+		}
+		if vv.EndOpen {
+			sb.WriteString(")")
+		} else {
+			sb.WriteString("]")
+		}
+	case *FunDef:
+		sb.WriteString(Format(vv, FormatOptions{LineWidth: math.MaxInt}))
+	case *NativeFun, *Macro:
+		name, ok := GetPrelude().nameOf(vv)
+		if !ok {
+			return fmt.Errorf("cannot print anonymous %T", v)
+		}
+		sb.WriteString(name)
+	default:
+		return fmt.Errorf("cannot print %T as FEEL literal", v)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package feel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatValue(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{`5.000`, `5`},
+		{`-2.50`, `-2.5`},
+		{`0.1 + 0.2`, `0.3`},
+		{`"say \"hi\"\n\tand \\ bye"`, `"say \"hi\"\n\tand \\ bye"`},
+		{`[true, false, null]`, `[true, false, null]`},
+		{`{z: 1, "a b": {c: [1, 2]}, "x-y": "", "if": 2}`, `{a b: {c: [1, 2]}, "if": 2, "x-y": "", z: 1}`},
+		{`(1..5]`, `(1..5]`},
+		{`["a".."z")`, `["a".."z")`},
+		{`@"2023-06-07"`, `@"2023-06-07"`},
+		{`@"2023-06-07T10:20:30"`, `@"2023-06-07T10:20:30"`},
+		{`@"2023-06-07T10:20:30.250+08:00"`, `@"2023-06-07T10:20:30.25+08:00"`},
+		{`@"10:20:30-05:00"`, `@"10:20:30-05:00"`},
+		{`@"P1D"`, `@"P1D"`},
+		{`@"PT2H30M"`, `@"PT2H30M"`},
+		{`@"-P1Y2M"`, `@"-P1Y2M"`},
+		{`@"2023-06-07T10:00:00" - @"2023-06-07T10:00:00"`, `@"PT0S"`},
+		{`@"2023-06-07T10:00:00" - @"2023-06-08T11:00:00"`, `@"-P1DT1H"`},
+		{`function(a, b) a + b * 2`, `function(a, b) a + b * 2`},
+		{`string length`, `string length`},
+	}
+	for _, c := range cases {
+		v, err := EvalString(c.input)
+		if !assert.Nil(err, c.input) {
+			continue
+		}
+		s, err := FormatValue(v)
+		assert.Nil(err, c.input)
+		assert.Equal(c.expect, s, c.input)
+
+		// evaluating the printed text gives the value printed the same
+		reparsed, err := EvalString(s)
+		if assert.Nil(err, s) {
+			again, err := FormatValue(reparsed)
+			assert.Nil(err, s)
+			assert.Equal(s, again)
+		}
+	}
+}
+
+func TestFormatValueGo(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := FormatValue(map[string]any{"n": 2.5, "l": []any{1, "x", nil}, "ok": true})
+	assert.Nil(err)
+	assert.Equal(`{l: [1, "x", null], n: 2.5, ok: true}`, s)
+
+	s, err = FormatValue(&RangeValue{Start: 1, End: 5, EndOpen: true})
+	assert.Nil(err)
+	assert.Equal(`[1..5)`, s)
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err == nil {
+		s, err = FormatValue(&FEELDatetime{t: time.Date(2023, 6, 7, 10, 0, 0, 0, paris)})
+		assert.Nil(err)
+		assert.Equal(`@"2023-06-07T10:00:00@Europe/Paris"`, s)
+
+		dt, err := ParseDatetime("2023-06-07T10:00:00@Europe/Paris")
+		assert.Nil(err)
+		assert.Equal("Europe/Paris", dt.Time().Location().String())
+		_, offset := dt.Time().Zone()
+		assert.Equal(2*3600, offset)
+	}
+
+	// zones without IDs are printed as offsets
+	s, err = FormatValue(&FEELTime{t: time.Date(0, 1, 1, 8, 30, 0, 0, time.FixedZone("", 5*3600+1800))})
+	assert.Nil(err)
+	assert.Equal(`@"08:30:00+05:30"`, s)
+
+	_, err = FormatValue(NewNativeFunc(func(args map[string]any) (any, error) { return nil, nil }))
+	assert.NotNil(err)
+
+	_, err = FormatValue(make(chan int))
+	assert.NotNil(err)
+}
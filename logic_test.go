@@ -0,0 +1,167 @@
+package feel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+// evalBoth evaluates the input by the tree walker and by the VM, the
+// results must agree
+func evalBoth(assert *assert.Assertions, input string, scope Scope) any {
+	res, err := EvalStringWithScope(input, scope)
+	assert.Nil(err, input)
+
+	prog, err := CompileString(input)
+	assert.Nil(err, input)
+	vmRes, err := prog.RunWithScope(scope)
+	assert.Nil(err, input)
+	assert.True(cmp.Equal(res, vmRes), "%s gives different results by the VM", input)
+	return res
+}
+
+func TestThreeValuedLogic(t *testing.T) {
+	assert := assert.New(t)
+
+	// the truth tables of and, or and not
+	values := []string{"true", "false", "null"}
+	andTable := [][]any{
+		{true, false, Null},
+		{false, false, false},
+		{Null, false, Null},
+	}
+	orTable := [][]any{
+		{true, true, true},
+		{true, false, Null},
+		{true, Null, Null},
+	}
+	notTable := []any{false, true, Null}
+	for i, a := range values {
+		for j, b := range values {
+			assert.Equal(andTable[i][j], evalBoth(assert, a+" and "+b, nil), "%s and %s", a, b)
+			assert.Equal(orTable[i][j], evalBoth(assert, a+" or "+b, nil), "%s or %s", a, b)
+		}
+		assert.Equal(notTable[i], evalBoth(assert, "not("+a+")", nil), "not(%s)", a)
+	}
+
+	// the same with variable operands
+	scopeValues := []any{true, false, Null}
+	for i := range values {
+		for j := range values {
+			scope := Scope{"eligible": scopeValues[i], "premium": scopeValues[j]}
+			assert.Equal(andTable[i][j], evalBoth(assert, `eligible and premium`, scope), "%s and %s", values[i], values[j])
+			assert.Equal(orTable[i][j], evalBoth(assert, `eligible or premium`, scope), "%s or %s", values[i], values[j])
+			assert.Equal(orTable[i][j], evalBoth(assert, `c.eligible or c.premium`, Scope{"c": scope}), "%s or %s", values[i], values[j])
+		}
+	}
+	assert.Equal(true, evalBoth(assert, `date and time("2024-01-01T10:00:00") > date and time("2024-01-01T09:00:00") and ok`, Scope{"ok": true}))
+
+	// non-boolean operands are treated as null
+	assert.Equal(Null, evalBoth(assert, `true and "yes"`, nil))
+	assert.Equal(Null, evalBoth(assert, `false or 1`, nil))
+	assert.Equal(false, evalBoth(assert, `0 and false`, nil))
+	assert.Equal(true, evalBoth(assert, `[] or true`, nil))
+
+	// the right operand isn't evaluated when the left one decides
+	assert.Equal(false, evalBoth(assert, `false and missing.attr > 2`, nil))
+	assert.Equal(true, evalBoth(assert, `true or missing.attr > 2`, nil))
+}
+
+func TestNullComparisons(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(Null, evalBoth(assert, `1 > null`, nil))
+	assert.Equal(Null, evalBoth(assert, `null <= 2`, nil))
+	assert.Equal(Null, evalBoth(assert, `x < 3`, Scope{"x": Null}))
+	assert.Equal(true, evalBoth(assert, `null = null`, nil))
+	assert.Equal(false, evalBoth(assert, `1 = null`, nil))
+	assert.Equal(true, evalBoth(assert, `"a" != null`, nil))
+	assert.Equal(false, evalBoth(assert, `null != null`, nil))
+	assert.Equal(Null, evalBoth(assert, `1 < 2 and 3 < null`, nil))
+	assert.Equal(true, evalBoth(assert, `1 < 2 or 3 < null`, nil))
+
+	// values of different types are not ordered
+	assert.Equal(Null, evalBoth(assert, `1 < "a"`, nil))
+	assert.Equal(Null, evalBoth(assert, `"a" >= 1`, nil))
+	assert.Equal(Null, evalBoth(assert, `true > 0`, nil))
+	assert.Equal(Null, evalBoth(assert, `@"2024-01-01" <= 3`, nil))
+	assert.Equal(Null, evalBoth(assert, `x > 8`, Scope{"x": "9"}))
+	assert.Equal(false, evalBoth(assert, `1 > "a" and false`, nil))
+
+	// nor equal, = and != agree with the ordering
+	assert.Equal(Null, evalBoth(assert, `1 = "1"`, nil))
+	assert.Equal(Null, evalBoth(assert, `1 != "1"`, nil))
+	assert.Equal(Null, evalBoth(assert, `[1, 2] = [1, "2"]`, nil))
+	assert.Equal(Null, evalBoth(assert, `@"2024-01-01" = @"2024-01-01T00:00:00"`, nil))
+	assert.Equal(false, evalBoth(assert, `x = "1"`, Scope{"x": Null}))
+
+	// points not comparable with the range are not known to be in it
+	assert.Equal(Null, evalBoth(assert, `x in [1..5]`, Scope{"x": Null}))
+	assert.Equal(Null, evalBoth(assert, `x in [1..5]`, nil))
+	assert.Equal(Null, evalBoth(assert, `x in [1..5]`, Scope{"x": "a"}))
+	assert.Equal(true, evalBoth(assert, `x in [1..5]`, Scope{"x": 3}))
+	assert.Equal(Null, evalBoth(assert, `[1..5], 7`, Scope{"?": "a"}))
+}
+
+func TestTemporalComparisons(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(true, evalBoth(assert, `@"2020-01-01" < @"2020-02-01"`, nil))
+	assert.Equal(false, evalBoth(assert, `@"2020-01-01" >= @"2020-02-01"`, nil))
+	assert.Equal(true, evalBoth(assert, `date(2020, 1, 1) = @"2020-01-01"`, nil))
+	assert.Equal(true, evalBoth(assert, `@"2020-01-01" != @"2020-01-02"`, nil))
+	assert.Equal(true, evalBoth(assert, `@"2020-01-15" in [@"2020-01-01"..@"2020-02-01")`, nil))
+	assert.Equal(true, evalBoth(assert, `@"2020-01-15" in [@"2020-01-01", @"2020-01-15"]`, nil))
+	assert.Equal("2021-03-01", evalBoth(assert, `string(max([@"2020-01-01", @"2021-03-01"]))`, nil))
+
+	// days-time durations compare by their spans and years-months
+	// durations by their months
+	assert.Equal(true, evalBoth(assert, `@"P1D" = @"P1D"`, nil))
+	assert.Equal(true, evalBoth(assert, `@"P1D" = @"PT24H"`, nil))
+	assert.Equal(false, evalBoth(assert, `@"P1D" != @"PT24H"`, nil))
+	assert.Equal(true, evalBoth(assert, `@"PT90M" > @"PT1H"`, nil))
+	assert.Equal(true, evalBoth(assert, `@"-PT1H" < @"PT1S"`, nil))
+	assert.Equal(true, evalBoth(assert, `@"P1Y" = @"P12M"`, nil))
+	assert.Equal(true, evalBoth(assert, `@"P1Y" > @"P11M"`, nil))
+	assert.Equal(true, evalBoth(assert, `@"P1D" in [@"PT1H", @"P1D"]`, nil))
+	// a month has no fixed number of days
+	assert.Equal(Null, evalBoth(assert, `@"P1M" < @"P40D"`, nil))
+	assert.Equal(Null, evalBoth(assert, `@"P1M" = @"P30D"`, nil))
+}
+
+func TestThreeValuedConditions(t *testing.T) {
+	assert := assert.New(t)
+
+	// only true takes the then branch
+	assert.Equal("else", evalBoth(assert, `if null then "then" else "else"`, nil))
+	assert.Equal("else", evalBoth(assert, `if "yes" then "then" else "else"`, nil))
+	assert.Equal("else", evalBoth(assert, `if x > 1 then "then" else "else"`, Scope{"x": Null}))
+	assert.Equal("then", evalBoth(assert, `if x > 1 then "then" else "else"`, Scope{"x": 2}))
+
+	// quantifiers only take the items satisfying with true
+	assert.True(cmp.Equal(N(3), evalBoth(assert, `some x in [null, 1, 3] satisfies x > 2`, nil)))
+	assert.True(cmp.Equal([]any{N(3)}, evalBoth(assert, `every x in [null, 1, 3] satisfies x > 2`, nil)))
+
+	assert.Equal(Null, evalBoth(assert, `all([true, null])`, nil))
+	assert.Equal(false, evalBoth(assert, `all([null, false])`, nil))
+	assert.Equal(Null, evalBoth(assert, `any([false, null])`, nil))
+	assert.Equal(true, evalBoth(assert, `any([null, true])`, nil))
+}
+
+func TestThreeValuedUnaryTests(t *testing.T) {
+	assert := assert.New(t)
+
+	input := `> 8, <= 5`
+	assert.Equal(true, evalBoth(assert, input, Scope{"?": 4}))
+	assert.Equal(false, evalBoth(assert, input, Scope{"?": 7}))
+	// no test is true and some are null
+	assert.Equal(Null, evalBoth(assert, input, Scope{"?": Null}))
+
+	// a true test decides even if other tests are null
+	assert.Equal(true, evalBoth(assert, `null, > 8, 9`, Scope{"?": 9}))
+	assert.Equal(false, evalBoth(assert, `[1..5], 7`, Scope{"?": 6}))
+	assert.Equal(true, evalBoth(assert, `[1..5], 7`, Scope{"?": 3}))
+	assert.Equal(Null, evalBoth(assert, `[1..5], 7`, Scope{"?": Null}))
+	assert.Equal(Null, evalBoth(assert, `> 8, <= 5`, Scope{"?": "a"}))
+}
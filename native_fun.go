@@ -28,18 +28,37 @@ func (err ArgSizeError) Error() string {
 // native function
 type NativeFunDef func(args map[string]interface{}) (interface{}, error)
 
+// NativeFunEnvDef is a native function reading the environment of the
+// calling interpreter, such as the random source
+type NativeFunEnvDef func(intp *Interpreter, args map[string]interface{}) (interface{}, error)
+
 type NativeFun struct {
 	fn               NativeFunDef
+	envFn            NativeFunEnvDef
 	requiredArgNames []string
 	optionalArgNames []string
 	varArgName       string
 	help             string
+	// results differ between calls with the same arguments
+	nondeterministic bool
+	// other signatures of the function
+	overloads []argSignature
+}
+
+// argSignature is a signature of overloaded native functions
+type argSignature struct {
+	required []string
+	optional []string
 }
 
 func NewNativeFunc(fn NativeFunDef) *NativeFun {
 	return &NativeFun{fn: fn}
 }
 
+func NewNativeFuncWithEnv(fn NativeFunEnvDef) *NativeFun {
+	return &NativeFun{envFn: fn}
+}
+
 func (nfun *NativeFun) Required(argNames ...string) *NativeFun {
 	nfun.requiredArgNames = append(nfun.requiredArgNames, argNames...)
 	return nfun
@@ -60,6 +79,21 @@ func (nfun *NativeFun) Help(help string) *NativeFun {
 	return nfun
 }
 
+// Overload adds another signature, arguments are bound by the first
+// signature they fit. The signature given by Required and Optional is
+// tried first, it should require the fewest arguments.
+func (nfun *NativeFun) Overload(required []string, optional ...string) *NativeFun {
+	nfun.overloads = append(nfun.overloads, argSignature{required: required, optional: optional})
+	return nfun
+}
+
+// Nondeterministic marks the function so that calls are never folded
+// into constants by Optimize
+func (nfun *NativeFun) Nondeterministic() *NativeFun {
+	nfun.nondeterministic = true
+	return nfun
+}
+
 func (nfun NativeFun) ArgNameAt(at int) (string, bool) {
 	if at >= 0 && at < len(nfun.requiredArgNames) {
 		return nfun.requiredArgNames[at], true
@@ -69,12 +103,78 @@ func (nfun NativeFun) ArgNameAt(at int) (string, bool) {
 	return "", false
 }
 
+// argMap maps the evaluated arguments to the argument names, argNames
+// are the names of keyword arguments and nil for positional arguments
+func (nfun *NativeFun) argMap(args []any, argNames []string) (map[string]any, error) {
+	argVals, err := bindArgs(nfun.requiredArgNames, nfun.optionalArgNames, nfun.varArgName, args, argNames)
+	if err == nil {
+		return argVals, nil
+	}
+	for _, sig := range nfun.overloads {
+		argVals, err = bindArgs(sig.required, sig.optional, "", args, argNames)
+		if err == nil {
+			return argVals, nil
+		}
+	}
+	// the error of the last signature tried
+	return nil, err
+}
+
+func bindArgs(required, optional []string, varArgName string, args []any, argNames []string) (map[string]any, error) {
+	argVals := make(map[string]any, len(args))
+	if argNames != nil {
+		kwArgMap := make(map[string]any, len(args))
+		for i, name := range argNames {
+			kwArgMap[name] = args[i]
+		}
+		for _, argName := range required {
+			if v, ok := kwArgMap[argName]; ok {
+				argVals[argName] = v
+			} else {
+				return nil, NewErrKeywordArgument(argName)
+			}
+		}
+		for _, argName := range optional {
+			if v, ok := kwArgMap[argName]; ok {
+				argVals[argName] = v
+			}
+		}
+		return argVals, nil
+	}
+
+	if len(args) < len(required) {
+		return nil, NewErrTooFewArguments(required[len(args):])
+	}
+	for i, a := range args {
+		if i < len(required) {
+			argVals[required[i]] = a
+		} else if i < len(required)+len(optional) {
+			argVals[optional[i-len(required)]] = a
+		} else if varArgName != "" {
+			if vars, ok := argVals[varArgName]; ok {
+				argVals[varArgName] = append(vars.([]any), a)
+			} else {
+				argVals[varArgName] = []any{a}
+			}
+		} else {
+			return nil, NewErrTooManyArguments()
+		}
+	}
+	return argVals, nil
+}
+
 func (nfun *NativeFun) Call(intp *Interpreter, args map[string]interface{}) (interface{}, error) {
-	v, err := nfun.fn(args)
+	var v any
+	var err error
+	if nfun.envFn != nil {
+		v, err = nfun.envFn(intp, args)
+	} else {
+		v, err = nfun.fn(args)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return normalizeValue(v), nil
+	return ConvertValue(v)
 }
 
 // macro
@@ -162,7 +262,7 @@ func (prelude *Prelude) Load() {
 		var lastValue interface{}
 		var err error
 		for _, expr := range exprlist {
-			lastValue, err = expr.Eval(intp)
+			lastValue, err = intp.EvalNode(expr)
 			if err != nil {
 				return nil, err
 			}
@@ -171,7 +271,7 @@ func (prelude *Prelude) Load() {
 	}).Vararg("express list").Help("quote a sequence of expresses and return the last result"))
 
 	prelude.Bind("help", NewMacro(func(intp *Interpreter, args map[string]Node, exprlist []Node) (interface{}, error) {
-		v, err := args["value"].Eval(intp)
+		v, err := intp.EvalNode(args["value"])
 		if err != nil {
 			return nil, err
 		}
@@ -186,7 +286,7 @@ func (prelude *Prelude) Load() {
 	}).Required("value").Help("the help information of a value"))
 
 	prelude.Bind("typeof", NewMacro(func(intp *Interpreter, args map[string]Node, exprlist []Node) (interface{}, error) {
-		v, err := args["value"].Eval(intp)
+		v, err := intp.EvalNode(args["value"])
 		if err != nil {
 			return nil, err
 		}
@@ -195,8 +295,10 @@ func (prelude *Prelude) Load() {
 
 	installDatetimeFunctions(prelude)
 	installBuiltinFunctions(prelude)
+	installNumberFunctions(prelude)
 	installContextFunctions(prelude)
 	installRangeFunctions(prelude)
+	installRandomFunctions(prelude)
 }
 
 func (prelude *Prelude) Bind(name string, value interface{}) *Prelude {
@@ -212,6 +314,16 @@ func (prelude *Prelude) Resolve(name string) (interface{}, bool) {
 	return v, ok
 }
 
+// nameOf finds the name the function is bound to
+func (prelude *Prelude) nameOf(fn any) (string, bool) {
+	for name, v := range prelude.vars {
+		if v == fn {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 // buildin native funcs
 func nativeBind(intp *Interpreter, varname string, value interface{}) (interface{}, error) {
 	intp.Bind(varname, value)
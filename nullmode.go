@@ -0,0 +1,133 @@
+package feel
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Warning records a runtime problem turned into null in the null mode
+type Warning struct {
+	Code    int       `json:"code"`
+	Message string    `json:"message"`
+	Range   TextRange `json:"range"`
+}
+
+func (warning Warning) String() string {
+	return fmt.Sprintf("%d:%d: %d %s", warning.Range.Start.Row+1, warning.Range.Start.Column+1, warning.Code, warning.Message)
+}
+
+// EvalResult is the value of an evaluation with the warnings of the
+// null mode
+type EvalResult struct {
+	Value    any       `json:"value"`
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// EnableNullMode follows the null semantics of the FEEL spec, type
+// mismatches, missing context keys, bad indexes and bad function
+// arguments give null and the evaluation continues, a warning is
+// recorded for each of them. Null operands of arithmetic and path
+// expressions give null silently. Failures of resolvers, aborts of
+// hooks and other errors are still returned.
+func (intp *Interpreter) EnableNullMode() {
+	intp.nullMode = true
+}
+
+// Warnings returns the warnings recorded in the null mode by the last
+// evaluation
+func (intp *Interpreter) Warnings() []Warning {
+	return intp.warnings
+}
+
+// undefinedError is the failure of an operation whose result FEEL
+// defines as null, such as testing whether a string is in a range of
+// numbers. It gives null, and a warning in the null mode.
+type undefinedError struct {
+	*EvalError
+}
+
+func (err *undefinedError) Unwrap() error {
+	return err.EvalError
+}
+
+func undefined(err *EvalError) error {
+	return &undefinedError{EvalError: err}
+}
+
+func isUndefined(err error) bool {
+	var undefinedErr *undefinedError
+	return errors.As(err, &undefinedErr)
+}
+
+// nullOnError turns the error of the node into null and a warning
+// unless it's a hard error, out of the null mode only undefined
+// results give null
+func (intp *Interpreter) nullOnError(node Node, err error) (any, error) {
+	if !intp.nullMode {
+		if isUndefined(err) {
+			return Null, nil
+		}
+		return nil, err
+	}
+	warning := Warning{Range: node.TextRange()}
+	var evalErr *EvalError
+	if errors.As(err, &evalErr) && evalErr.Code != -3000 {
+		warning.Code = evalErr.Code
+		warning.Message = evalErr.Short
+		if evalErr.Message != "" {
+			warning.Message = fmt.Sprintf("%s, %s", evalErr.Short, evalErr.Message)
+		}
+	} else if errors.Is(err, ErrParseTemporal) {
+		warning.Code = -4003
+		warning.Message = fmt.Sprintf("value error, %s", err)
+	} else {
+		return nil, err
+	}
+	intp.warnings = append(intp.warnings, warning)
+	return Null, nil
+}
+
+// functionError makes the failure of a native function an EvalError,
+// so that it turns into null in the null mode
+func functionError(funcName string, err error) error {
+	var evalErr *EvalError
+	if errors.As(err, &evalErr) {
+		return err
+	}
+	return NewErrFunction(funcName, err)
+}
+
+// isNull tells whether the value is null, go nil is taken as null
+func isNull(v any) bool {
+	_, ok := v.(*NullValue)
+	return ok || v == nil
+}
+
+// nullPropagates tells whether the operator gives null on null operands
+// in the null mode
+func nullPropagates(op string) bool {
+	switch op {
+	case "+", "-", "*", "/", "%", "**":
+		return true
+	default:
+		return false
+	}
+}
+
+// EvalStringWithWarnings evaluates the input in the null mode
+func EvalStringWithWarnings(input string, scope Scope) (*EvalResult, error) {
+	ast, err := ParseString(input)
+	if err != nil {
+		return nil, err
+	}
+	intp := NewIntepreter()
+	intp.EnableNullMode()
+	if err := intp.pushScope(scope); err != nil {
+		return nil, err
+	}
+	v, err := intp.EvalNode(ast)
+	if err != nil {
+		return nil, err
+	}
+	return &EvalResult{Value: v, Warnings: intp.Warnings()}, nil
+}
@@ -0,0 +1,134 @@
+package feel
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNullMode(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		input    string
+		expect   any
+		warnings []string
+	}{
+		{`{a: 1}.b`, Null, []string{"1:1: -4000 key not found, cannot get key 'b'"}},
+		{`{a: 1}.b + 1`, Null, []string{"1:1: -4000 key not found, cannot get key 'b'"}},
+		{`missing.b.c * 2`, Null, nil},
+		{`1 + "a"`, Null, []string{"1:1: -5001 type mismatch in op, bad types in op,  number + string"}},
+		{`[1, 2][5]`, Null, []string{"1:1: -4001 index error, index out of range"}},
+		{`[1, "a", 3][2] * 2 + 1`, Null, []string{"1:1: -3101 invalid types, bad type in op, string * *feel.Number"}},
+		{`[{price: 1}, {cost: 2}, {price: 3}].price`, Null, []string{"1:1: -4002 type mismatch, expect map"}},
+		{`for x in [{price: 1}, {cost: 2}] return x.price`, []any{N(1), Null}, []string{"1:41: -4000 key not found, cannot get key 'price'"}},
+		{`for x in 5 return x`, Null, []string{"1:1: -4002 type mismatch, expect list"}},
+		{`string length(5)`, Null, []string{"1:1: -4020 function error, `string length`: "}},
+		{`substring("abc")`, Null, []string{"1:1: -4011 too few argument, require arguments: start position"}},
+		{`5(1)`, Null, []string{"1:1: -4002 type mismatch, expect function"}},
+		{`@"bad"`, Null, []string{"1:1: -4003 value error, fail to parse temporal value"}},
+		{`count([{a: 1}.b, 2])`, N(2), []string{"1:8: -4000 key not found, cannot get key 'b'"}},
+		{`(function(x) x.a)({b: 1})`, Null, []string{"1:14: -4000 key not found, cannot get key 'a'"}},
+		{`{a: 1}.a + 1`, N(2), nil},
+		{`is defined({a: 1}.b)`, false, nil},
+		{`is defined([1, 2][5])`, false, nil},
+		{`is defined({a: 1}.a)`, true, nil},
+	}
+	for _, c := range cases {
+		res, err := EvalStringWithWarnings(c.input, nil)
+		assert.Nil(err, c.input)
+		assert.True(cmp.Equal(c.expect, res.Value), "%s gives %v", c.input, res.Value)
+		if assert.Equal(len(c.warnings), len(res.Warnings), c.input) {
+			for i, w := range res.Warnings {
+				assert.True(strings.HasPrefix(w.String(), c.warnings[i]), "%s warns %s", c.input, w)
+			}
+		}
+
+		// the VM gives the same results and warnings
+		prog, err := CompileString(c.input)
+		assert.Nil(err)
+		intp := NewIntepreter()
+		intp.EnableNullMode()
+		v, err := prog.Run(intp)
+		assert.Nil(err, c.input)
+		assert.True(cmp.Equal(c.expect, v), "%s gives %v by VM", c.input, v)
+		assert.Equal(res.Warnings, intp.Warnings(), c.input)
+	}
+
+	// errors are kept out of the null mode
+	_, err := EvalString(`{a: 1}.b + 1`)
+	assert.NotNil(err)
+}
+
+func TestNullModeHardErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	failure := errors.New("connection lost")
+	intp := NewIntepreter()
+	intp.EnableNullMode()
+	intp.AddResolver(ResolverFunc(func(name string) (any, bool, error) {
+		return nil, false, failure
+	}))
+	ast, err := ParseString(`customer.age + 1`)
+	assert.Nil(err)
+	_, err = intp.EvalNode(ast)
+	assert.ErrorIs(err, failure)
+
+	prog := Compile(ast)
+	_, err = prog.Run(intp)
+	assert.ErrorIs(err, failure)
+}
+
+func TestNullModeReusedInterpreter(t *testing.T) {
+	assert := assert.New(t)
+
+	intp := NewIntepreter()
+	intp.EnableNullMode()
+	bad, err := ParseString(`{a: 1}.b`)
+	assert.Nil(err)
+	good, err := ParseString(`{a: 1}.a`)
+	assert.Nil(err)
+
+	_, err = intp.EvalNode(bad)
+	assert.Nil(err)
+	assert.Equal(1, len(intp.Warnings()))
+	_, err = intp.EvalNode(bad)
+	assert.Nil(err)
+	assert.Equal(1, len(intp.Warnings()))
+
+	// the warnings of the previous evaluation are dropped
+	_, err = intp.EvalNode(good)
+	assert.Nil(err)
+	assert.Equal(0, len(intp.Warnings()))
+
+	_, err = Compile(bad).Run(intp)
+	assert.Nil(err)
+	assert.Equal(1, len(intp.Warnings()))
+	_, err = Compile(good).Run(intp)
+	assert.Nil(err)
+	assert.Equal(0, len(intp.Warnings()))
+}
+
+func TestNullModeRanges(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, scope := range []Scope{nil, {"x": "a"}} {
+		res, err := EvalStringWithWarnings(`x in [1..5]`, scope)
+		assert.Nil(err)
+		assert.Equal(Null, res.Value)
+		assert.Equal(1, len(res.Warnings), "x is %v", scope["x"])
+
+		prog, err := CompileString(`x in [1..5]`)
+		assert.Nil(err)
+		intp := NewIntepreter()
+		intp.EnableNullMode()
+		intp.Push(scope)
+		v, err := prog.Run(intp)
+		assert.Nil(err)
+		assert.Equal(Null, v)
+		assert.Equal(res.Warnings, intp.Warnings())
+	}
+}
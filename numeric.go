@@ -4,37 +4,111 @@ package feel
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"math/big"
+	"strconv"
+	"strings"
 )
 
 const (
+	// Prec is the precision of the *big.Float returned by BigFloat
 	Prec = 34 * 8
+
+	// Digits is the number of significant decimal digits of numbers,
+	// as IEEE 754 DECIMAL128
+	Digits = 34
+
+	// the range of exponents of DECIMAL128, a number beyond maxExponent
+	// overflows and one below minExponent is rounded to it
+	maxExponent = 6144
+	minExponent = -6176
+
+	// the largest exponent of **, beyond which the result is null
+	maxPower = 999999999
 )
 
 var (
 	ErrParseNumber = errors.New("fail to parse number")
 )
 
+var (
+	bigOne = big.NewInt(1)
+	bigTen = big.NewInt(10)
+)
+
+// Number is a decimal of at most 34 significant digits, the results of
+// arithmetic are rounded half to even. Methods return nil where FEEL
+// gives null, on division by zero or overflow, and nil operands give
+// nil.
 type Number struct {
-	v *big.Float
+	// the value is coef * 10^exp, coef has no trailing zeros and zero
+	// has exp 0
+	coef *big.Int
+	exp  int
 }
 
 func NewNumber(strn string) *Number {
-	v := new(big.Float)
-	v.SetPrec(Prec).SetString(strn)
-	return &Number{v: v}
+	n, err := parseNumber(strn)
+	if err != nil {
+		return &Number{coef: new(big.Int)}
+	}
+	return n
 }
 
 func NewNumberFromInt64(input int64) *Number {
-	v := new(big.Float)
-	v.SetPrec(200).SetInt64(input)
-	return &Number{v: v}
+	return newNumber(big.NewInt(input), 0, false)
 }
 
+// NewNumberFromFloat converts the float to the shortest decimal which
+// reads back as the float, so 0.1 gives 0.1
 func NewNumberFromFloat(input float64) *Number {
-	v := new(big.Float)
-	v.SetPrec(200).SetFloat64(input)
-	return &Number{v: v}
+	if math.IsNaN(input) || math.IsInf(input, 0) {
+		return nil
+	}
+	return NewNumber(strconv.FormatFloat(input, 'g', -1, 64))
+}
+
+// parseNumber parses decimals such as -12.50 and 1.5e-3
+func parseNumber(strn string) (*Number, error) {
+	s := strn
+	exp := 0
+	if at := strings.IndexAny(s, "eE"); at >= 0 {
+		e, err := strconv.Atoi(s[at+1:])
+		if err != nil || e > 10*maxExponent || e < 10*minExponent {
+			return nil, ErrParseNumber
+		}
+		exp = e
+		s = s[:at]
+	}
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return nil, ErrParseNumber
+	}
+	digits := intPart + fracPart
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, ErrParseNumber
+		}
+	}
+	if hasDot {
+		exp -= len(fracPart)
+	}
+	coef, _ := new(big.Int).SetString(digits, 10)
+	if neg {
+		coef.Neg(coef)
+	}
+	n := newNumber(coef, exp, false)
+	if n == nil {
+		return nil, ErrParseNumber
+	}
+	return n, nil
 }
 
 func ParseNumberWithErr(v interface{}) (*Number, error) {
@@ -44,9 +118,12 @@ func ParseNumberWithErr(v interface{}) (*Number, error) {
 	case int64:
 		return NewNumberFromInt64(vv), nil
 	case float64:
-		return NewNumberFromFloat(vv), nil
+		if n := NewNumberFromFloat(vv); n != nil {
+			return n, nil
+		}
+		return nil, ErrParseNumber
 	case string:
-		return NewNumber(vv), nil
+		return parseNumber(vv)
 	case *Number:
 		return vv, nil
 	default:
@@ -62,9 +139,119 @@ func N(v interface{}) *Number {
 	return n
 }
 
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// numDigits counts the decimal digits of |x|, 1 for zero
+func numDigits(x *big.Int) int {
+	if x.Sign() == 0 {
+		return 1
+	}
+	// the estimate by bit length is off by at most one
+	n := int(float64(x.BitLen()) * math.Log10(2))
+	if n < 1 {
+		n = 1
+	}
+	abs := new(big.Int).Abs(x)
+	for abs.Cmp(pow10(n)) >= 0 {
+		n++
+	}
+	for n > 1 && abs.Cmp(pow10(n-1)) < 0 {
+		n--
+	}
+	return n
+}
+
+// newNumber rounds coef * 10^exp half to even to the number of digits,
+// sticky tells whether a nonzero remainder was dropped below the value,
+// nil is returned on overflow
+func newNumber(coef *big.Int, exp int, sticky bool) *Number {
+	return roundNumber(coef, exp, sticky, Digits)
+}
+
+func roundNumber(coef *big.Int, exp int, sticky bool, digits int) *Number {
+	nd := numDigits(coef)
+	drop := nd - digits
+	if minExponent-exp > drop {
+		drop = minExponent - exp
+	}
+	if drop > nd {
+		// every digit is dropped and the value is below half of the
+		// last digit kept
+		coef = new(big.Int)
+	} else if drop > 0 {
+		neg := coef.Sign() < 0
+		q, r := new(big.Int).QuoRem(new(big.Int).Abs(coef), pow10(drop), new(big.Int))
+		half := new(big.Int).Mul(big.NewInt(5), pow10(drop-1))
+		switch c := r.Cmp(half); {
+		case c > 0, c == 0 && sticky, c == 0 && q.Bit(0) == 1:
+			q.Add(q, bigOne)
+		}
+		if neg {
+			q.Neg(q)
+		}
+		coef = q
+		exp += drop
+	} else {
+		coef = new(big.Int).Set(coef)
+	}
+
+	if coef.Sign() == 0 {
+		return &Number{coef: coef}
+	}
+	// strip the trailing zeros
+	r := new(big.Int)
+	for {
+		q, m := new(big.Int).QuoRem(coef, bigTen, r)
+		if m.Sign() != 0 {
+			break
+		}
+		coef = q
+		exp++
+	}
+	if exp+numDigits(coef)-1 > maxExponent {
+		return nil
+	}
+	return &Number{coef: coef, exp: exp}
+}
+
+// adjusted is the exponent of the leading digit
+func (number *Number) adjusted() int {
+	return number.exp + numDigits(number.coef) - 1
+}
+
+// aligned returns the coefficients of both numbers at the smaller exponent
+func aligned(a, b *Number) (*big.Int, *big.Int, int) {
+	if a.exp == b.exp {
+		return a.coef, b.coef, a.exp
+	} else if a.exp > b.exp {
+		return new(big.Int).Mul(a.coef, pow10(a.exp-b.exp)), b.coef, b.exp
+	} else {
+		return a.coef, new(big.Int).Mul(b.coef, pow10(b.exp-a.exp)), a.exp
+	}
+}
+
 func (number Number) Int64() int64 {
-	i64v, _ := number.v.Int64()
-	return i64v
+	i := number.truncated()
+	if !i.IsInt64() {
+		if i.Sign() < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+	return i.Int64()
+}
+
+// truncated returns the integer part
+func (number Number) truncated() *big.Int {
+	if number.exp >= 0 {
+		return new(big.Int).Mul(number.coef, pow10(number.exp))
+	}
+	if -number.exp > numDigits(number.coef) {
+		return new(big.Int)
+	}
+	return new(big.Int).Quo(number.coef, pow10(-number.exp))
 }
 
 func (number Number) Int() int {
@@ -72,58 +259,202 @@ func (number Number) Int() int {
 }
 
 func (number Number) Float64() float64 {
-	f64v, _ := number.v.Float64()
+	f64v, _ := strconv.ParseFloat(number.scientific(), 64)
 	return f64v
 }
 
+// IsInteger reports whether the number has no fractional part
+func (number Number) IsInteger() bool {
+	return number.exp >= 0
+}
+
+// Sign returns -1, 0 or 1 by the sign of the number
+func (number Number) Sign() int {
+	return number.coef.Sign()
+}
+
+// BigFloat returns a copy of the number as *big.Float
+func (number Number) BigFloat() *big.Float {
+	v, _, _ := big.ParseFloat(number.scientific(), 10, Prec, big.ToNearestEven)
+	return v
+}
+
+// scientific formats the number as coef e exp
+func (number Number) scientific() string {
+	return number.coef.String() + "e" + strconv.Itoa(number.exp)
+}
+
 func (number *Number) Add(other *Number) *Number {
-	newv := new(big.Float)
-	newv.SetPrec(Prec).Add(number.v, other.v)
-	return &Number{v: newv}
+	if number == nil || other == nil {
+		return nil
+	}
+	if number.Sign() == 0 {
+		return other
+	} else if other.Sign() == 0 {
+		return number
+	}
+	// an operand below half of the last digit of the other leaves the
+	// rounded sum unchanged
+	if number.adjusted()-other.adjusted() > Digits+1 {
+		return number
+	} else if other.adjusted()-number.adjusted() > Digits+1 {
+		return other
+	}
+	a, b, exp := aligned(number, other)
+	return newNumber(new(big.Int).Add(a, b), exp, false)
 }
 
 func (number *Number) Sub(other *Number) *Number {
-	newv := new(big.Float)
-	newv.SetPrec(Prec).Sub(number.v, other.v)
-	return &Number{v: newv}
+	return number.Add(other.Neg())
+}
+
+// Neg returns the number negated
+func (number *Number) Neg() *Number {
+	if number == nil {
+		return nil
+	}
+	return &Number{coef: new(big.Int).Neg(number.coef), exp: number.exp}
 }
 
 func (number *Number) Mul(other *Number) *Number {
-	newv := new(big.Float)
-	newv.SetPrec(Prec).Mul(number.v, other.v)
-	return &Number{v: newv}
+	if number == nil || other == nil {
+		return nil
+	}
+	return newNumber(new(big.Int).Mul(number.coef, other.coef), number.exp+other.exp, false)
+}
+
+// Div divides the number by other, nil is returned when other is zero
+func (number *Number) Div(other *Number) *Number {
+	return number.quo(other, Digits)
+}
+
+func (number *Number) quo(other *Number, digits int) *Number {
+	if number == nil || other == nil || other.Sign() == 0 {
+		return nil
+	}
+	if number.Sign() == 0 {
+		return &Number{coef: new(big.Int)}
+	}
+	// scale the dividend so that the quotient has more digits than
+	// kept, the remainder decides the rounding of ties
+	shift := digits + 1 + numDigits(other.coef) - numDigits(number.coef)
+	if shift < 0 {
+		shift = 0
+	}
+	a := new(big.Int).Mul(number.coef, pow10(shift))
+	q, r := new(big.Int).QuoRem(a, other.coef, new(big.Int))
+	return roundNumber(q, number.exp-other.exp-shift, r.Sign() != 0, digits)
+}
+
+// Mod is the modulo of FEEL, number - other * floor(number / other),
+// which has the sign of other, nil is returned when other is zero
+func (number *Number) Mod(other *Number) *Number {
+	if number == nil || other == nil || other.Sign() == 0 {
+		return nil
+	}
+	if number.adjusted()-other.exp > maxExponent {
+		// too far apart to compute exactly
+		return nil
+	}
+	a, b, exp := aligned(number, other)
+	m := new(big.Int).Mod(a, b)
+	if m.Sign() != 0 && b.Sign() < 0 {
+		// big.Int.Mod is euclidean, in [0, |b|)
+		m.Add(m, b)
+	}
+	return newNumber(m, exp, false)
+}
+
+// Pow raises the number to the power of other, an integer power is
+// computed exactly and others as e^(other ln number) to 34 digits, nil
+// is returned for zero to a negative power, negative numbers to
+// non-integer powers and on overflow
+func (number *Number) Pow(other *Number) *Number {
+	if number == nil || other == nil {
+		return nil
+	}
+	if !other.IsInteger() {
+		switch number.Sign() {
+		case -1:
+			return nil
+		case 0:
+			if other.Sign() < 0 {
+				return nil
+			}
+			return number
+		}
+		// x^y = e^(y ln x)
+		y := new(big.Float).SetPrec(seriesPrec).Mul(other.BigFloat(), lnFloat(number.BigFloat()))
+		return expFloat(y)
+	}
+	if other.adjusted() > 9 {
+		return nil
+	}
+	p := other.Int64()
+	if p > maxPower || p < -maxPower {
+		return nil
+	}
+	neg := p < 0
+	if neg {
+		p = -p
+	}
+	// squaring with guard digits so that exact results stay exact
+	const workDigits = Digits + 9
+	result := &Number{coef: big.NewInt(1)}
+	base := number
+	for ; p > 0; p >>= 1 {
+		if p&1 == 1 {
+			result = roundNumber(new(big.Int).Mul(result.coef, base.coef), result.exp+base.exp, false, workDigits)
+			if result == nil {
+				return nil
+			}
+		}
+		if p > 1 {
+			base = roundNumber(new(big.Int).Mul(base.coef, base.coef), 2*base.exp, false, workDigits)
+			if base == nil {
+				return nil
+			}
+		}
+	}
+	if neg {
+		return N(1).Div(result)
+	}
+	return newNumber(result.coef, result.exp, false)
 }
 
 func (number *Number) Cmp(other *Number) int {
-	return number.v.Cmp(other.v)
+	return number.Compare(*other)
 }
 
+// IntDiv divides the integer parts of numbers, nil is returned when the
+// integer part of other is zero.
+//
+// Deprecated: use Div
 func (number *Number) IntDiv(other *Number) *Number {
-	newv := new(big.Int)
-	a, _ := number.v.Int(nil)
-	b, _ := other.v.Int(nil)
-	newv.Div(a, b)
-	newf := new(big.Float)
-	newf.SetPrec(Prec).SetInt(newv)
-	return &Number{v: newf}
+	b := other.truncated()
+	if b.Sign() == 0 {
+		return nil
+	}
+	return newNumber(new(big.Int).Div(number.truncated(), b), 0, false)
 }
 
+// FloatDiv divides the number by other.
+//
+// Deprecated: use Div
 func (number *Number) FloatDiv(other *Number) *Number {
-	a, _ := number.v.Float64()
-	b, _ := other.v.Float64()
-	newf := new(big.Float)
-	newf.SetPrec(Prec).SetFloat64(a / b)
-	return &Number{v: newf}
+	return number.Div(other)
 }
 
+// IntMod is the euclidean modulo of the integer parts of numbers, nil
+// is returned when the integer part of other is zero.
+//
+// Deprecated: use Mod
 func (number *Number) IntMod(other *Number) *Number {
-	newv := new(big.Int)
-	a, _ := number.v.Int(nil)
-	b, _ := other.v.Int(nil)
-	newv.Mod(a, b)
-	newf := new(big.Float)
-	newf.SetPrec(Prec).SetInt(newv)
-	return &Number{v: newf}
+	b := other.truncated()
+	if b.Sign() == 0 {
+		return nil
+	}
+	return newNumber(new(big.Int).Mod(number.truncated(), b), 0, false)
 }
 
 func (number Number) Equal(other Number) bool {
@@ -131,19 +462,377 @@ func (number Number) Equal(other Number) bool {
 }
 
 func (number Number) Compare(other Number) int {
-	return number.v.Cmp(other.v)
+	sa, sb := number.Sign(), other.Sign()
+	if sa != sb {
+		if sa < sb {
+			return -1
+		}
+		return 1
+	} else if sa == 0 {
+		return 0
+	}
+	// numbers of the same sign are ordered by their leading digits
+	if adja, adjb := number.adjusted(), other.adjusted(); adja != adjb {
+		if (adja < adjb) == (sa > 0) {
+			return -1
+		}
+		return 1
+	}
+	a, b, _ := aligned(&number, &other)
+	return a.Cmp(b)
 }
 
+// String formats the number in decimals without exponent and trailing
+// zeros, e.g. 5, -0.25
 func (number Number) String() string {
-	//return number.v.String()
-	return number.v.Text('f', 18)
+	digits := new(big.Int).Abs(number.coef).String()
+	sign := ""
+	if number.coef.Sign() < 0 {
+		sign = "-"
+	}
+	if number.exp >= 0 {
+		return sign + digits + strings.Repeat("0", number.exp)
+	}
+	point := len(digits) + number.exp
+	if point <= 0 {
+		return sign + "0." + strings.Repeat("0", -point) + digits
+	}
+	return sign + digits[:point] + "." + digits[point:]
 }
 
+// MarshalJSON encodes the number as a JSON number of all its digits
 func (number Number) MarshalJSON() ([]byte, error) {
-	if f32v, acc := number.v.Float32(); acc == big.Exact {
-		return json.Marshal(f32v)
+	return []byte(number.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number, or a string of a number, exactly
+func (number *Number) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
 	}
-	return json.Marshal(number.String())
+	if strings.HasPrefix(s, "\"") {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	n, err := parseNumber(s)
+	if err != nil {
+		return err
+	}
+	*number = *n
+	return nil
 }
 
 var Zero = N(0)
+
+// RoundingMode tells how Round drops digits
+type RoundingMode int
+
+const (
+	// to the nearest, ties to the even digit
+	RoundHalfEven RoundingMode = iota
+	// to the nearest, ties away from zero
+	RoundHalfUp
+	// to the nearest, ties towards zero
+	RoundHalfDown
+	// away from zero
+	RoundUp
+	// towards zero
+	RoundDown
+	// towards negative infinity
+	RoundFloor
+	// towards positive infinity
+	RoundCeiling
+)
+
+// the range of scales accepted by the rounding functions
+const (
+	minScale = -6111
+	maxScale = 6176
+)
+
+// Round rounds the number to scale digits after the decimal point, a
+// negative scale rounds to tens, hundreds and so on
+func (number *Number) Round(scale int, mode RoundingMode) *Number {
+	if number == nil {
+		return nil
+	}
+	drop := -scale - number.exp
+	if drop <= 0 {
+		return number
+	}
+	neg := number.Sign() < 0
+	abs := new(big.Int).Abs(number.coef)
+	var q, r, half *big.Int
+	if drop > numDigits(abs) {
+		// every digit is dropped and the value is below half
+		q, r = new(big.Int), abs
+		half = new(big.Int).Add(abs, bigOne)
+	} else {
+		q, r = new(big.Int).QuoRem(abs, pow10(drop), new(big.Int))
+		half = new(big.Int).Mul(big.NewInt(5), pow10(drop-1))
+	}
+	up := false
+	switch c := r.Cmp(half); mode {
+	case RoundHalfEven:
+		up = c > 0 || c == 0 && q.Bit(0) == 1
+	case RoundHalfUp:
+		up = c >= 0
+	case RoundHalfDown:
+		up = c > 0
+	case RoundUp:
+		up = r.Sign() != 0
+	case RoundFloor:
+		up = r.Sign() != 0 && neg
+	case RoundCeiling:
+		up = r.Sign() != 0 && !neg
+	}
+	if up {
+		q.Add(q, bigOne)
+	}
+	if neg {
+		q.Neg(q)
+	}
+	return newNumber(q, -scale, false)
+}
+
+// Abs returns the absolute value of the number
+func (number *Number) Abs() *Number {
+	if number == nil || number.Sign() >= 0 {
+		return number
+	}
+	return number.Neg()
+}
+
+// Sqrt returns the square root of the number, nil for negative numbers
+func (number *Number) Sqrt() *Number {
+	if number == nil || number.Sign() < 0 {
+		return nil
+	}
+	if number.Sign() == 0 {
+		return number
+	}
+	// scale the coefficient by an even power of ten to have more
+	// digits in the root than kept
+	shift := 2*(Digits+2) - numDigits(number.coef)
+	if shift < 0 {
+		shift = 0
+	}
+	if (number.exp-shift)%2 != 0 {
+		shift++
+	}
+	a := new(big.Int).Mul(number.coef, pow10(shift))
+	root := new(big.Int).Sqrt(a)
+	exact := new(big.Int).Mul(root, root).Cmp(a) == 0
+	return roundNumber(root, (number.exp-shift)/2, !exact, Digits)
+}
+
+// the precision of the series computing exp and ln, in bits
+const seriesPrec = 256
+
+// Exp returns e to the power of the number, nil on overflow
+func (number *Number) Exp() *Number {
+	if number == nil {
+		return nil
+	}
+	return expFloat(number.BigFloat())
+}
+
+// expFloat computes e^x, nil on overflow
+func expFloat(x *big.Float) *Number {
+	x = new(big.Float).SetPrec(seriesPrec).Set(x)
+	// e^14200 is beyond the largest number and e^-14200 below the
+	// smallest
+	if x.Cmp(big.NewFloat(14200)) > 0 {
+		return nil
+	} else if x.Cmp(big.NewFloat(-14200)) < 0 {
+		return &Number{coef: new(big.Int)}
+	}
+	// reduce the argument below 2^-10 by halving, then square back
+	halvings := x.MantExp(nil) + 10
+	if halvings < 0 {
+		halvings = 0
+	}
+	r := new(big.Float).SetPrec(seriesPrec).SetMantExp(x, -halvings)
+	sum := new(big.Float).SetPrec(seriesPrec).SetInt64(1)
+	term := new(big.Float).SetPrec(seriesPrec).SetInt64(1)
+	for i := int64(1); ; i++ {
+		term.Mul(term, r)
+		term.Quo(term, new(big.Float).SetInt64(i))
+		if term.Sign() == 0 || term.MantExp(nil) < sum.MantExp(nil)-seriesPrec {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	for i := 0; i < halvings; i++ {
+		sum.Mul(sum, sum)
+	}
+	return numberFromBigFloat(sum)
+}
+
+// Ln returns the natural logarithm of the number, nil for numbers not
+// above zero
+func (number *Number) Ln() *Number {
+	if number == nil || number.Sign() <= 0 {
+		return nil
+	}
+	return numberFromBigFloat(lnFloat(number.BigFloat()))
+}
+
+// lnFloat computes the natural logarithm of the positive x
+func lnFloat(x *big.Float) *big.Float {
+	// x = mant * 2^exp, ln x = ln mant + exp * ln 2
+	mant := new(big.Float).SetPrec(seriesPrec)
+	exp := x.MantExp(mant)
+	ln := lnSeries(mant)
+	ln2 := lnSeries(new(big.Float).SetPrec(seriesPrec).SetInt64(2))
+	return ln.Add(ln, ln2.Mul(ln2, new(big.Float).SetInt64(int64(exp))))
+}
+
+// lnSeries computes ln y = 2 atanh((y-1)/(y+1)), which converges fast
+// for y in [0.5, 2]
+func lnSeries(y *big.Float) *big.Float {
+	one := new(big.Float).SetInt64(1)
+	z := new(big.Float).SetPrec(seriesPrec).Sub(y, one)
+	z.Quo(z, new(big.Float).SetPrec(seriesPrec).Add(y, one))
+	z2 := new(big.Float).SetPrec(seriesPrec).Mul(z, z)
+	sum := new(big.Float).SetPrec(seriesPrec).Set(z)
+	power := new(big.Float).SetPrec(seriesPrec).Set(z)
+	for i := int64(3); z.Sign() != 0; i += 2 {
+		power.Mul(power, z2)
+		term := new(big.Float).SetPrec(seriesPrec).Quo(power, new(big.Float).SetInt64(i))
+		if term.Sign() == 0 || term.MantExp(nil) < sum.MantExp(nil)-seriesPrec {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return sum.Mul(sum, new(big.Float).SetInt64(2))
+}
+
+func numberFromBigFloat(f *big.Float) *Number {
+	n, err := parseNumber(f.Text('e', Digits+6))
+	if err != nil {
+		return nil
+	}
+	return n
+}
+
+// numberArg gets the number argument, nil is returned for null or an
+// absent optional argument
+func numberArg(args map[string]any, name string) (*Number, error) {
+	switch v := args[name].(type) {
+	case *Number:
+		return v, nil
+	case nil, *NullValue:
+		return nil, nil
+	default:
+		return nil, NewErrTypeMismatch("number")
+	}
+}
+
+// scaleArg gets the integer scale argument of the rounding functions,
+// ok is false when it's null or out of range
+func scaleArg(args map[string]any, name string) (scale int, ok bool, err error) {
+	n, err := numberArg(args, name)
+	if err != nil || n == nil {
+		return 0, false, err
+	}
+	if !n.IsInteger() || n.Compare(*N(minScale)) < 0 || n.Compare(*N(maxScale)) > 0 {
+		return 0, false, nil
+	}
+	return n.Int(), true, nil
+}
+
+// numberFunc makes a function of a number, null gives null
+func numberFunc(fn func(n *Number) any) *NativeFun {
+	return NewNativeFunc(func(args map[string]any) (any, error) {
+		n, err := numberArg(args, "n")
+		if err != nil || n == nil {
+			return Null, err
+		}
+		return fn(n), nil
+	}).Required("n")
+}
+
+// roundingFunc makes a rounding function, the scale defaults to 0 when
+// it's optional
+func roundingFunc(mode RoundingMode, scaleOptional bool) *NativeFun {
+	fn := NewNativeFunc(func(args map[string]any) (any, error) {
+		n, err := numberArg(args, "n")
+		if err != nil || n == nil {
+			return Null, err
+		}
+		scale := 0
+		if _, given := args["scale"]; given || !scaleOptional {
+			var ok bool
+			if scale, ok, err = scaleArg(args, "scale"); !ok {
+				return Null, err
+			}
+		}
+		return numberOrNull(n.Round(scale, mode)), nil
+	}).Required("n")
+	if scaleOptional {
+		return fn.Optional("scale")
+	}
+	return fn.Required("scale")
+}
+
+// builtin functions
+// refs https://docs.camunda.io/docs/components/modeler/feel/builtin-functions/feel-built-in-functions-numeric/
+func installNumberFunctions(prelude *Prelude) {
+	prelude.Bind("decimal", roundingFunc(RoundHalfEven, false))
+	prelude.Bind("floor", roundingFunc(RoundFloor, true))
+	prelude.Bind("ceiling", roundingFunc(RoundCeiling, true))
+	prelude.Bind("round up", roundingFunc(RoundUp, false))
+	prelude.Bind("round down", roundingFunc(RoundDown, false))
+	prelude.Bind("round half up", roundingFunc(RoundHalfUp, false))
+	prelude.Bind("round half down", roundingFunc(RoundHalfDown, false))
+
+	prelude.Bind("abs", NewNativeFunc(func(args map[string]any) (any, error) {
+		switch v := args["n"].(type) {
+		case *Number:
+			return v.Abs(), nil
+		case *FEELDuration:
+			abs := *v
+			abs.Neg = false
+			return &abs, nil
+		case *NullValue:
+			return Null, nil
+		default:
+			return nil, NewErrTypeMismatch("number or duration")
+		}
+	}).Required("n"))
+
+	prelude.Bind("modulo", NewNativeFunc(func(args map[string]any) (any, error) {
+		dividend, err := numberArg(args, "dividend")
+		if err != nil {
+			return nil, err
+		}
+		divisor, err := numberArg(args, "divisor")
+		if err != nil {
+			return nil, err
+		}
+		return numberOrNull(dividend.Mod(divisor)), nil
+	}).Required("dividend", "divisor"))
+
+	prelude.Bind("sqrt", numberFunc(func(n *Number) any {
+		return numberOrNull(n.Sqrt())
+	}))
+
+	prelude.Bind("log", numberFunc(func(n *Number) any {
+		return numberOrNull(n.Ln())
+	}))
+
+	prelude.Bind("exp", numberFunc(func(n *Number) any {
+		return numberOrNull(n.Exp())
+	}))
+
+	prelude.Bind("odd", numberFunc(func(n *Number) any {
+		return n.IsInteger() && n.truncated().Bit(0) == 1
+	}))
+
+	prelude.Bind("even", numberFunc(func(n *Number) any {
+		return n.IsInteger() && n.truncated().Bit(0) == 0
+	}))
+}
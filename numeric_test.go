@@ -0,0 +1,158 @@
+package feel
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecimalArithmetic(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{`0.1 + 0.2`, `0.3`},
+		{`1.10 * 3`, `3.3`},
+		{`19.99 * 3 - 0.97`, `59`},
+		{`1 / 3`, `0.3333333333333333333333333333333333`},
+		{`2 / 3`, `0.6666666666666666666666666666666667`},
+		{`10 / 4`, `2.5`},
+		{`-1 / 8`, `-0.125`},
+		// rounded half to even at 34 digits
+		{`1234567890123456789012345678901234.5 + 0`, `1234567890123456789012345678901234`},
+		{`1234567890123456789012345678901235.5 + 0`, `1234567890123456789012345678901236`},
+		{`1234567890123456789012345678901234.51 + 0`, `1234567890123456789012345678901235`},
+		{`10000000000000000000000000000000000 - 0.5`, `10000000000000000000000000000000000`},
+		// modulo has the sign of the divisor
+		{`7 % 3`, `1`},
+		{`-7 % 3`, `2`},
+		{`7 % -3`, `-2`},
+		{`-7 % -3`, `-1`},
+		{`5.5 % 2`, `1.5`},
+		{`2 ** 10`, `1024`},
+		{`2 ** -2`, `0.25`},
+		{`1.1 ** 2`, `1.21`},
+		{`2 ** 3 ** 2`, `64`},
+		{`2 * 3 ** 2`, `18`},
+		{`0 ** 0`, `1`},
+		// non-integer exponents are computed in decimals to 34 digits
+		{`2 ** 0.5`, `1.414213562373095048801688724209698`},
+		{`10 ** 0.1`, `1.258925411794167210423954106395801`},
+		{`2 ** -0.5`, `0.707106781186547524400844362104849`},
+		{`4 ** 0.5`, `2`},
+		{`0.01 ** 1.5`, `0.001`},
+		{`0 ** 0.5`, `0`},
+		{`10 ** 6144 > 0`, `true`},
+		{`1 / 0`, `null`},
+		{`1 % 0`, `null`},
+		{`0 ** -1`, `null`},
+		{`10 ** 6145`, `null`},
+		{`-8 ** 0.5`, `null`},
+		{`0 ** -0.5`, `null`},
+	}
+	for _, c := range cases {
+		ast, err := ParseString(c.input)
+		if !assert.Nil(err, c.input) {
+			continue
+		}
+		res, err := NewIntepreter().EvalNode(ast)
+		assert.Nil(err, c.input)
+		s, _ := FormatValue(res)
+		assert.Equal(c.expect, s, c.input)
+
+		res, err = Compile(ast).Run(NewIntepreter())
+		assert.Nil(err, c.input)
+		s, _ = FormatValue(res)
+		assert.Equal(c.expect, s, "compiled %s", c.input)
+	}
+}
+
+func TestNumberConversions(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("5", N(5).String())
+	assert.Equal("-0.025", N("-2.50e-2").String())
+	assert.Equal("1200", N("1.2e3").String())
+	assert.Equal("0.1", N(0.1).String())
+	assert.Equal(0.1, N("0.1").Float64())
+	assert.Equal(int64(-3), N("-3.9").Int64())
+	assert.True(N("2.000").IsInteger())
+	assert.False(N("2.5").IsInteger())
+	assert.Equal(0, N("1.50").Compare(*N("1.5")))
+	assert.Equal(-1, N("-2").Compare(*N("0.001")))
+	assert.Equal(1, N("1e10").Compare(*N("9999999999.99")))
+
+	_, err := ParseNumberWithErr("1.2.3")
+	assert.NotNil(err)
+	_, err = ParseNumberWithErr("abc")
+	assert.NotNil(err)
+
+	data, err := json.Marshal([]any{N("12345678901234567890.125"), N(3)})
+	assert.Nil(err)
+	assert.Equal(`[12345678901234567890.125,3]`, string(data))
+}
+
+func TestNumberFunctions(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{`decimal(1 / 3, 2)`, `0.33`},
+		{`decimal(2.5, 0)`, `2`},
+		{`decimal(3.5, 0)`, `4`},
+		{`decimal(1234.5, -2)`, `1200`},
+		{`floor(-1.5)`, `-2`},
+		{`floor(-1.56, 1)`, `-1.6`},
+		{`ceiling(1.2)`, `2`},
+		{`ceiling(-1.56, 1)`, `-1.5`},
+		{`round up(5.5, 0)`, `6`},
+		{`round up(-5.5, 0)`, `-6`},
+		{`round up(1.121, 2)`, `1.13`},
+		{`round down(-5.5, 0)`, `-5`},
+		{`round down(1.129, 2)`, `1.12`},
+		{`round half up(-5.5, 0)`, `-6`},
+		{`round half up(1.125, 2)`, `1.13`},
+		{`round half down(-5.5, 0)`, `-5`},
+		{`round half down(1.126, 2)`, `1.13`},
+		{`abs(-10.5)`, `10.5`},
+		{`abs(@"-PT5H")`, `@"PT5H"`},
+		{`modulo(12, 5)`, `2`},
+		{`modulo(-12, 5)`, `3`},
+		{`modulo(12, -5)`, `-3`},
+		{`modulo(10.1, 4.5)`, `1.1`},
+		{`modulo(10, 0)`, `null`},
+		{`sqrt(16)`, `4`},
+		{`sqrt(2)`, `1.414213562373095048801688724209698`},
+		{`sqrt(-1)`, `null`},
+		{`log(10)`, `2.302585092994045684017991454684364`},
+		{`log(0)`, `null`},
+		{`exp(1)`, `2.718281828459045235360287471352662`},
+		{`exp(20000)`, `null`},
+		{`log(exp(2))`, `2`},
+		{`odd(5)`, `true`},
+		{`odd(2.5)`, `false`},
+		{`even(-2)`, `true`},
+		{`decimal(null, 2)`, `null`},
+		{`decimal(1, 2.5)`, `null`},
+		{`floor(n: 2.7)`, `2`},
+	}
+	for _, c := range cases {
+		res, err := EvalString(c.input)
+		assert.Nil(err, c.input)
+		s, _ := FormatValue(res)
+		assert.Equal(c.expect, s, c.input)
+	}
+
+	_, err := EvalString(`decimal("1", 2)`)
+	assert.NotNil(err)
+
+	result, err := CheckTypesString(`abs(x) + round half up(x, 2)`, map[string]*Type{"x": NumberType})
+	assert.Nil(err)
+	assert.Empty(result.Diagnostics)
+	assert.Equal(NumberType.String(), result.Type.String())
+}
@@ -0,0 +1,304 @@
+package feel
+
+import (
+	"fmt"
+)
+
+// ConstNode is a value computed ahead of evaluation by Optimize
+type ConstNode struct {
+	Value any
+
+	repr  string
+	Range TextRange
+}
+
+func (node ConstNode) TextRange() TextRange {
+	return node.Range
+}
+
+func (node ConstNode) Repr() string {
+	if node.repr != "" {
+		return node.repr
+	}
+	return constRepr(node.Value)
+}
+
+func (node ConstNode) Eval(intp *Interpreter) (any, error) {
+	return node.Value, nil
+}
+
+// constRepr renders a folded value as a FEEL literal
+func constRepr(v any) string {
+	if s, err := FormatValue(v); err == nil {
+		return s
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// foldable values are immutable, lists and contexts are not folded as
+// they may be modified after evaluation
+func foldable(v any) bool {
+	switch v.(type) {
+	case *Number, string, bool, *NullValue, *FEELDate, *FEELTime, *FEELDatetime, *FEELDuration:
+		return true
+	default:
+		return false
+	}
+}
+
+// Optimize returns a simplified copy of the AST which evaluates to the
+// same results, literal subexpressions are folded into constants,
+// temporal and number literals are parsed once and branches of
+// constant conditions are removed.
+//
+// Calls of prelude functions with constant arguments are folded
+// unless the function is nondeterministic, the names given as inputs
+// are the variables of the evaluations, which shadow prelude functions
+func Optimize(node Node, inputs ...string) Node {
+	opt := &optimizer{
+		intp:   NewIntepreter(),
+		inputs: make(map[string]bool),
+	}
+	for _, name := range inputs {
+		opt.inputs[name] = true
+	}
+	return opt.optimize(node)
+}
+
+type optimizer struct {
+	intp   *Interpreter
+	inputs map[string]bool
+	bound  []map[string]bool
+}
+
+func (opt *optimizer) push(names ...string) {
+	scope := make(map[string]bool)
+	for _, name := range names {
+		scope[name] = true
+	}
+	opt.bound = append(opt.bound, scope)
+}
+
+func (opt *optimizer) pop() {
+	opt.bound = opt.bound[:len(opt.bound)-1]
+}
+
+func (opt *optimizer) isBound(name string) bool {
+	for i := len(opt.bound) - 1; i >= 0; i-- {
+		if opt.bound[i][name] {
+			return true
+		}
+	}
+	return opt.inputs[name]
+}
+
+func (opt *optimizer) optimizeScoped(node Node, names ...string) Node {
+	opt.push(names...)
+	defer opt.pop()
+	return opt.optimize(node)
+}
+
+func isConstNode(node Node) bool {
+	switch v := node.(type) {
+	case *ConstNode:
+		return true
+	case *ArrayNode:
+		for _, elem := range v.Elements {
+			if !isConstNode(elem) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func constValue(node Node) (any, bool) {
+	if c, ok := node.(*ConstNode); ok {
+		return c.Value, true
+	}
+	return nil, false
+}
+
+// producesBool tells whether the node evaluates to a boolean, null or fails
+func producesBool(node Node) bool {
+	switch v := node.(type) {
+	case *BoolNode, *MultiTests:
+		return true
+	case *ConstNode:
+		_, ok := v.Value.(bool)
+		return ok
+	case *Binop:
+		switch v.Op {
+		case "and", "or", "<", "<=", ">", ">=", "=", "!=", "in":
+			return true
+		}
+	}
+	return false
+}
+
+// fold evaluates the node whose children are constant, the node is
+// kept when the evaluation fails so that the error is raised at runtime
+func (opt *optimizer) fold(node Node) Node {
+	v, err := node.Eval(opt.intp)
+	if err != nil || !foldable(v) {
+		return node
+	}
+	return &ConstNode{Value: v, Range: node.TextRange()}
+}
+
+func (opt *optimizer) isPureFunction(funRef Node) bool {
+	ref, ok := funRef.(*Var)
+	if !ok || opt.isBound(ref.Name) {
+		return false
+	}
+	fn, ok := GetPrelude().Resolve(ref.Name)
+	if !ok {
+		return false
+	}
+	nfun, ok := fn.(*NativeFun)
+	return ok && !nfun.nondeterministic
+}
+
+func (opt *optimizer) optimize(node Node) Node {
+	switch v := node.(type) {
+	case *NumberNode:
+		return &ConstNode{Value: NewNumber(v.Value), repr: v.Value, Range: v.Range}
+	case *StringNode:
+		return &ConstNode{Value: v.Content(), repr: v.Value, Range: v.Range}
+	case *BoolNode:
+		return &ConstNode{Value: v.Value, repr: v.Repr(), Range: v.Range}
+	case *NullNode:
+		return &ConstNode{Value: Null, repr: v.Repr(), Range: v.Range}
+	case *TemporalNode:
+		if value, err := ParseTemporalValue(v.Content()); err == nil {
+			return &ConstNode{Value: value, repr: v.Value, Range: v.Range}
+		}
+		return v
+	case *Binop:
+		return opt.optimizeBinop(v)
+	case *DotOp:
+		op := &DotOp{Left: opt.optimize(v.Left), Attr: v.Attr, Range: v.Range}
+		if isConstNode(op.Left) {
+			return opt.fold(op)
+		}
+		return op
+	case *FunCall:
+		call := &FunCall{FunRef: opt.optimize(v.FunRef), KeywordArgs: v.KeywordArgs, Range: v.Range}
+		allConst := opt.isPureFunction(call.FunRef)
+		for _, arg := range v.Args {
+			argNode := opt.optimize(arg.Arg)
+			allConst = allConst && isConstNode(argNode)
+			call.Args = append(call.Args, FunCallArg{Name: arg.Name, Arg: argNode})
+		}
+		if allConst {
+			return opt.fold(call)
+		}
+		return call
+	case *FunDef:
+		return &FunDef{Args: v.Args, Body: opt.optimizeScoped(v.Body, v.Args...), Range: v.Range}
+	case *IfExpr:
+		cond := opt.optimize(v.Cond)
+		if c, ok := constValue(cond); ok {
+			if c == true {
+				return opt.optimize(v.ThenBranch)
+			}
+			return opt.optimize(v.ElseBranch)
+		}
+		return &IfExpr{
+			Cond:       cond,
+			ThenBranch: opt.optimize(v.ThenBranch),
+			ElseBranch: opt.optimize(v.ElseBranch),
+			Range:      v.Range,
+		}
+	case *ArrayNode:
+		arr := &ArrayNode{Range: v.Range}
+		for _, elem := range v.Elements {
+			arr.Elements = append(arr.Elements, opt.optimize(elem))
+		}
+		return arr
+	case *MapNode:
+		m := &MapNode{Range: v.Range}
+		opt.push()
+		defer opt.pop()
+		for _, item := range v.Values {
+			m.Values = append(m.Values, MapItem{Name: item.Name, Value: opt.optimize(item.Value)})
+			opt.bound[len(opt.bound)-1][item.Name] = true
+		}
+		return m
+	case *RangeNode:
+		return &RangeNode{
+			StartOpen: v.StartOpen,
+			Start:     opt.optimize(v.Start),
+			EndOpen:   v.EndOpen,
+			End:       opt.optimize(v.End),
+			Range:     v.Range,
+		}
+	case *MultiTests:
+		tests := &MultiTests{Range: v.Range}
+		for _, elem := range v.Elements {
+			tests.Elements = append(tests.Elements, opt.optimize(elem))
+		}
+		return tests
+	case *ForExpr:
+		return &ForExpr{
+			Varname:    v.Varname,
+			ListExpr:   opt.optimize(v.ListExpr),
+			ReturnExpr: opt.optimizeScoped(v.ReturnExpr, v.Varname),
+			Range:      v.Range,
+		}
+	case *SomeExpr:
+		return &SomeExpr{
+			Varname:    v.Varname,
+			ListExpr:   opt.optimize(v.ListExpr),
+			FilterExpr: opt.optimizeScoped(v.FilterExpr, v.Varname),
+			Range:      v.Range,
+		}
+	case *EveryExpr:
+		return &EveryExpr{
+			Varname:    v.Varname,
+			ListExpr:   opt.optimize(v.ListExpr),
+			FilterExpr: opt.optimizeScoped(v.FilterExpr, v.Varname),
+			Range:      v.Range,
+		}
+	default:
+		return node
+	}
+}
+
+func (opt *optimizer) optimizeBinop(binop *Binop) Node {
+	left := opt.optimize(binop.Left)
+	right := opt.optimize(binop.Right)
+	newop := &Binop{Op: binop.Op, Left: left, Right: right, Range: binop.Range}
+	if isConstNode(left) && isConstNode(right) {
+		return opt.fold(newop)
+	}
+
+	// the circuit break operators evaluate to booleans or null
+	leftValue, leftConst := constValue(left)
+	rightValue, rightConst := constValue(right)
+	switch binop.Op {
+	case "and":
+		if leftConst {
+			if leftValue == false {
+				return &ConstNode{Value: false, Range: binop.Range}
+			} else if leftValue == true && producesBool(right) {
+				return right
+			}
+		} else if rightConst && rightValue == true && producesBool(left) {
+			return left
+		}
+	case "or":
+		if leftConst {
+			if leftValue == true {
+				return &ConstNode{Value: true, Range: binop.Range}
+			} else if leftValue == false && producesBool(right) {
+				return right
+			}
+		} else if rightConst && rightValue == false && producesBool(left) {
+			return left
+		}
+	}
+	return newop
+}
@@ -0,0 +1,75 @@
+package feel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptimizeFolding(t *testing.T) {
+	assert := assert.New(t)
+
+	pairs := [][2]string{
+		{`1 + 2 * 3`, `7`},
+		{`[1, 2, 3][2] + a`, `(+ 2 a)`},
+		{`if 2 > 1 then a else b`, `a`},
+		{`if false then a else b + 1`, `(+ b 1)`},
+		{`upper case("abc") + x`, `(+ "ABC" x)`},
+		{`@"2023-07-21T13:57:32@CST" - @"PT2H3M"`, `@"2023-07-21T11:54:32@CST"`},
+		{`false and a > 1`, `false`},
+		{`true and a > 1`, `(> a 1)`},
+		{`a > 1 or false`, `(> a 1)`},
+		{`true or x`, `true`},
+		// truthy values are not booleans
+		{`true and a`, `(and true a)`},
+		// errors are raised at runtime
+		{`@"P1D" * 30`, `(* @"P1D" 30)`},
+		// lists are not shared between evaluations
+		{`reverse([1, 2])`, `(call reverse [[1, 2]])`},
+		// nondeterministic functions
+		{`now() > @"2023-06-01T10:33:20"`, `(> (call now []) @"2023-06-01T10:33:20")`},
+		{`uuid() != ""`, `(!= (call uuid []) "")`},
+		// names bound by the expression shadow builtins
+		{`for string in ["a"] return string(1)`, `(for string ["a"] (call string [1]))`},
+		{`function(x) x + (2 * 3)`, `(function [x] (+ x 6))`},
+	}
+	for _, pair := range pairs {
+		ast, err := ParseString(pair[0])
+		assert.Nil(err)
+		assert.Equal(pair[1], Optimize(ast).Repr(), pair[0])
+	}
+
+	// inputs shadow builtins
+	ast, err := ParseString(`upper case("x") + "y"`)
+	assert.Nil(err)
+	assert.Equal(`"Xy"`, Optimize(ast).Repr())
+	assert.Equal("(+ (call `upper case` [\"x\"]) \"y\")", Optimize(ast, "upper case").Repr())
+}
+
+func TestOptimizePreservesResults(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, p := range evalPairs {
+		ast, err := ParseString(p.input)
+		assert.Nil(err)
+
+		var inputs []string
+		intp := NewIntepreter()
+		if p.context != "" {
+			assert.Nil(intp.PushVars(p.context))
+			for name := range intp.ScopeStack[intp.Len()-1] {
+				inputs = append(inputs, name)
+			}
+		}
+		optimized := Optimize(ast, inputs...)
+		res, err := intp.EvalNode(optimized)
+		assert.Nil(err, p.input)
+		assert.True(cmp.Equal(p.expect, res), "%s gives %v", p.input, res)
+
+		// the optimized tree can be evaluated again
+		res, err = intp.EvalNode(optimized)
+		assert.Nil(err, p.input)
+		assert.True(cmp.Equal(p.expect, res), "%s gives %v", p.input, res)
+	}
+}
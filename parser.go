@@ -105,10 +105,10 @@ func (p *Parser) parseUnaryTest() (Node, error) {
 		}
 		textRange.End = p.CurrentToken().Pos
 		exp := &Binop{
-			Left:      &Var{Name: "?"},
-			Op:        op,
-			Right:     right,
-			textRange: textRange,
+			Left:  &Var{Name: "?"},
+			Op:    op,
+			Right: right,
+			Range: textRange,
 		}
 		return exp, nil
 	} else {
@@ -135,7 +135,7 @@ func (p *Parser) parseUnaryTests() (Node, error) {
 			elements = append(elements, uexp)
 		}
 		textRange.End = p.CurrentToken().Pos
-		return &MultiTests{Elements: elements, textRange: textRange}, nil
+		return &MultiTests{Elements: elements, Range: textRange}, nil
 	} else {
 		return exp, nil
 	}
@@ -163,7 +163,7 @@ func (p *Parser) binop(ops []string, subfunc astFunc) (Node, error) {
 		}
 		textRange := TextRange{Start: left.TextRange().Start}
 		textRange.End = p.CurrentToken().Pos
-		left = &Binop{Op: op, Left: left, Right: right, textRange: textRange}
+		left = &Binop{Op: op, Left: left, Right: right, Range: textRange}
 	}
 	return left, nil
 }
@@ -185,7 +185,7 @@ func (p *Parser) binopKeywords(ops []string, subfunc astFunc) (Node, error) {
 		textRange := TextRange{Start: left.TextRange().Start}
 		textRange.End = p.CurrentToken().Pos
 
-		left = &Binop{Op: op, Left: left, Right: right, textRange: textRange}
+		left = &Binop{Op: op, Left: left, Right: right, Range: textRange}
 	}
 	return left, nil
 }
@@ -229,6 +229,13 @@ func (p *Parser) addOrSubOp() (Node, error) {
 func (p *Parser) mulOrDivOp() (Node, error) {
 	return p.binop(
 		[]string{"*", "/", "%"},
+		p.powOp,
+	)
+}
+
+func (p *Parser) powOp() (Node, error) {
+	return p.binop(
+		[]string{"**"},
 		p.parseFuncallOrIndexOrDot,
 	)
 }
@@ -269,13 +276,13 @@ func (p *Parser) parseFuncallOrIndexOrDot() (Node, error) {
 // 	funcallWithRbracket := p.CurrentToken().Value
 // 	funcName := funcallTrailing.ReplaceAllString(funcallWithRbracket, "")
 // 	textRange := TextRange{Start: Node.TextRange().Start, End: p.CurrentToken().Pos}
-// 	return p.parseFuncallRest(&Var{Name: funcName, textRange: })
+// 	return p.parseFuncallRest(&Var{Name: funcName, Range: })
 // // }
 
-func (p *Parser) parseFunccallArg() (funcallArg, error) {
+func (p *Parser) parseFunccallArg() (FunCallArg, error) {
 	arg, err := p.expression()
 	if err != nil {
-		return funcallArg{}, err
+		return FunCallArg{}, err
 	}
 
 	if p.CurrentToken().Expect(":") { // kwargs
@@ -283,35 +290,35 @@ func (p *Parser) parseFunccallArg() (funcallArg, error) {
 			p.scanner.Next()
 			argValue, err := p.expression()
 			if err != nil {
-				return funcallArg{}, err
+				return FunCallArg{}, err
 			}
-			return funcallArg{argName: varArg.Name, arg: argValue}, nil
+			return FunCallArg{Name: varArg.Name, Arg: argValue}, nil
 		} else {
-			return funcallArg{}, p.Unexpected("var")
+			return FunCallArg{}, p.Unexpected("var")
 		}
 	} else {
-		return funcallArg{argName: "", arg: arg}, nil
+		return FunCallArg{Name: "", Arg: arg}, nil
 	}
 }
 
 func (p *Parser) parseFuncallRest(funExpr Node) (Node, error) {
 	p.scanner.Next()
 	// parse function arguments
-	var args []funcallArg = nil
+	var args []FunCallArg = nil
 	keywordArgs := false
 	for !p.CurrentToken().Expect(")") {
 		arg, err := p.parseFunccallArg()
 		if err != nil {
 			return nil, err
 		}
-		if !keywordArgs && arg.argName != "" {
+		if !keywordArgs && arg.Name != "" {
 			keywordArgs = true
 		}
 		if len(args) > 0 {
-			if arg.argName != "" && args[0].argName == "" {
+			if arg.Name != "" && args[0].Name == "" {
 				return nil, p.Unexpected("non var")
 			}
-			if arg.argName == "" && args[0].argName != "" {
+			if arg.Name == "" && args[0].Name != "" {
 				return nil, p.Unexpected("var")
 			}
 		}
@@ -331,8 +338,8 @@ func (p *Parser) parseFuncallRest(funExpr Node) (Node, error) {
 	return &FunCall{
 		FunRef:      funExpr,
 		Args:        args,
-		keywordArgs: keywordArgs,
-		textRange:   textRange,
+		KeywordArgs: keywordArgs,
+		Range:       textRange,
 	}, nil
 }
 
@@ -351,18 +358,18 @@ func (p *Parser) parseIndexRest(exp Node) (Node, error) {
 	p.scanner.Next()
 	textRange := TextRange{Start: exp.TextRange().Start, End: p.CurrentToken().Pos}
 
-	return &Binop{Left: exp, Op: "[]", Right: at, textRange: textRange}, nil
+	return &Binop{Left: exp, Op: "[]", Right: at, Range: textRange}, nil
 }
 
 func (p *Parser) parseDotRest(exp Node) (Node, error) {
 	p.scanner.Next()
 	// parse index arguments
-	attr, err := p.parseName()
+	attr, err := p.parseName(exprStopKeywords...)
 	if err != nil {
 		return nil, err
 	}
 	textRange := TextRange{Start: exp.TextRange().Start, End: p.CurrentToken().Pos}
-	return &DotOp{Left: exp, Attr: attr, textRange: textRange}, nil
+	return &DotOp{Left: exp, Attr: attr, Range: textRange}, nil
 }
 
 func (p *Parser) simpleValue() (Node, error) {
@@ -432,12 +439,12 @@ func (p *Parser) singleElement() (Node, error) {
 
 func (p *Parser) parseVar() (Node, error) {
 	textRange := p.startTextRange()
-	name, err := p.parseName()
+	name, err := p.parseName(exprStopKeywords...)
 	if err != nil {
 		return nil, err
 	}
 	textRange.End = p.CurrentToken().Pos
-	return &Var{Name: name, textRange: textRange}, nil
+	return &Var{Name: name, Range: textRange}, nil
 }
 
 func (p *Parser) parseBool() (Node, error) {
@@ -447,9 +454,9 @@ func (p *Parser) parseBool() (Node, error) {
 	textRange.End = p.CurrentToken().Pos
 	switch v {
 	case "true":
-		return &BoolNode{Value: true, textRange: textRange}, nil
+		return &BoolNode{Value: true, Range: textRange}, nil
 	case "false":
-		return &BoolNode{Value: false, textRange: textRange}, nil
+		return &BoolNode{Value: false, Range: textRange}, nil
 	default:
 		return nil, p.Unexpected("true", "false")
 	}
@@ -459,7 +466,7 @@ func (p *Parser) parseNull() (Node, error) {
 	textRange := p.startTextRange()
 	p.scanner.Next()
 	textRange.End = p.CurrentToken().Pos
-	return &NullNode{textRange: textRange}, nil
+	return &NullNode{Range: textRange}, nil
 }
 
 func containsKeywords(keywords []string, kw string) bool {
@@ -471,6 +478,22 @@ func containsKeywords(keywords []string, kw string) bool {
 	return false
 }
 
+// keywords such as `and` may be part of names like `date and time`,
+// while these following an expression end the name
+var exprStopKeywords = []string{"then", "else", "return", "satisfies", "in", "and", "or"}
+
+// continuesPreludeName tells whether the keyword continues the name into
+// a prelude name, such as `and` after `date`
+func continuesPreludeName(names []string, keyword string) bool {
+	prefix := strings.Join(append(names, keyword), " ") + " "
+	for name := range GetPrelude().vars {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parser) parseName(stopKeywords ...string) (string, error) {
 	names := make([]string, 0)
 
@@ -482,7 +505,7 @@ func (p *Parser) parseName(stopKeywords ...string) (string, error) {
 			// keyworlds
 			//if p.CurrentToken()
 			kwVal := p.CurrentToken().Value
-			if len(names) > 0 && containsKeywords(stopKeywords, kwVal) {
+			if len(names) > 0 && containsKeywords(stopKeywords, kwVal) && !continuesPreludeName(names, kwVal) {
 				break
 			} else {
 				names = append(names, kwVal)
@@ -515,11 +538,11 @@ func (p *Parser) parseBracketOrRange() (Node, error) {
 		if p.CurrentToken().Kind == ")" {
 			p.scanner.Next()
 			textRange.End = p.CurrentToken().Pos
-			return &RangeNode{StartOpen: true, Start: c, EndOpen: true, End: d, textRange: textRange}, nil
+			return &RangeNode{StartOpen: true, Start: c, EndOpen: true, End: d, Range: textRange}, nil
 		} else if p.CurrentToken().Kind == "]" {
 			p.scanner.Next()
 			textRange.End = p.CurrentToken().Pos
-			return &RangeNode{StartOpen: true, Start: c, EndOpen: false, End: d, textRange: textRange}, nil
+			return &RangeNode{StartOpen: true, Start: c, EndOpen: false, End: d, Range: textRange}, nil
 		}
 		return nil, p.Unexpected(")", "]")
 	} else if p.CurrentToken().Expect(")") {
@@ -537,7 +560,8 @@ func (p *Parser) parseRangeOrArray() (Node, error) {
 	if p.CurrentToken().Expect("]") {
 		p.scanner.Next()
 		// empty array
-		return &ArrayNode{}, nil
+		rng.End = p.CurrentToken().Pos
+		return &ArrayNode{Range: rng}, nil
 	}
 	c, err := p.expression()
 	if err != nil {
@@ -545,7 +569,7 @@ func (p *Parser) parseRangeOrArray() (Node, error) {
 	}
 
 	if p.CurrentToken().Expect(",", "]") {
-		return p.parseArrayGivenFirst(prefixKind, c)
+		return p.parseArrayGivenFirst(rng, c)
 	}
 
 	if !p.CurrentToken().Expect("..") {
@@ -561,17 +585,16 @@ func (p *Parser) parseRangeOrArray() (Node, error) {
 	if p.CurrentToken().Kind == ")" {
 		p.scanner.Next()
 		rng.End = p.CurrentToken().Pos
-		return &RangeNode{StartOpen: startOpen, Start: c, EndOpen: true, End: d, textRange: rng}, nil
+		return &RangeNode{StartOpen: startOpen, Start: c, EndOpen: true, End: d, Range: rng}, nil
 	} else if p.CurrentToken().Kind == "]" {
 		p.scanner.Next()
 		rng.End = p.CurrentToken().Pos
-		return &RangeNode{StartOpen: startOpen, Start: c, EndOpen: false, End: d, textRange: rng}, nil
+		return &RangeNode{StartOpen: startOpen, Start: c, EndOpen: false, End: d, Range: rng}, nil
 	}
 	return nil, p.Unexpected(")", "]")
 }
 
-func (p *Parser) parseArrayGivenFirst(prefixKind string, firstElem Node) (Node, error) {
-	rng := p.startTextRange()
+func (p *Parser) parseArrayGivenFirst(rng TextRange, firstElem Node) (Node, error) {
 	elements := []Node{firstElem}
 	for p.CurrentToken().Expect(",") {
 		p.scanner.Next()
@@ -586,7 +609,7 @@ func (p *Parser) parseArrayGivenFirst(prefixKind string, firstElem Node) (Node,
 	}
 	p.scanner.Next()
 	rng.End = p.CurrentToken().Pos
-	return &ArrayNode{Elements: elements, textRange: rng}, nil
+	return &ArrayNode{Elements: elements, Range: rng}, nil
 }
 
 func (p *Parser) parseNumberNode() (Node, error) {
@@ -594,7 +617,7 @@ func (p *Parser) parseNumberNode() (Node, error) {
 	v := p.CurrentToken().Value
 	p.scanner.Next()
 	rng.End = p.CurrentToken().Pos
-	return &NumberNode{Value: v, textRange: rng}, nil
+	return &NumberNode{Value: v, Range: rng}, nil
 }
 
 func (p *Parser) parseStringNode() (Node, error) {
@@ -602,7 +625,7 @@ func (p *Parser) parseStringNode() (Node, error) {
 	v := p.CurrentToken().Value
 	p.scanner.Next()
 	rng.End = p.CurrentToken().Pos
-	return &StringNode{Value: v, textRange: rng}, nil
+	return &StringNode{Value: v, Range: rng}, nil
 }
 
 func (p *Parser) parseMapKey() (string, error) {
@@ -625,13 +648,13 @@ func (p *Parser) parseTemporalNode() (Node, error) {
 	v := p.CurrentToken().Value
 	p.scanner.Next()
 	rng.End = p.CurrentToken().Pos
-	return &TemporalNode{Value: v, textRange: rng}, nil
+	return &TemporalNode{Value: v, Range: rng}, nil
 }
 
 func (p *Parser) parseMapNode() (Node, error) {
 	rng := p.startTextRange()
 	p.scanner.Next()
-	var mapValues []mapItem
+	var mapValues []MapItem
 
 	for !p.CurrentToken().Expect("}") {
 		key, err := p.parseMapKey()
@@ -649,7 +672,7 @@ func (p *Parser) parseMapNode() (Node, error) {
 			return nil, err
 		}
 
-		mapValues = append(mapValues, mapItem{Name: key, Value: exp})
+		mapValues = append(mapValues, MapItem{Name: key, Value: exp})
 
 		if p.CurrentToken().Expect(",") {
 			p.scanner.Next()
@@ -661,7 +684,7 @@ func (p *Parser) parseMapNode() (Node, error) {
 		p.scanner.Next()
 	}
 	rng.End = p.CurrentToken().Pos
-	return &MapNode{Values: mapValues, textRange: rng}, nil
+	return &MapNode{Values: mapValues, Range: rng}, nil
 }
 
 func (p *Parser) parseIfExpression() (Node, error) {
@@ -691,7 +714,7 @@ func (p *Parser) parseIfExpression() (Node, error) {
 	}
 
 	rng.End = p.CurrentToken().Pos
-	return &IfExpr{Cond: cond, ThenBranch: then_branch, ElseBranch: else_branch, textRange: rng}, nil
+	return &IfExpr{Cond: cond, ThenBranch: then_branch, ElseBranch: else_branch, Range: rng}, nil
 
 }
 
@@ -719,10 +742,12 @@ func (p *Parser) parseForExpr() (Node, error) {
 		if err != nil {
 			return nil, err
 		}
+		rng.End = returnExpr.TextRange().End
 		return &ForExpr{
 			Varname:    varName,
 			ListExpr:   listExpr,
 			ReturnExpr: returnExpr,
+			Range:      rng,
 		}, nil
 	}
 
@@ -741,7 +766,7 @@ func (p *Parser) parseForExpr() (Node, error) {
 		Varname:    varName,
 		ListExpr:   listExpr,
 		ReturnExpr: returnExpr,
-		textRange:  rng,
+		Range:      rng,
 	}, nil
 }
 
@@ -780,14 +805,14 @@ func (p *Parser) parseSomeOrEvery() (Node, error) {
 			Varname:    varName,
 			ListExpr:   listExpr,
 			FilterExpr: filterExpr,
-			textRange:  rng,
+			Range:      rng,
 		}, nil
 	} else {
 		return &EveryExpr{
 			Varname:    varName,
 			ListExpr:   listExpr,
 			FilterExpr: filterExpr,
-			textRange:  rng,
+			Range:      rng,
 		}, nil
 	}
 }
@@ -830,8 +855,8 @@ func (p *Parser) parseFunDef() (Node, error) {
 	}
 	rng.End = p.CurrentToken().Pos
 	return &FunDef{
-		Args:      args,
-		Body:      exp,
-		textRange: rng,
+		Args:  args,
+		Body:  exp,
+		Range: rng,
 	}, nil
 }
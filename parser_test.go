@@ -74,6 +74,13 @@ func TestForExpression(t *testing.T) {
 	ast, err := ParseString(input)
 	assert.Nil(err)
 	assert.Equal("(for x [3, 4] (for y [5, 9] (* x y)))", ast.Repr())
+
+	// the range of the outer for covers the nested one
+	input = `for x in [3, 4], y in [5, 9] return x * y`
+	ast, err = ParseString(input)
+	assert.Nil(err)
+	assert.Equal(0, ast.TextRange().Start.Column)
+	assert.Equal(len(input), ast.TextRange().End.Column)
 }
 
 func TestSomeExpression(t *testing.T) {
@@ -188,3 +195,34 @@ func TestTemporal(t *testing.T) {
 	assert.True(ok)
 	assert.Equal("2023-06-07", node.Content())
 }
+
+func TestArrayRange(t *testing.T) {
+	assert := assert.New(t)
+
+	// arrays range from the opening bracket
+	ast, err := ParseString(`[1, 2]`)
+	assert.Nil(err)
+	assert.Equal(0, ast.TextRange().Start.Column)
+	assert.Equal(6, ast.TextRange().End.Column)
+
+	ast, err = ParseString(`[]`)
+	assert.Nil(err)
+	assert.Equal(0, ast.TextRange().Start.Column)
+	assert.Equal(2, ast.TextRange().End.Column)
+}
+
+func TestNamesBeforeKeywords(t *testing.T) {
+	assert := assert.New(t)
+
+	ast, err := ParseString(`if a.b then c else d`)
+	assert.Nil(err)
+	assert.Equal(`(if (. a b) c d)`, ast.Repr())
+
+	ast, err = ParseString(`for x in items return every y in x.parts satisfies y`)
+	assert.Nil(err)
+	assert.Equal(`(for x items (every "y" (. x parts) y))`, ast.Repr())
+
+	ast, err = ParseString(`date and time("2023-06-01T10:33:20")`)
+	assert.Nil(err)
+	assert.Equal("(call `date and time` [\"2023-06-01T10:33:20\"])", ast.Repr())
+}
@@ -0,0 +1,180 @@
+package feel
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// a minimal encoder of the pprof profile format, see
+// https://github.com/google/pprof/blob/main/proto/profile.proto
+
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protoBuffer) key(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protoBuffer) uint64Field(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, 0)
+	b.varint(x)
+}
+
+func (b *protoBuffer) int64Field(field int, x int64) {
+	b.uint64Field(field, uint64(x))
+}
+
+func (b *protoBuffer) bytesField(field int, data []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+func (b *protoBuffer) packedUint64s(field int, xs []uint64) {
+	var packed protoBuffer
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	b.bytesField(field, packed.data)
+}
+
+func (b *protoBuffer) message(field int, encode func(msg *protoBuffer)) {
+	var msg protoBuffer
+	encode(&msg)
+	b.bytesField(field, msg.data)
+}
+
+// fields of the Profile message
+const (
+	pprofSampleType        = 1
+	pprofSample            = 2
+	pprofLocation          = 4
+	pprofFunction          = 5
+	pprofStringTable       = 6
+	pprofTimeNanos         = 9
+	pprofDurationNanos     = 10
+	pprofPeriodType        = 11
+	pprofPeriod            = 12
+	pprofDefaultSampleType = 14
+)
+
+type pprofStrings struct {
+	table []string
+	index map[string]int64
+}
+
+func (strs *pprofStrings) id(s string) int64 {
+	if i, ok := strs.index[s]; ok {
+		return i
+	}
+	i := int64(len(strs.table))
+	strs.table = append(strs.table, s)
+	strs.index[s] = i
+	return i
+}
+
+// WritePprof writes the profile in the gzipped pprof format, which
+// can be inspected by `go tool pprof`. Functions and source
+// locations are the frames of the stacks
+func (profiler *Profiler) WritePprof(w io.Writer) error {
+	strs := &pprofStrings{index: make(map[string]int64)}
+	strs.id("")
+
+	var buf protoBuffer
+	valueType := func(field int, tp, unit string) {
+		buf.message(field, func(msg *protoBuffer) {
+			msg.int64Field(1, strs.id(tp))
+			msg.int64Field(2, strs.id(unit))
+		})
+	}
+	valueType(pprofSampleType, "calls", "count")
+	valueType(pprofSampleType, "time", "nanoseconds")
+	valueType(pprofSampleType, "alloc_objects", "count")
+	valueType(pprofSampleType, "alloc_space", "bytes")
+
+	keys := make([]string, 0, len(profiler.samples))
+	for key := range profiler.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		sample := profiler.samples[key]
+		locIds := make([]uint64, len(sample.stack))
+		for i, entry := range sample.stack {
+			locIds[i] = entry.id
+		}
+		buf.message(pprofSample, func(msg *protoBuffer) {
+			msg.packedUint64s(1, locIds)
+			msg.packedUint64s(2, []uint64{
+				uint64(sample.calls),
+				uint64(sample.time.Nanoseconds()),
+				uint64(sample.allocs.objects),
+				uint64(sample.allocs.bytes),
+			})
+		})
+	}
+
+	// every entry is a location with a function of the same id
+	for _, entry := range profiler.ordered {
+		entry := entry
+		buf.message(pprofLocation, func(msg *protoBuffer) {
+			msg.uint64Field(1, entry.id)
+			msg.message(4, func(line *protoBuffer) {
+				line.uint64Field(1, entry.id)
+				line.int64Field(2, int64(entry.Range.Start.Row+1))
+				line.int64Field(3, int64(entry.Range.Start.Column+1))
+			})
+		})
+	}
+	for _, entry := range profiler.ordered {
+		entry := entry
+		name := entry.Name
+		filename := profiler.SourceName
+		if entry.function {
+			if entry.Kind != "function" {
+				filename = "<builtin>"
+			}
+		} else {
+			name = entry.Kind + " " + entry.Name
+		}
+		buf.message(pprofFunction, func(msg *protoBuffer) {
+			msg.uint64Field(1, entry.id)
+			msg.int64Field(2, strs.id(name))
+			msg.int64Field(3, strs.id(entry.Kind))
+			msg.int64Field(4, strs.id(filename))
+			msg.int64Field(5, int64(entry.Range.Start.Row+1))
+		})
+	}
+
+	if !profiler.startTime.IsZero() {
+		buf.int64Field(pprofTimeNanos, profiler.startTime.UnixNano())
+	}
+	buf.int64Field(pprofDurationNanos, profiler.total.Nanoseconds())
+	valueType(pprofPeriodType, "calls", "count")
+	buf.int64Field(pprofPeriod, 1)
+	buf.int64Field(pprofDefaultSampleType, strs.id("time"))
+
+	// the string table goes last as the ids are collected above
+	for _, s := range strs.table {
+		buf.bytesField(pprofStringTable, []byte(s))
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(buf.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,346 @@
+package feel
+
+import (
+	"fmt"
+	"runtime/metrics"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProfileEntry is the cost of a function or of a source location
+type ProfileEntry struct {
+	// function name, or the expression at the source location
+	Name string
+	// native, macro or function for functions, node kind for locations
+	Kind string
+	// the source range of a location, or the body of a user defined function
+	Range TextRange
+
+	Calls int64
+	// time spent in the entry, excluding the nested entries
+	Self time.Duration
+	// time spent in the entry and the entries nested in it
+	Cum time.Duration
+	// heap allocations are read from the runtime metrics, which are
+	// approximate at small granularity
+	SelfAllocs     int64
+	SelfAllocBytes int64
+	CumAllocs      int64
+	CumAllocBytes  int64
+
+	id       uint64
+	function bool
+	// the number of active frames of the entry, so that the cumulative
+	// cost of recursive calls is only counted once
+	active int
+}
+
+type allocStat struct {
+	objects int64
+	bytes   int64
+}
+
+func (stat allocStat) sub(other allocStat) allocStat {
+	return allocStat{objects: stat.objects - other.objects, bytes: stat.bytes - other.bytes}
+}
+
+func (stat allocStat) add(other allocStat) allocStat {
+	return allocStat{objects: stat.objects + other.objects, bytes: stat.bytes + other.bytes}
+}
+
+type profileFrame struct {
+	entry      *ProfileEntry
+	start      time.Time
+	startAlloc allocStat
+	childTime  time.Duration
+	childAlloc allocStat
+}
+
+// profileSample is the self cost of a stack of entries, in pprof terms
+type profileSample struct {
+	stack  []*ProfileEntry
+	calls  int64
+	time   time.Duration
+	allocs allocStat
+}
+
+type locationKey struct {
+	kind string
+	rng  TextRange
+}
+
+// Profiler is an EvalHook measuring call counts, time and heap
+// allocations of every function call and source location
+type Profiler struct {
+	// the file name of the source reported in pprof profiles
+	SourceName string
+
+	entries   map[locationKey]*ProfileEntry
+	functions map[string]*ProfileEntry
+	ordered   []*ProfileEntry
+	samples   map[string]*profileSample
+	stack     []*profileFrame
+
+	startTime time.Time
+	total     time.Duration
+
+	allocMetrics []metrics.Sample
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		SourceName: "<input>",
+		entries:    make(map[locationKey]*ProfileEntry),
+		functions:  make(map[string]*ProfileEntry),
+		samples:    make(map[string]*profileSample),
+		allocMetrics: []metrics.Sample{
+			{Name: "/gc/heap/allocs:objects"},
+			{Name: "/gc/heap/allocs:bytes"},
+		},
+	}
+}
+
+// EnableProfile creates a profiler measuring the evaluations of intp
+func (intp *Interpreter) EnableProfile() *Profiler {
+	profiler := NewProfiler()
+	intp.AddHook(profiler)
+	return profiler
+}
+
+func (profiler *Profiler) readAlloc() allocStat {
+	metrics.Read(profiler.allocMetrics)
+	var stat allocStat
+	if s := profiler.allocMetrics[0]; s.Value.Kind() == metrics.KindUint64 {
+		stat.objects = int64(s.Value.Uint64())
+	}
+	if s := profiler.allocMetrics[1]; s.Value.Kind() == metrics.KindUint64 {
+		stat.bytes = int64(s.Value.Uint64())
+	}
+	return stat
+}
+
+func (profiler *Profiler) newEntry(name, kind string, rng TextRange, function bool) *ProfileEntry {
+	entry := &ProfileEntry{
+		Name:     name,
+		Kind:     kind,
+		Range:    rng,
+		id:       uint64(len(profiler.ordered) + 1),
+		function: function,
+	}
+	profiler.ordered = append(profiler.ordered, entry)
+	return entry
+}
+
+func (profiler *Profiler) locationEntry(node Node) *ProfileEntry {
+	key := locationKey{kind: nodeKind(node), rng: node.TextRange()}
+	entry, ok := profiler.entries[key]
+	if !ok {
+		entry = profiler.newEntry(shortRepr(node.Repr()), key.kind, key.rng, false)
+		profiler.entries[key] = entry
+	}
+	return entry
+}
+
+func (profiler *Profiler) functionEntry(funcName string, fn any) *ProfileEntry {
+	var kind string
+	var rng TextRange
+	switch v := fn.(type) {
+	case *NativeFun:
+		kind = "native"
+	case *Macro:
+		kind = "macro"
+	case *FunDef:
+		kind = "function"
+		rng = v.Body.TextRange()
+	default:
+		kind = typeName(fn)
+	}
+	key := kind + " " + funcName
+	if kind == "function" {
+		// anonymous functions are told apart by their bodies
+		key = fmt.Sprintf("%s@%d:%d", key, rng.Start.Row, rng.Start.Column)
+	}
+	entry, ok := profiler.functions[key]
+	if !ok {
+		entry = profiler.newEntry(funcName, kind, rng, true)
+		profiler.functions[key] = entry
+	}
+	return entry
+}
+
+func (profiler *Profiler) push(entry *ProfileEntry) {
+	if len(profiler.stack) == 0 && profiler.startTime.IsZero() {
+		profiler.startTime = time.Now()
+	}
+	entry.active++
+	profiler.stack = append(profiler.stack, &profileFrame{
+		entry:      entry,
+		startAlloc: profiler.readAlloc(),
+		// read the clock last so that reading the metrics is not counted
+		start: time.Now(),
+	})
+}
+
+func (profiler *Profiler) pop() {
+	now := time.Now()
+	alloc := profiler.readAlloc()
+	n := len(profiler.stack)
+	if n == 0 {
+		return
+	}
+	frame := profiler.stack[n-1]
+	profiler.stack = profiler.stack[:n-1]
+
+	entry := frame.entry
+	elapsed := now.Sub(frame.start)
+	allocated := alloc.sub(frame.startAlloc)
+	selfTime := elapsed - frame.childTime
+	selfAlloc := allocated.sub(frame.childAlloc)
+
+	entry.active--
+	entry.Calls++
+	entry.Self += selfTime
+	entry.SelfAllocs += selfAlloc.objects
+	entry.SelfAllocBytes += selfAlloc.bytes
+	if entry.active == 0 {
+		entry.Cum += elapsed
+		entry.CumAllocs += allocated.objects
+		entry.CumAllocBytes += allocated.bytes
+	}
+
+	if n > 1 {
+		parent := profiler.stack[n-2]
+		parent.childTime += elapsed
+		parent.childAlloc = parent.childAlloc.add(allocated)
+	} else {
+		profiler.total += elapsed
+	}
+	profiler.addSample(frame.entry, selfTime, selfAlloc)
+}
+
+func (profiler *Profiler) addSample(leaf *ProfileEntry, selfTime time.Duration, selfAlloc allocStat) {
+	stack := make([]*ProfileEntry, 0, len(profiler.stack)+1)
+	stack = append(stack, leaf)
+	for i := len(profiler.stack) - 1; i >= 0; i-- {
+		stack = append(stack, profiler.stack[i].entry)
+	}
+	var sb strings.Builder
+	for _, entry := range stack {
+		fmt.Fprintf(&sb, "%d,", entry.id)
+	}
+	key := sb.String()
+	sample, ok := profiler.samples[key]
+	if !ok {
+		sample = &profileSample{stack: stack}
+		profiler.samples[key] = sample
+	}
+	sample.calls++
+	sample.time += selfTime
+	sample.allocs = sample.allocs.add(selfAlloc)
+}
+
+func (profiler *Profiler) BeforeEval(intp *Interpreter, node Node) error {
+	profiler.push(profiler.locationEntry(node))
+	return nil
+}
+
+func (profiler *Profiler) AfterEval(intp *Interpreter, node Node, value any, err error) {
+	profiler.pop()
+}
+
+func (profiler *Profiler) BeforeCall(intp *Interpreter, funcName string, fn any, args map[string]any) {
+	profiler.push(profiler.functionEntry(funcName, fn))
+}
+
+func (profiler *Profiler) AfterCall(intp *Interpreter, funcName string, fn any, result any, err error) {
+	profiler.pop()
+}
+
+// Total is the time of the evaluations profiled
+func (profiler *Profiler) Total() time.Duration {
+	return profiler.total
+}
+
+func sortedEntries(entries []*ProfileEntry) []ProfileEntry {
+	sorted := make([]ProfileEntry, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, *entry)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Self != sorted[j].Self {
+			return sorted[i].Self > sorted[j].Self
+		}
+		return sorted[i].Cum > sorted[j].Cum
+	})
+	return sorted
+}
+
+// Functions returns the costs of functions, the most expensive first
+func (profiler *Profiler) Functions() []ProfileEntry {
+	var entries []*ProfileEntry
+	for _, entry := range profiler.ordered {
+		if entry.function {
+			entries = append(entries, entry)
+		}
+	}
+	return sortedEntries(entries)
+}
+
+// Locations returns the costs of source locations, the most expensive first
+func (profiler *Profiler) Locations() []ProfileEntry {
+	var entries []*ProfileEntry
+	for _, entry := range profiler.ordered {
+		if !entry.function {
+			entries = append(entries, entry)
+		}
+	}
+	return sortedEntries(entries)
+}
+
+func percent(d, total time.Duration) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(d) * 100 / float64(total)
+}
+
+func (profiler *Profiler) writeTop(sb *strings.Builder, title string, entries []ProfileEntry, n int) {
+	fmt.Fprintf(sb, "%s:\n", title)
+	fmt.Fprintf(sb, "%12s %6s %12s %6s %8s %10s  %s\n", "self", "self%", "cum", "cum%", "calls", "alloc", "name")
+	for i, entry := range entries {
+		if n > 0 && i >= n {
+			break
+		}
+		name := entry.Name
+		if entry.function {
+			name = fmt.Sprintf("%s (%s)", entry.Name, entry.Kind)
+		} else {
+			pos := entry.Range.Start
+			name = fmt.Sprintf("%d:%d %s", pos.Row+1, pos.Column+1, entry.Name)
+		}
+		fmt.Fprintf(sb, "%12s %5.1f%% %12s %5.1f%% %8d %9dB  %s\n",
+			entry.Self, percent(entry.Self, profiler.total),
+			entry.Cum, percent(entry.Cum, profiler.total),
+			entry.Calls, entry.CumAllocBytes, name)
+	}
+}
+
+// Top renders the n most expensive functions and source locations by
+// self time, n <= 0 renders all of them
+func (profiler *Profiler) Top(n int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "total %s\n", profiler.total)
+	profiler.writeTop(&sb, "functions", profiler.Functions(), n)
+	profiler.writeTop(&sb, "locations", profiler.Locations(), n)
+	return sb.String()
+}
+
+func shortRepr(repr string) string {
+	const maxLen = 60
+	runes := []rune(repr)
+	if len(runes) > maxLen {
+		return string(runes[:maxLen-3]) + "..."
+	}
+	return repr
+}
@@ -0,0 +1,81 @@
+package feel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileEvaluation(t *testing.T) {
+	assert := assert.New(t)
+
+	intp := NewIntepreter()
+	assert.Nil(intp.PushVars(`{double: function(v) v * 2}`))
+	profiler := intp.EnableProfile()
+
+	ast, err := ParseString(`for x in [1, 2, 3] return double(sum([x, 1]))`)
+	assert.Nil(err)
+	res, err := intp.EvalNode(ast)
+	assert.Nil(err)
+	assert.True(cmp.Equal([]any{N(4), N(6), N(8)}, res))
+
+	funcs := make(map[string]ProfileEntry)
+	for _, entry := range profiler.Functions() {
+		funcs[entry.Name] = entry
+	}
+	assert.Equal(2, len(funcs))
+	assert.Equal("native", funcs["sum"].Kind)
+	assert.Equal(int64(3), funcs["sum"].Calls)
+	assert.Equal("function", funcs["double"].Kind)
+	assert.Equal(int64(3), funcs["double"].Calls)
+	assert.True(funcs["double"].Cum >= funcs["double"].Self)
+
+	var root ProfileEntry
+	for _, entry := range profiler.Locations() {
+		if entry.Kind == "ForExpr" {
+			root = entry
+		}
+		assert.True(entry.Self >= 0)
+	}
+	assert.Equal(int64(1), root.Calls)
+	assert.Equal(profiler.Total(), root.Cum)
+
+	top := profiler.Top(3)
+	assert.True(strings.Contains(top, "functions:"))
+	assert.True(strings.Contains(top, "(native)"))
+	assert.True(strings.Contains(top, "locations:"))
+
+	var buf bytes.Buffer
+	assert.Nil(profiler.WritePprof(&buf))
+	zr, err := gzip.NewReader(&buf)
+	assert.Nil(err)
+	data, err := io.ReadAll(zr)
+	assert.Nil(err)
+	assert.True(bytes.Contains(data, []byte("alloc_space")))
+	assert.True(bytes.Contains(data, []byte("double")))
+}
+
+func TestProfileRecursion(t *testing.T) {
+	assert := assert.New(t)
+
+	intp := NewIntepreter()
+	assert.Nil(intp.PushVars(`{fact: function(n) if n < 2 then 1 else n * fact(n - 1)}`))
+	profiler := intp.EnableProfile()
+
+	ast, err := ParseString(`fact(5)`)
+	assert.Nil(err)
+	res, err := intp.EvalNode(ast)
+	assert.Nil(err)
+	assert.True(cmp.Equal(N(120), res))
+
+	fact := profiler.Functions()[0]
+	assert.Equal("fact", fact.Name)
+	assert.Equal(int64(5), fact.Calls)
+	// nested calls are counted once in the cumulative time
+	assert.True(fact.Cum <= profiler.Total())
+}
@@ -0,0 +1,59 @@
+package feel
+
+// random functions, refer to
+// https://docs.camunda.io/docs/components/modeler/feel/builtin-functions/feel-built-in-functions-numeric/#random-number
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// SetRandomSource sets the source of the bytes random number() and
+// uuid() are made of, e.g. a seeded math/rand.Rand gives reproducible
+// evaluations, nil restores crypto/rand
+func (intp *Interpreter) SetRandomSource(source io.Reader) {
+	intp.random = source
+}
+
+func randomSource(intp *Interpreter) io.Reader {
+	if intp == nil || intp.random == nil {
+		return rand.Reader
+	}
+	return intp.random
+}
+
+// randomNumber makes a number in [0, 1) of 34 random digits
+func randomNumber(source io.Reader) (*Number, error) {
+	// 192 bits make the bias of the modulo negligible
+	buf := make([]byte, 24)
+	if _, err := io.ReadFull(source, buf); err != nil {
+		return nil, err
+	}
+	coef := new(big.Int).SetBytes(buf)
+	coef.Mod(coef, pow10(Digits))
+	return newNumber(coef, -Digits, false), nil
+}
+
+// randomUUID makes a version 4 UUID
+func randomUUID(source io.Reader) (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(source, b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// builtin functions
+func installRandomFunctions(prelude *Prelude) {
+	prelude.Bind("random number", NewNativeFuncWithEnv(func(intp *Interpreter, args map[string]any) (any, error) {
+		return randomNumber(randomSource(intp))
+	}).Nondeterministic())
+
+	prelude.Bind("uuid", NewNativeFuncWithEnv(func(intp *Interpreter, args map[string]any) (any, error) {
+		return randomUUID(randomSource(intp))
+	}).Nondeterministic())
+}
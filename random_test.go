@@ -0,0 +1,53 @@
+package feel
+
+import (
+	"math/rand"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomFunctions(t *testing.T) {
+	assert := assert.New(t)
+
+	ast, err := ParseString(`[random number(), uuid(), random number()]`)
+	assert.Nil(err)
+
+	// the same seed gives the same results in the tree walker and the VM
+	intp := NewIntepreter()
+	intp.SetRandomSource(rand.New(rand.NewSource(42)))
+	walked, err := intp.EvalNode(ast)
+	assert.Nil(err)
+
+	intp = NewIntepreter()
+	intp.SetRandomSource(rand.New(rand.NewSource(42)))
+	run, err := Compile(ast).Run(intp)
+	assert.Nil(err)
+
+	s, err := FormatValue(walked)
+	assert.Nil(err)
+	again, err := FormatValue(run)
+	assert.Nil(err)
+	assert.Equal(s, again)
+
+	values := walked.([]any)
+	for _, v := range []any{values[0], values[2]} {
+		n := v.(*Number)
+		assert.True(n.Sign() >= 0 && n.Cmp(N(1)) < 0, n.String())
+	}
+	assert.NotEqual(values[0].(*Number).String(), values[2].(*Number).String())
+	assert.Regexp(regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), values[1])
+
+	// different seeds diverge
+	intp = NewIntepreter()
+	intp.SetRandomSource(rand.New(rand.NewSource(7)))
+	other, err := intp.EvalNode(ast)
+	assert.Nil(err)
+	assert.NotEqual(values[1], other.([]any)[1])
+
+	// crypto/rand is the default source
+	res, err := EvalString(`uuid() != uuid()`)
+	assert.Nil(err)
+	assert.Equal(true, res)
+}
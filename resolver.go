@@ -0,0 +1,132 @@
+package feel
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Resolver provides the values of names on demand, it's consulted
+// after the scope stack when resolving variables, and when a context
+// value is a Resolver, `DotOp` fetches its attributes through it.
+// Resolved values are cached until the next evaluation of the
+// interpreter starts.
+type Resolver interface {
+	Resolve(name string) (any, bool, error)
+}
+
+// KeysResolver is a Resolver knowing all its names, a context made of
+// it can be used as a whole, such as by get entries() or in printing.
+// Contexts of other resolvers fail there, as part of the entries would
+// be missing.
+type KeysResolver interface {
+	Resolver
+	Keys() []string
+}
+
+// ResolverFunc adapts a function to Resolver
+type ResolverFunc func(name string) (any, bool, error)
+
+func (fn ResolverFunc) Resolve(name string) (any, bool, error) {
+	return fn(name)
+}
+
+type resolvedEntry struct {
+	value any
+	found bool
+}
+
+// lazyContext wraps a Resolver and caches what it resolves
+type lazyContext struct {
+	resolver Resolver
+
+	mu    sync.Mutex
+	cache map[string]resolvedEntry
+}
+
+func newLazyContext(resolver Resolver) *lazyContext {
+	if lazy, ok := resolver.(*lazyContext); ok {
+		return lazy
+	}
+	return &lazyContext{
+		resolver: resolver,
+		cache:    make(map[string]resolvedEntry),
+	}
+}
+
+func (lazy *lazyContext) Resolve(name string) (any, bool, error) {
+	lazy.mu.Lock()
+	defer lazy.mu.Unlock()
+	if entry, ok := lazy.cache[name]; ok {
+		return entry.value, entry.found, nil
+	}
+	v, found, err := lazy.resolver.Resolve(name)
+	if err != nil {
+		// errors are not cached so that a later access may retry
+		return nil, false, err
+	}
+	if found {
+		// nested resolvers are wrapped as lazy contexts by normalizing
+		if v, err = ConvertValue(v); err != nil {
+			return nil, false, err
+		}
+	}
+	lazy.cache[name] = resolvedEntry{value: v, found: found}
+	return v, found, nil
+}
+
+// reset drops the cached values, so that they are resolved afresh
+func (lazy *lazyContext) reset() {
+	lazy.mu.Lock()
+	defer lazy.mu.Unlock()
+	if len(lazy.cache) > 0 {
+		lazy.cache = make(map[string]resolvedEntry)
+	}
+}
+
+// materialize resolves all the entries into a context, it fails when
+// the resolver doesn't know its names
+func (lazy *lazyContext) materialize() (map[string]any, error) {
+	keysResolver, ok := lazy.resolver.(KeysResolver)
+	if !ok {
+		return nil, NewErrValue("the keys of the lazy context are unknown")
+	}
+	ctx := make(map[string]any)
+	for _, key := range keysResolver.Keys() {
+		v, found, err := lazy.Resolve(key)
+		if err != nil {
+			return nil, err
+		} else if found {
+			ctx[key] = v
+		}
+	}
+	return ctx, nil
+}
+
+// MarshalJSON dumps all the entries, see materialize
+func (lazy *lazyContext) MarshalJSON() ([]byte, error) {
+	ctx, err := lazy.materialize()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ctx)
+}
+
+// AddResolver appends a resolver consulted, in the order of adding,
+// when a name is not found in the scope stack
+func (intp *Interpreter) AddResolver(resolver Resolver) {
+	intp.resolvers = append(intp.resolvers, newLazyContext(resolver))
+}
+
+// EvalStringWithResolver evaluates the input, fetching variables on
+// demand from the resolver
+func EvalStringWithResolver(input string, resolver Resolver) (any, error) {
+	ast, err := ParseString(input)
+	if err != nil {
+		return nil, err
+	}
+	intp := NewIntepreter()
+	if resolver != nil {
+		intp.AddResolver(resolver)
+	}
+	return intp.EvalNode(ast)
+}
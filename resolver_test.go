@@ -0,0 +1,186 @@
+package feel
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingResolver struct {
+	values map[string]any
+	loads  map[string]int
+}
+
+func (r *countingResolver) Resolve(name string) (any, bool, error) {
+	r.loads[name]++
+	if name == "broken" {
+		return nil, false, errors.New("backend unavailable")
+	}
+	v, ok := r.values[name]
+	return v, ok, nil
+}
+
+func TestLazyResolver(t *testing.T) {
+	assert := assert.New(t)
+
+	addressLoads := 0
+	address := ResolverFunc(func(name string) (any, bool, error) {
+		addressLoads++
+		if name == "zip" {
+			return "10001", true, nil
+		}
+		return nil, false, nil
+	})
+
+	customer := &countingResolver{
+		values: map[string]any{
+			"age":     42,
+			"address": address,
+		},
+		loads: make(map[string]int),
+	}
+
+	res, err := EvalStringWithResolver(
+		`customer.age > 18 and customer.age < 65 and customer.address.zip = "10001" and customer.address["zip"] = "10001"`,
+		ResolverFunc(func(name string) (any, bool, error) {
+			if name == "customer" {
+				return customer, true, nil
+			}
+			return nil, false, nil
+		}))
+	assert.Nil(err)
+	assert.Equal(true, res)
+	// every attribute is loaded once
+	assert.Equal(1, customer.loads["age"])
+	assert.Equal(1, customer.loads["address"])
+	assert.Equal(1, addressLoads)
+	// untouched attributes are never loaded
+	assert.Equal(0, customer.loads["income"])
+
+	// scope values take precedence over resolvers
+	intp := NewIntepreter()
+	intp.Push(Scope{"customer": map[string]any{"age": 10}})
+	intp.AddResolver(ResolverFunc(func(name string) (any, bool, error) {
+		return customer, true, nil
+	}))
+	ast, err := ParseString(`customer.age`)
+	assert.Nil(err)
+	v, err := ast.Eval(intp)
+	assert.Nil(err)
+	assert.True(N(10).Equal(*v.(*Number)))
+
+	// lazy contexts can also be put in scopes
+	res, err = EvalStringWithScope(`is defined(c.address.city)`, Scope{"c": customer})
+	assert.Nil(err)
+	assert.Equal(false, res)
+
+	// resolver errors abort the evaluation
+	_, err = EvalStringWithScope(`c.broken + 1`, Scope{"c": customer})
+	assert.EqualError(err, "backend unavailable")
+}
+
+func TestLazyResolverAcrossEvaluations(t *testing.T) {
+	assert := assert.New(t)
+
+	rate := &countingResolver{
+		values: map[string]any{"usd": 7},
+		loads:  make(map[string]int),
+	}
+	intp := NewIntepreter()
+	intp.Push(Scope{"rate": rate})
+	intp.AddResolver(rate)
+	ast, err := ParseString(`rate.usd + usd`)
+	assert.Nil(err)
+
+	v, err := intp.EvalNode(ast)
+	assert.Nil(err)
+	assert.True(N(14).Equal(*v.(*Number)))
+	assert.Equal(2, rate.loads["usd"])
+
+	// values are resolved afresh by every evaluation
+	rate.values["usd"] = 8
+	v, err = intp.EvalNode(ast)
+	assert.Nil(err)
+	assert.True(N(16).Equal(*v.(*Number)))
+	assert.Equal(4, rate.loads["usd"])
+
+	prog := Compile(ast)
+	rate.values["usd"] = 9
+	v, err = prog.Run(intp)
+	assert.Nil(err)
+	assert.True(N(18).Equal(*v.(*Number)))
+}
+
+// listingResolver knows all its names
+type listingResolver struct {
+	*countingResolver
+}
+
+func (r listingResolver) Keys() []string {
+	var keys []string
+	for k := range r.values {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestLazyContextFunctions(t *testing.T) {
+	assert := assert.New(t)
+
+	customer := &countingResolver{
+		values: map[string]any{
+			"age":  42,
+			"name": "Ann",
+			"address": map[string]any{
+				"city": "Paris",
+			},
+		},
+		loads: make(map[string]int),
+	}
+	eval := func(input string, c Resolver) (string, error) {
+		res, err := EvalStringWithScope(input, Scope{"c": c})
+		if err != nil {
+			return "", err
+		}
+		return FormatValue(res)
+	}
+
+	for _, c := range []Resolver{customer, listingResolver{customer}} {
+		s, err := eval(`get value(c, "age")`, c)
+		assert.Nil(err)
+		assert.Equal(`42`, s)
+		s, err = eval(`get value(c, ["address", "city"])`, c)
+		assert.Nil(err)
+		assert.Equal(`"Paris"`, s)
+		s, err = eval(`get value(c, "income")`, c)
+		assert.Nil(err)
+		assert.Equal(`null`, s)
+	}
+
+	// functions on whole contexts see all the entries
+	c := listingResolver{customer}
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{`if c.age > 0 then count(get entries(c)) else null`, `3`},
+		{`context put(c, "b", 1)`, `{address: {city: "Paris"}, age: 42, b: 1, name: "Ann"}`},
+		{`context merge([c, {age: 1}])`, `{address: {city: "Paris"}, age: 1, name: "Ann"}`},
+		{`c`, `{address: {city: "Paris"}, age: 42, name: "Ann"}`},
+	}
+	for _, tc := range cases {
+		s, err := eval(tc.input, c)
+		assert.Nil(err, tc.input)
+		assert.Equal(tc.expect, s, tc.input)
+	}
+
+	// but fail on resolvers not knowing their names
+	for _, input := range []string{`if c.age > 0 then get entries(c) else null`, `context put(c, "b", 1)`, `context merge([c, {x: 1}])`, `c`} {
+		_, err := eval(input, customer)
+		assert.EqualError(err, "-4003 value error, the keys of the lazy context are unknown", input)
+	}
+
+	_, err := EvalString(`get value(1, "a")`)
+	assert.NotNil(err)
+}
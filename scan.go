@@ -77,6 +77,7 @@ var tokenMatchers = []tokenMatcher{
 
 	match("+", ""),
 	match("-", ""),
+	match("**", ""),
 	match("*", ""),
 	match("/", ""),
 	match("%", ""),
@@ -87,8 +88,8 @@ var tokenMatchers = []tokenMatcher{
 }
 
 type ScanPosition struct {
-	Row    int
-	Column int
+	Row    int `json:"row"`
+	Column int `json:"column"`
 }
 
 type ScannerToken struct {
@@ -98,11 +99,12 @@ type ScannerToken struct {
 }
 
 var opTokens = map[string]bool{
-	"+": true,
-	"-": true,
-	"*": true,
-	"/": true,
-	"%": true,
+	"+":  true,
+	"-":  true,
+	"*":  true,
+	"**": true,
+	"/":  true,
+	"%":  true,
 }
 
 func (token ScannerToken) IsOp() bool {
@@ -7,8 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"regexp"
-	"strconv"
+	"strings"
 	"time"
 )
 
@@ -25,25 +24,33 @@ type HasDate interface {
 // time
 type FEELTime struct {
 	t time.Time
+	// parsed from 24:00:00, which is kept in printing
+	endOfDay bool
 }
 
 func (st FEELTime) Time() time.Time {
 	return st.t
 }
 
-var timePatterns = []string{
-	"15:04:05",
-	"15:04:05-07:00",
-	"15:04:05@MST",
-}
-
+// ParseTime parses hh:mm:ss with optional fractional seconds and zone,
+// 24:00:00 equals 00:00:00 but prints as it's written
 func ParseTime(temporalStr string) (*FEELTime, error) {
-	for _, pat := range timePatterns {
-		if t, err := time.Parse(pat, temporalStr); err == nil {
-			return &FEELTime{t: t}, nil
-		}
+	sc := &temporalScanner{input: temporalStr}
+	hour, min, sec, nsec, err := sc.clock()
+	if err != nil {
+		return nil, err
+	}
+	loc, err := sc.zone()
+	if err != nil {
+		return nil, err
 	}
-	return nil, ErrParseTemporal
+	if err := sc.end(); err != nil {
+		return nil, err
+	}
+	if hour == 24 {
+		return &FEELTime{t: time.Date(0, 1, 1, 0, 0, 0, 0, loc), endOfDay: true}, nil
+	}
+	return &FEELTime{t: time.Date(0, 1, 1, hour, min, sec, nsec, loc)}, nil
 }
 
 func (st FEELTime) GetAttr(name string) (interface{}, bool) {
@@ -69,7 +76,16 @@ func (st FEELTime) MarshalJSON() ([]byte, error) {
 }
 
 func (st FEELTime) String() string {
-	return st.t.Format("15:04:05-07:00")
+	s := formatInZone(st.t, "15:04:05.999999999", func(s string) (time.Time, bool) {
+		if t, err := ParseTime(s); err == nil {
+			return t.t, true
+		}
+		return time.Time{}, false
+	})
+	if st.endOfDay {
+		return "24" + s[len("00"):]
+	}
+	return s
 }
 
 // Date
@@ -101,22 +117,26 @@ func (date FEELDate) MarshalJSON() ([]byte, error) {
 	return json.Marshal(date.String())
 }
 
-var datePatterns = []string{
-	"2006-01-02",
-}
-
-func ParseDate(timeStr string) (*FEELDate, error) {
-	for _, pat := range datePatterns {
-		if t, err := time.Parse(pat, timeStr); err == nil {
-			return &FEELDate{t: t}, nil
-		}
+// ParseDate parses yyyy-mm-dd, years can be negative or of more than
+// 4 digits
+func ParseDate(temporalStr string) (*FEELDate, error) {
+	sc := &temporalScanner{input: temporalStr}
+	t, err := sc.date()
+	if err != nil {
+		return nil, err
 	}
-	return nil, ErrParseTemporal
+	if err := sc.end(); err != nil {
+		return nil, err
+	}
+	return &FEELDate{t: t}, nil
 }
 
 // Date and Time
 type FEELDatetime struct {
 	t time.Time
+	// parsed from 24:00:00, t is the start of the next day and it
+	// prints as the end of the day
+	endOfDay bool
 }
 
 func (sdt FEELDatetime) Time() time.Time {
@@ -170,7 +190,18 @@ func (sdt FEELDatetime) MarshalJSON() ([]byte, error) {
 }
 
 func (sdt FEELDatetime) String() string {
-	return sdt.t.Format("2006-01-02T15:04:05@MST")
+	const layout = "2006-01-02T15:04:05.999999999"
+	s := formatInZone(sdt.t, layout, func(s string) (time.Time, bool) {
+		if t, err := ParseDatetime(s); err == nil {
+			return t.t, true
+		}
+		return time.Time{}, false
+	})
+	if sdt.endOfDay {
+		zone := s[len(sdt.t.Format(layout)):]
+		return sdt.t.AddDate(0, 0, -1).Format("2006-01-02") + "T24:00:00" + zone
+	}
+	return s
 }
 
 func (sdt *FEELDatetime) Add(dur *FEELDuration) *FEELDatetime {
@@ -198,19 +229,72 @@ func (sdt *FEELDatetime) Sub(v HasTime) *FEELDuration {
 	return NewFEELDuration(delta)
 }
 
-var dateTimePatterns = []string{
-	"2006-01-02T15:04:05",
-	"2006-01-02T15:04:05-07:00",
-	"2006-01-02T15:04:05@MST",
+// Add moves the date by the duration, days-time durations move it as
+// from the midnight and the time of day is dropped
+func (date *FEELDate) Add(dur *FEELDuration) *FEELDate {
+	t := (&FEELDatetime{t: date.t}).Add(dur).t
+	return &FEELDate{t: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func (date *FEELDate) Sub(other *FEELDate) *FEELDuration {
+	return NewFEELDuration(date.t.Sub(other.t))
+}
+
+// Add moves the time around the clock by a days-time duration
+func (st *FEELTime) Add(dur *FEELDuration) *FEELTime {
+	t := st.t.Add(dur.Duration())
+	return &FEELTime{t: time.Date(0, 1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())}
+}
+
+func (st *FEELTime) Sub(other *FEELTime) *FEELDuration {
+	return NewFEELDuration(st.t.Sub(other.t))
 }
 
+// ParseDatetime parses the date and the time separated by T, 24:00:00
+// is the start of the next day but prints as it's written
 func ParseDatetime(temporalStr string) (*FEELDatetime, error) {
-	for _, pat := range dateTimePatterns {
-		if t, err := time.Parse(pat, temporalStr); err == nil {
-			return &FEELDatetime{t: t}, nil
+	sc := &temporalScanner{input: temporalStr}
+	date, err := sc.date()
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.expect('T', "T between the date and the time"); err != nil {
+		return nil, err
+	}
+	hour, min, sec, nsec, err := sc.clock()
+	if err != nil {
+		return nil, err
+	}
+	loc, err := sc.zone()
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.end(); err != nil {
+		return nil, err
+	}
+	return &FEELDatetime{
+		t:        time.Date(date.Year(), date.Month(), date.Day(), hour, min, sec, nsec, loc),
+		endOfDay: hour == 24,
+	}, nil
+}
+
+// formatInZone formats the time with the zone ID when parsing it back
+// gives the same instant, or else with the zone offset, times in UTC
+// have no zone
+func formatInZone(t time.Time, layout string, parse func(string) (time.Time, bool)) string {
+	s := t.Format(layout)
+	loc := t.Location()
+	if loc == time.UTC {
+		return s
+	} else if loc == zulu {
+		return s + "Z"
+	}
+	if name := loc.String(); name != "" && name != "Local" {
+		if pt, ok := parse(s + "@" + name); ok && pt.Equal(t) {
+			return s + "@" + name
 		}
 	}
-	return nil, ErrParseTemporal
+	return s + t.Format("-07:00")
 }
 
 func MustParseDatetime(temporalStr string) *FEELDatetime {
@@ -229,10 +313,16 @@ type FEELDuration struct {
 	Hours   int
 	Minutes int
 	Seconds int
+	// the fraction of seconds
+	Nanoseconds int
 }
 
 func NewFEELDuration(dur time.Duration) *FEELDuration {
 	d := &FEELDuration{}
+	if dur < 0 {
+		d.Neg = true
+		dur = -dur
+	}
 	ndur := int(dur)
 	nhours := ndur / int(time.Hour)
 	remain := ndur - nhours*int(time.Hour)
@@ -240,11 +330,13 @@ func NewFEELDuration(dur time.Duration) *FEELDuration {
 
 	remain -= nmins * int(time.Minute)
 	nsecs := remain / int(time.Second)
+	remain -= nsecs * int(time.Second)
 
 	d.Days = nhours / 24
 	d.Hours = nhours - d.Days*24
 	d.Minutes = nmins
 	d.Seconds = nsecs
+	d.Nanoseconds = remain
 	return d
 }
 
@@ -274,13 +366,41 @@ func (dur FEELDuration) Duration() time.Duration {
 	// dur.Year and dur.Month
 	dv := (time.Duration(dur.Days*24+dur.Hours)*time.Hour +
 		time.Duration(dur.Minutes)*time.Minute +
-		time.Duration(dur.Seconds)*time.Second)
+		time.Duration(dur.Seconds)*time.Second +
+		time.Duration(dur.Nanoseconds))
 	if dur.Neg {
 		dv = -dv
 	}
 	return dv
 }
 
+// TotalMonths is the years and months of the duration in months
+func (dur FEELDuration) TotalMonths() int {
+	months := dur.Years*12 + dur.Months
+	if dur.Neg {
+		return -months
+	}
+	return months
+}
+
+// Add sums durations of the same kind, years and months or days and
+// time, ok is false when the kinds differ
+func (dur *FEELDuration) Add(other *FEELDuration) (*FEELDuration, bool) {
+	if dur.Duration() == 0 && other.Duration() == 0 {
+		months := dur.TotalMonths() + other.TotalMonths()
+		sum := &FEELDuration{}
+		if months < 0 {
+			sum.Neg = true
+			months = -months
+		}
+		sum.Years, sum.Months = months/12, months%12
+		return sum, true
+	} else if dur.TotalMonths() == 0 && other.TotalMonths() == 0 {
+		return NewFEELDuration(dur.Duration() + other.Duration()), true
+	}
+	return nil, false
+}
+
 func (dur *FEELDuration) Negative() *FEELDuration {
 	neg := *dur
 	neg.Neg = !dur.Neg
@@ -309,126 +429,199 @@ func (dur FEELDuration) String() string {
 	if dur.Minutes != 0 {
 		sMinute = fmt.Sprintf("%dM", dur.Minutes)
 	}
-	if dur.Seconds != 0 {
+	if dur.Nanoseconds != 0 {
+		frac := strings.TrimRight(fmt.Sprintf("%09d", dur.Nanoseconds), "0")
+		sSecond = fmt.Sprintf("%d.%sS", dur.Seconds, frac)
+	} else if dur.Seconds != 0 {
 		sSecond = fmt.Sprintf("%dS", dur.Seconds)
 	}
-	if sYear != "" || sMonth != "" {
-		return fmt.Sprintf("%sP%s%s", sNeg, sYear, sMonth)
-	} else {
-		return fmt.Sprintf("%sP%sT%s%s%s", sNeg, sDay, sHour, sMinute, sSecond)
+	sTime := ""
+	if sHour != "" || sMinute != "" || sSecond != "" {
+		sTime = "T" + sHour + sMinute + sSecond
+	} else if sYear == "" && sMonth == "" && sDay == "" {
+		// the zero duration
+		sTime = "T0S"
 	}
+	return fmt.Sprintf("%sP%s%s%s%s", sNeg, sYear, sMonth, sDay, sTime)
 }
 
-var yearmonthDurationPattern = regexp.MustCompile(`^(\-?)P((\d+)Y)?((\d+)M)?$`)
-var timeDurationPattern = regexp.MustCompile(`^(\-?)P((\d+)D)?T((\d+)H)?((\d+)M)?((\d+)S)?$`)
-
+// ParseDuration parses the ISO 8601 duration of years and months, or of
+// days and time, seconds can have fractions
 func ParseDuration(temporalStr string) (*FEELDuration, error) {
-	// parse year month duration
-	if submatches := yearmonthDurationPattern.FindStringSubmatch(temporalStr); submatches != nil {
-		dur := &FEELDuration{}
-		if submatches[1] != "" {
-			dur.Neg = true
-		}
+	sc := &temporalScanner{input: temporalStr}
+	return sc.duration()
+}
 
-		if submatches[2] != "" {
-			y, err := strconv.ParseInt(submatches[3], 10, 64)
-			if err != nil {
-				return nil, err
-			}
-			dur.Years = int(y)
-		}
-		if submatches[4] != "" {
-			m, err := strconv.ParseInt(submatches[5], 10, 64)
-			if err != nil {
-				return nil, err
-			}
-			if m > 12 {
-				return nil, errors.New("months cannot exceed 12")
-			}
-			dur.Months = int(m)
-		}
-		return dur, nil
+// componentArg gets the integer component of temporal values within
+// [min, max], ok is false when it's null
+func componentArg(args map[string]any, name string, min, max int) (v int, ok bool, err error) {
+	n, err := numberArg(args, name)
+	if err != nil || n == nil {
+		return 0, false, err
+	}
+	if !n.IsInteger() {
+		return 0, false, NewErrValue(fmt.Sprintf("%s %s is not an integer", name, n))
 	}
+	if n.Cmp(N(min)) < 0 || n.Cmp(N(max)) > 0 {
+		return 0, false, NewErrValue(fmt.Sprintf("%s %s out of range [%d, %d]", name, n, min, max))
+	}
+	return n.Int(), true, nil
+}
 
-	// parse day time duration
-	if submatches := timeDurationPattern.FindStringSubmatch(temporalStr); submatches != nil {
-		dur := &FEELDuration{}
-		if submatches[1] != "" {
-			dur.Neg = true
-		}
-		if submatches[2] != "" {
-			v, err := strconv.ParseInt(submatches[3], 10, 64)
-			if err != nil {
-				return nil, err
-			}
-			dur.Days = int(v)
-		}
-		if submatches[4] != "" {
-			v, err := strconv.ParseInt(submatches[5], 10, 64)
-			if err != nil {
-				return nil, err
-			}
-			if v > 24 {
-				return nil, errors.New("hours cannot exceed 24")
-			}
-			dur.Hours = int(v)
-		}
-		if submatches[6] != "" {
-			v, err := strconv.ParseInt(submatches[7], 10, 64)
-			if err != nil {
-				return nil, err
-			}
-			if v > 60 {
-				return nil, errors.New("minutes cannot exceed 60")
-			}
-			dur.Minutes = int(v)
-		}
-		if submatches[8] != "" {
-			v, err := strconv.ParseInt(submatches[9], 10, 64)
-			if err != nil {
-				return nil, err
-			}
-			if v > 60 {
-				return nil, errors.New("seconds cannot exceed 60")
-			}
-			dur.Seconds = int(v)
-		}
-		return dur, nil
+func dateFrom(from any) (any, error) {
+	switch v := from.(type) {
+	case string:
+		return ParseDate(v)
+	case *FEELDate:
+		return v, nil
+	case *FEELDatetime:
+		return &FEELDate{t: time.Date(v.t.Year(), v.t.Month(), v.t.Day(), 0, 0, 0, 0, time.UTC)}, nil
+	case nil, *NullValue:
+		return Null, nil
 	}
+	return nil, NewErrTypeMismatch("string, date or date and time")
+}
 
-	return nil, ErrParseTemporal
+// dateOf makes the date of year, month and day, null if any is null
+func dateOf(args map[string]any) (any, error) {
+	year, ok1, err := componentArg(args, "year", -999999999, 999999999)
+	if err != nil {
+		return nil, err
+	}
+	month, ok2, err := componentArg(args, "month", 1, 12)
+	if err != nil {
+		return nil, err
+	}
+	day, ok3, err := componentArg(args, "day", 1, 31)
+	if err != nil {
+		return nil, err
+	}
+	if !ok1 || !ok2 || !ok3 {
+		return Null, nil
+	}
+	if reason := checkDay(year, time.Month(month), day); reason != "" {
+		return nil, NewErrValue(reason)
+	}
+	return &FEELDate{t: time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)}, nil
 }
 
-func ParseTemporalValue(temporalStr string) (interface{}, error) {
-	if v, err := ParseDatetime(temporalStr); err == nil {
+func timeFrom(from any) (any, error) {
+	switch v := from.(type) {
+	case string:
+		return ParseTime(v)
+	case *FEELTime:
 		return v, nil
+	case *FEELDatetime:
+		return &FEELTime{t: time.Date(0, 1, 1, v.t.Hour(), v.t.Minute(), v.t.Second(), v.t.Nanosecond(), v.t.Location())}, nil
+	case nil, *NullValue:
+		return Null, nil
 	}
+	return nil, NewErrTypeMismatch("string, time or date and time")
+}
 
-	if v, err := ParseTime(temporalStr); err == nil {
-		return v, nil
+// timeOf makes the time of hour, minute, second and the optional
+// offset, seconds can have fractions
+func timeOf(args map[string]any) (any, error) {
+	hour, ok1, err := componentArg(args, "hour", 0, 23)
+	if err != nil {
+		return nil, err
+	}
+	minute, ok2, err := componentArg(args, "minute", 0, 59)
+	if err != nil {
+		return nil, err
+	}
+	second, err := numberArg(args, "second")
+	if err != nil {
+		return nil, err
+	}
+	if !ok1 || !ok2 || second == nil {
+		return Null, nil
 	}
+	if second.Sign() < 0 || second.Cmp(N(60)) >= 0 {
+		return nil, NewErrValue(fmt.Sprintf("second %s out of range [0, 60)", second))
+	}
+	nanos := second.Mul(N(int64(time.Second))).Round(0, RoundHalfEven).Int64()
+
+	loc := time.UTC
+	switch offset := args["offset"].(type) {
+	case nil, *NullValue:
+	case *FEELDuration:
+		d := offset.Duration()
+		if offset.Years != 0 || offset.Months != 0 || d < -14*time.Hour || d > 14*time.Hour {
+			return nil, NewErrValue(fmt.Sprintf("offset %s out of range [-PT14H, PT14H]", offset))
+		}
+		loc = time.FixedZone("", int(d/time.Second))
+	default:
+		return nil, NewErrTypeMismatch("duration")
+	}
+	return &FEELTime{t: time.Date(0, 1, 1, hour, minute, 0, int(nanos), loc)}, nil
+}
 
-	if v, err := ParseDate(temporalStr); err == nil {
+func datetimeFrom(from any) (any, error) {
+	switch v := from.(type) {
+	case string:
+		return ParseDatetime(v)
+	case *FEELDatetime:
 		return v, nil
+	case nil, *NullValue:
+		return Null, nil
 	}
+	return nil, NewErrTypeMismatch("string or date and time")
+}
 
-	return ParseDuration(temporalStr)
+// datetimeOf combines the date of a date or a date and time with the
+// time
+func datetimeOf(args map[string]any) (any, error) {
+	var date time.Time
+	switch v := args["date"].(type) {
+	case *FEELDate:
+		date = v.t
+	case *FEELDatetime:
+		date = v.t
+	case nil, *NullValue:
+		return Null, nil
+	default:
+		return nil, NewErrTypeMismatch("date or date and time")
+	}
+	var clock time.Time
+	switch v := args["time"].(type) {
+	case *FEELTime:
+		clock = v.t
+	case nil, *NullValue:
+		return Null, nil
+	default:
+		return nil, NewErrTypeMismatch("time")
+	}
+	return &FEELDatetime{t: time.Date(
+		date.Year(), date.Month(), date.Day(),
+		clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(),
+		clock.Location())}, nil
 }
 
 // builtin functions
 func installDatetimeFunctions(prelude *Prelude) {
-	// conversions
-	prelude.Bind("date", wrapTyped(func(frm string) (interface{}, error) {
-		return ParseDate(frm)
-	}).Required("from"))
+	// conversions, refer to
+	// https://docs.camunda.io/docs/components/modeler/feel/builtin-functions/feel-built-in-functions-conversion/
+	prelude.Bind("date", NewNativeFunc(func(args map[string]any) (any, error) {
+		if from, ok := args["from"]; ok {
+			return dateFrom(from)
+		}
+		return dateOf(args)
+	}).Required("from").Overload([]string{"year", "month", "day"}))
 
-	prelude.Bind("time", wrapTyped(func(frm string) (interface{}, error) {
-		return ParseTime(frm)
-	}).Required("from"))
+	prelude.Bind("time", NewNativeFunc(func(args map[string]any) (any, error) {
+		if from, ok := args["from"]; ok {
+			return timeFrom(from)
+		}
+		return timeOf(args)
+	}).Required("from").Overload([]string{"hour", "minute", "second"}, "offset"))
 
-	prelude.Bind("date and time", wrapTyped(func(frm string) (interface{}, error) {
-		return ParseDatetime(frm)
-	}).Required("from"))
+	prelude.Bind("date and time", NewNativeFunc(func(args map[string]any) (any, error) {
+		if from, ok := args["from"]; ok {
+			return datetimeFrom(from)
+		}
+		return datetimeOf(args)
+	}).Required("from").Overload([]string{"date", "time"}))
 
 	prelude.Bind("duration", wrapTyped(func(frm string) (interface{}, error) {
 		return ParseDuration(frm)
@@ -437,11 +630,12 @@ func installDatetimeFunctions(prelude *Prelude) {
 	// temporal functions
 	prelude.Bind("now", wrapTyped(func() (interface{}, error) {
 		return &FEELDatetime{t: time.Now()}, nil
-	}))
+	}).Nondeterministic())
 
 	prelude.Bind("today", wrapTyped(func() (interface{}, error) {
-		return &FEELDate{t: time.Now()}, nil
-	}))
+		now := time.Now()
+		return &FEELDate{t: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)}, nil
+	}).Nondeterministic())
 
 	prelude.Bind("day of week", wrapTyped(func(v HasDate) (interface{}, error) {
 		return v.Date().Weekday(), nil
@@ -460,12 +654,6 @@ func installDatetimeFunctions(prelude *Prelude) {
 		return v.Date().Month(), nil
 	}).Required("date"))
 
-	prelude.Bind("abs", wrapTyped(func(dur *FEELDuration) (interface{}, error) {
-		newDur := *dur
-		newDur.Neg = false
-		return newDur, nil
-	}).Required("dur"))
-
 	// refs https://docs.camunda.io/docs/components/modeler/feel/builtin-functions/feel-built-in-functions-temporal/#last-day-of-monthdate
 	prelude.Bind("last day of month", wrapTyped(func(v HasDate) (interface{}, error) {
 		month := v.Date().Month()
@@ -0,0 +1,379 @@
+package feel
+
+// parsing the lexical forms of temporal values, which are those of
+// XML Schema and ISO 8601 with zone IDs, refer to
+// https://www.w3.org/TR/xmlschema11-2/#dateTime
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TemporalParseError tells where and why a temporal string cannot be
+// parsed, it wraps ErrParseTemporal
+type TemporalParseError struct {
+	Input  string
+	Offset int
+	Reason string
+}
+
+func (err *TemporalParseError) Error() string {
+	return fmt.Sprintf("%s %q at offset %d, %s", ErrParseTemporal, err.Input, err.Offset, err.Reason)
+}
+
+func (err *TemporalParseError) Unwrap() error {
+	return ErrParseTemporal
+}
+
+// zulu is the zone of times suffixed by Z, times without zones are in
+// time.UTC
+var zulu = time.FixedZone("Z", 0)
+
+// unknown zone abbreviations such as CST are taken as zones of zero
+// offset, as time.Parse does
+var zoneAbbrevPattern = regexp.MustCompile(`^[A-Z]{3,5}$`)
+
+func loadZone(id string) (*time.Location, bool) {
+	switch id {
+	case "", "Local":
+		return nil, false
+	case "UTC":
+		// time.UTC is taken by times without zones
+		return time.FixedZone(id, 0), true
+	}
+	if loc, err := time.LoadLocation(id); err == nil {
+		return loc, true
+	}
+	if zoneAbbrevPattern.MatchString(id) {
+		return time.FixedZone(id, 0), true
+	}
+	return nil, false
+}
+
+type temporalScanner struct {
+	input string
+	pos   int
+}
+
+func (sc *temporalScanner) errorf(format string, args ...any) error {
+	return &TemporalParseError{
+		Input:  sc.input,
+		Offset: sc.pos,
+		Reason: fmt.Sprintf(format, args...),
+	}
+}
+
+func (sc *temporalScanner) done() bool {
+	return sc.pos >= len(sc.input)
+}
+
+func (sc *temporalScanner) peek() byte {
+	if sc.done() {
+		return 0
+	}
+	return sc.input[sc.pos]
+}
+
+func (sc *temporalScanner) accept(c byte) bool {
+	if !sc.done() && sc.input[sc.pos] == c {
+		sc.pos++
+		return true
+	}
+	return false
+}
+
+func (sc *temporalScanner) expect(c byte, what string) error {
+	if !sc.accept(c) {
+		return sc.errorf("expect %s", what)
+	}
+	return nil
+}
+
+// end fails unless the whole input is scanned
+func (sc *temporalScanner) end() error {
+	if !sc.done() {
+		return sc.errorf("unexpected %q", sc.input[sc.pos:])
+	}
+	return nil
+}
+
+func (sc *temporalScanner) digits() string {
+	start := sc.pos
+	for !sc.done() && sc.input[sc.pos] >= '0' && sc.input[sc.pos] <= '9' {
+		sc.pos++
+	}
+	return sc.input[start:sc.pos]
+}
+
+// field scans exactly 2 digits of a value within [min, max]
+func (sc *temporalScanner) field(name string, min, max int) (int, error) {
+	start := sc.pos
+	ds := sc.digits()
+	if len(ds) != 2 {
+		sc.pos = start
+		return 0, sc.errorf("expect 2 digits of %s", name)
+	}
+	v, _ := strconv.Atoi(ds)
+	if v < min || v > max {
+		sc.pos = start
+		return 0, sc.errorf("%s %s out of range [%02d, %02d]", name, ds, min, max)
+	}
+	return v, nil
+}
+
+// fraction scans the digits after the decimal point as nanoseconds
+func (sc *temporalScanner) fraction() (int, error) {
+	start := sc.pos
+	frac := sc.digits()
+	if frac == "" {
+		return 0, sc.errorf("expect digits after the decimal point")
+	}
+	if len(frac) > 9 {
+		sc.pos = start + 9
+		return 0, sc.errorf("fractional seconds beyond nanoseconds")
+	}
+	nsec, _ := strconv.Atoi(frac + strings.Repeat("0", 9-len(frac)))
+	return nsec, nil
+}
+
+// date scans yyyy-mm-dd, years of more than 4 digits don't start with
+// 0 and negative years are before the year 0
+func (sc *temporalScanner) date() (time.Time, error) {
+	start := sc.pos
+	neg := sc.accept('-')
+	ys := sc.digits()
+	switch {
+	case len(ys) < 4:
+		sc.pos = start
+		return time.Time{}, sc.errorf("expect a year of at least 4 digits")
+	case len(ys) > 4 && ys[0] == '0':
+		sc.pos = start
+		return time.Time{}, sc.errorf("year %s of more than 4 digits starts with 0", ys)
+	case len(ys) > 9:
+		sc.pos = start
+		return time.Time{}, sc.errorf("year %s out of range", ys)
+	}
+	year, _ := strconv.Atoi(ys)
+	if neg {
+		year = -year
+	}
+	if err := sc.expect('-', "'-' after the year"); err != nil {
+		return time.Time{}, err
+	}
+	month, err := sc.field("month", 1, 12)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := sc.expect('-', "'-' after the month"); err != nil {
+		return time.Time{}, err
+	}
+	dayStart := sc.pos
+	day, err := sc.field("day", 1, 31)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if reason := checkDay(year, time.Month(month), day); reason != "" {
+		sc.pos = dayStart
+		return time.Time{}, sc.errorf("%s", reason)
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// checkDay tells why the day is not in the month, "" if it is
+func checkDay(year int, month time.Month, day int) string {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day < 1 || day > last {
+		return fmt.Sprintf("day %d out of range, %s %d has %d days", day, month, year, last)
+	}
+	return ""
+}
+
+// clock scans hh:mm:ss with optional fractional seconds, 24:00:00 is
+// the end of the day
+func (sc *temporalScanner) clock() (hour, min, sec, nsec int, err error) {
+	start := sc.pos
+	if hour, err = sc.field("hour", 0, 24); err != nil {
+		return
+	}
+	if err = sc.expect(':', "':' after the hour"); err != nil {
+		return
+	}
+	if min, err = sc.field("minute", 0, 59); err != nil {
+		return
+	}
+	if err = sc.expect(':', "':' after the minute"); err != nil {
+		return
+	}
+	if sec, err = sc.field("second", 0, 59); err != nil {
+		return
+	}
+	if sc.accept('.') {
+		if nsec, err = sc.fraction(); err != nil {
+			return
+		}
+	}
+	if hour == 24 && (min != 0 || sec != 0 || nsec != 0) {
+		sc.pos = start
+		err = sc.errorf("only 24:00:00 is allowed at hour 24")
+	}
+	return
+}
+
+// zone scans the optional zone, which is Z, an offset or @ followed by
+// a zone ID
+func (sc *temporalScanner) zone() (*time.Location, error) {
+	start := sc.pos
+	switch {
+	case sc.done():
+		return time.UTC, nil
+	case sc.accept('Z'):
+		return zulu, nil
+	case sc.accept('@'):
+		id := sc.input[sc.pos:]
+		loc, ok := loadZone(id)
+		if !ok {
+			return nil, sc.errorf("unknown zone ID %q", id)
+		}
+		sc.pos = len(sc.input)
+		return loc, nil
+	case sc.peek() == '+' || sc.peek() == '-':
+		sign := 1
+		if sc.input[sc.pos] == '-' {
+			sign = -1
+		}
+		sc.pos++
+		h, err := sc.field("offset hour", 0, 14)
+		if err != nil {
+			return nil, err
+		}
+		if err := sc.expect(':', "':' after the offset hour"); err != nil {
+			return nil, err
+		}
+		m, err := sc.field("offset minute", 0, 59)
+		if err != nil {
+			return nil, err
+		}
+		if h == 14 && m != 0 {
+			sc.pos = start
+			return nil, sc.errorf("offset out of range [-14:00, +14:00]")
+		}
+		return time.FixedZone("", sign*(h*3600+m*60)), nil
+	}
+	return nil, sc.errorf("expect Z, an offset or @ and a zone ID")
+}
+
+// duration scans the ISO 8601 duration, years and months can't be
+// mixed with days and time
+func (sc *temporalScanner) duration() (*FEELDuration, error) {
+	dur := &FEELDuration{}
+	dur.Neg = sc.accept('-')
+	if err := sc.expect('P', "P of duration"); err != nil {
+		return nil, err
+	}
+	units := []struct {
+		designator byte
+		inTime     bool
+		value      *int
+	}{
+		{'Y', false, &dur.Years},
+		{'M', false, &dur.Months},
+		{'D', false, &dur.Days},
+		{'H', true, &dur.Hours},
+		{'M', true, &dur.Minutes},
+		{'S', true, &dur.Seconds},
+	}
+	next, count := 0, 0
+	inTime, yearMonth := false, false
+	for !sc.done() {
+		if sc.peek() == 'T' {
+			if inTime {
+				return nil, sc.errorf("unexpected second T")
+			}
+			sc.pos++
+			inTime = true
+			next = 3
+			if sc.done() {
+				return nil, sc.errorf("expect hours, minutes or seconds after T")
+			}
+			continue
+		}
+		start := sc.pos
+		ds := sc.digits()
+		if ds == "" {
+			return nil, sc.errorf("unexpected %q", sc.input[sc.pos:])
+		}
+		v, err := strconv.Atoi(ds)
+		if err != nil {
+			sc.pos = start
+			return nil, sc.errorf("%s out of range", ds)
+		}
+		nsec := -1
+		if sc.accept('.') {
+			if nsec, err = sc.fraction(); err != nil {
+				return nil, err
+			}
+		}
+		i := next
+		for i < len(units) && (units[i].designator != sc.peek() || units[i].inTime != inTime) {
+			i++
+		}
+		if i == len(units) {
+			if sc.done() {
+				return nil, sc.errorf("expect a designator after %s", sc.input[start:])
+			}
+			if strings.IndexByte("YMDHS", sc.peek()) >= 0 {
+				return nil, sc.errorf("designator %q out of order", sc.peek())
+			}
+			return nil, sc.errorf("unexpected designator %q", sc.peek())
+		}
+		if nsec >= 0 && units[i].designator != 'S' {
+			sc.pos = start
+			return nil, sc.errorf("only seconds can have fractions")
+		}
+		if i < 2 {
+			yearMonth = true
+		} else if yearMonth {
+			sc.pos = start
+			return nil, sc.errorf("years and months can't be mixed with days and time")
+		}
+		sc.pos++
+		*units[i].value = v
+		if nsec > 0 {
+			dur.Nanoseconds = nsec
+		}
+		next = i + 1
+		count++
+	}
+	if count == 0 {
+		return nil, sc.errorf("expect at least one component of duration")
+	}
+	return dur, nil
+}
+
+var timeShape = regexp.MustCompile(`^\d\d:`)
+var dateShape = regexp.MustCompile(`^-?\d+-`)
+
+// ParseTemporalValue parses the string as a duration, a date and time,
+// a time or a date, telling which by its shape
+func ParseTemporalValue(temporalStr string) (interface{}, error) {
+	// zone IDs may contain T
+	head := temporalStr
+	if at := strings.IndexByte(head, '@'); at >= 0 {
+		head = head[:at]
+	}
+	switch {
+	case strings.HasPrefix(head, "P") || strings.HasPrefix(head, "-P"):
+		return ParseDuration(temporalStr)
+	case strings.Contains(head, "T"):
+		return ParseDatetime(temporalStr)
+	case timeShape.MatchString(head):
+		return ParseTime(temporalStr)
+	case dateShape.MatchString(head):
+		return ParseDate(temporalStr)
+	}
+	sc := &temporalScanner{input: temporalStr}
+	return nil, sc.errorf("expect a date, a time, a date and time or a duration")
+}
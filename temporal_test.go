@@ -0,0 +1,194 @@
+package feel
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTemporal(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{`2023-06-07`, `2023-06-07`},
+		{`-0044-03-15`, `-0044-03-15`},
+		{`12345-01-01`, `12345-01-01`},
+		{`2024-02-29`, `2024-02-29`},
+		{`12:00:00`, `12:00:00`},
+		{`12:00:00.123`, `12:00:00.123`},
+		{`12:00:00.500000000Z`, `12:00:00.5Z`},
+		{`08:30:00+05:30`, `08:30:00+05:30`},
+		{`24:00:00`, `24:00:00`},
+		{`24:00:00+02:00`, `24:00:00+02:00`},
+		{`10:00:00@UTC`, `10:00:00@UTC`},
+		{`2024-03-01T10:00:00@Europe/Paris`, `2024-03-01T10:00:00@Europe/Paris`},
+		{`2024-03-01T10:00:00.25-14:00`, `2024-03-01T10:00:00.25-14:00`},
+		{`2023-12-31T24:00:00Z`, `2023-12-31T24:00:00Z`},
+		{`2023-12-31T24:00:00@Europe/Paris`, `2023-12-31T24:00:00@Europe/Paris`},
+		{`P1Y2M`, `P1Y2M`},
+		{`P14M`, `P14M`},
+		{`-P3D`, `-P3D`},
+		{`PT36H`, `PT36H`},
+		{`P1DT3H25M60S`, `P1DT3H25M60S`},
+		{`PT1.5S`, `PT1.5S`},
+		{`PT0S`, `PT0S`},
+	}
+	for _, c := range cases {
+		v, err := ParseTemporalValue(c.input)
+		if !assert.Nil(err, c.input) {
+			continue
+		}
+		s := v.(interface{ String() string }).String()
+		assert.Equal(c.expect, s, c.input)
+
+		again, err := ParseTemporalValue(s)
+		assert.Nil(err, s)
+		assert.Equal(s, again.(interface{ String() string }).String())
+	}
+
+	errorCases := []struct {
+		input  string
+		offset int
+		reason string
+	}{
+		{`bad`, 0, `expect a date, a time, a date and time or a duration`},
+		{`2023-02-29`, 8, `day 29 out of range, February 2023 has 28 days`},
+		{`2023-13-01`, 5, `month 13 out of range [01, 12]`},
+		{`01234-01-01`, 0, `year 01234 of more than 4 digits starts with 0`},
+		{`2023-06-07 10:00:00`, 10, `unexpected " 10:00:00"`},
+		{`2023-06-07T25:00:00`, 11, `hour 25 out of range [00, 24]`},
+		{`24:00:01`, 0, `only 24:00:00 is allowed at hour 24`},
+		{`12:60:00`, 3, `minute 60 out of range [00, 59]`},
+		{`12:00`, 5, `expect ':' after the minute`},
+		{`12:00:00.`, 9, `expect digits after the decimal point`},
+		{`12:00:00.1234567891`, 18, `fractional seconds beyond nanoseconds`},
+		{`12:00:00+14:30`, 8, `offset out of range [-14:00, +14:00]`},
+		{`12:00:00@Mars/Base`, 9, `unknown zone ID "Mars/Base"`},
+		{`12:00:00X`, 8, `expect Z, an offset or @ and a zone ID`},
+		{`P`, 1, `expect at least one component of duration`},
+		{`PT`, 2, `expect hours, minutes or seconds after T`},
+		{`P1Y2D`, 3, `years and months can't be mixed with days and time`},
+		{`P1D2Y`, 4, `designator 'Y' out of order`},
+		{`P1.5D`, 1, `only seconds can have fractions`},
+		{`P5`, 2, `expect a designator after 5`},
+	}
+	for _, c := range errorCases {
+		_, err := ParseTemporalValue(c.input)
+		assert.ErrorIs(err, ErrParseTemporal, c.input)
+		var perr *TemporalParseError
+		if assert.True(errors.As(err, &perr), c.input) {
+			assert.Equal(c.offset, perr.Offset, c.input)
+			assert.Equal(c.reason, perr.Reason, c.input)
+		}
+	}
+}
+
+func TestTemporalConstructors(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{`date(2024, 2, 29)`, `@"2024-02-29"`},
+		{`date(year: -44, month: 3, day: 15)`, `@"-0044-03-15"`},
+		{`date(from: @"2024-03-01T10:00:00@Europe/Paris")`, `@"2024-03-01"`},
+		{`date("2024-01-01")`, `@"2024-01-01"`},
+		{`date(null, 2, 3)`, `null`},
+		{`time(10, 30, 15.25)`, `@"10:30:15.25"`},
+		{`time(hour: 8, minute: 30, second: 0, offset: @"PT5H30M")`, `@"08:30:00+05:30"`},
+		{`time(10, 30, 0, @"-PT2H")`, `@"10:30:00-02:00"`},
+		{`time(from: @"2024-03-01T10:00:00+01:00")`, `@"10:00:00+01:00"`},
+		{`date and time(@"2024-03-01", @"10:00:00@Europe/Paris")`, `@"2024-03-01T10:00:00@Europe/Paris"`},
+		{`date and time(date: date(2024, 3, 1), time: time(10, 0, 0))`, `@"2024-03-01T10:00:00"`},
+		{`date and time(@"2024-03-01T23:00:00", @"01:02:03Z")`, `@"2024-03-01T01:02:03Z"`},
+		{`date and time(from: "2024-03-01T10:00:00Z")`, `@"2024-03-01T10:00:00Z"`},
+		{`date and time(null, @"10:00:00")`, `null`},
+		// 24:00:00 is the start of the next day
+		{`@"24:00:00" = @"00:00:00"`, `true`},
+		{`@"2023-12-31T24:00:00Z" = @"2024-01-01T00:00:00Z"`, `true`},
+		{`@"2023-12-31T24:00:00Z" + @"PT1H"`, `@"2024-01-01T01:00:00Z"`},
+		{`date(@"2023-12-31T24:00:00")`, `@"2024-01-01"`},
+	}
+	for _, c := range cases {
+		ast, err := ParseString(c.input)
+		if !assert.Nil(err, c.input) {
+			continue
+		}
+		res, err := NewIntepreter().EvalNode(ast)
+		assert.Nil(err, c.input)
+		s, _ := FormatValue(res)
+		assert.Equal(c.expect, s, c.input)
+
+		res, err = Compile(ast).Run(NewIntepreter())
+		assert.Nil(err, c.input)
+		s, _ = FormatValue(res)
+		assert.Equal(c.expect, s, "compiled %s", c.input)
+	}
+
+	errorCases := []struct {
+		input  string
+		expect string
+	}{
+		{`date(2023, 2, 30)`, `-4003 value error, day 30 out of range, February 2023 has 28 days`},
+		{`date(2024, 13, 1)`, `-4003 value error, month 13 out of range [1, 12]`},
+		{`date(2024.5, 1, 1)`, `-4003 value error, year 2024.5 is not an integer`},
+		{`date(2024, 2)`, `-4011 too few argument, require arguments: day`},
+		{`date(year: 2024, month: 2)`, `-4010 keyword argument required, require keyword arg day`},
+		{`time(24, 0, 0)`, `-4003 value error, hour 24 out of range [0, 23]`},
+		{`time(10, 0, 60)`, `-4003 value error, second 60 out of range [0, 60)`},
+		{`time(10, 0, 0, @"P1M")`, `-4003 value error, offset P1M out of range [-PT14H, PT14H]`},
+		{`date and time(@"10:00:00", @"10:00:00")`, `-4002 type mismatch, expect date or date and time`},
+	}
+	for _, c := range errorCases {
+		_, err := EvalString(c.input)
+		if assert.NotNil(err, c.input) {
+			assert.Equal(c.expect, err.Error(), c.input)
+		}
+	}
+
+	result, err := CheckTypesString(`date(2024, 2, 29)`, nil)
+	assert.Nil(err)
+	assert.Empty(result.Diagnostics)
+	assert.Equal(DateType.String(), result.Type.String())
+}
+
+func TestTemporalArithmetic(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{`@"2020-01-31" + @"P1D"`, `@"2020-02-01"`},
+		{`@"P1M" + @"2020-01-15"`, `@"2020-02-15"`},
+		{`@"2020-01-15" - @"P1Y2M"`, `@"2018-11-15"`},
+		// days-time durations move dates as from the midnight
+		{`@"2020-01-01" - @"PT1H"`, `@"2019-12-31"`},
+		{`@"2020-03-01" - @"2020-02-01"`, `@"P29D"`},
+		{`@"23:00:00" + @"PT2H"`, `@"01:00:00"`},
+		{`@"12:00:00" - @"10:30:00"`, `@"PT1H30M"`},
+		{`@"PT1H" + @"2020-01-01T10:00:00"`, `@"2020-01-01T11:00:00"`},
+		{`@"P1D" + @"PT12H"`, `@"P1DT12H"`},
+		{`@"P1Y" - @"P13M"`, `@"-P1M"`},
+	}
+	for _, c := range cases {
+		res, err := EvalString(c.input)
+		if !assert.Nil(err, c.input) {
+			continue
+		}
+		s, _ := FormatValue(res)
+		assert.Equal(c.expect, s, c.input)
+	}
+
+	// years-months durations don't add to days-time ones or to times, durations
+	// minus dates are not defined
+	for _, input := range []string{`@"P1Y" + @"P1D"`, `@"10:00:00" + @"P1M"`, `@"P1D" - @"2020-01-01"`} {
+		_, err := EvalString(input)
+		assert.NotNil(err, input)
+	}
+}
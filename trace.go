@@ -0,0 +1,220 @@
+package feel
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// TraceNode records the evaluation of a node
+type TraceNode struct {
+	Kind       string           `json:"kind"`
+	Expr       string           `json:"expr"`
+	Range      TextRange        `json:"range"`
+	Value      any              `json:"value"`
+	Error      string           `json:"error,omitempty"`
+	Calls      []*TraceCall     `json:"calls,omitempty"`
+	Branch     string           `json:"branch,omitempty"`
+	Iterations []TraceIteration `json:"iterations,omitempty"`
+	Children   []*TraceNode     `json:"children,omitempty"`
+}
+
+// TraceCall records a function invocation
+type TraceCall struct {
+	Function string         `json:"function"`
+	Args     map[string]any `json:"args"`
+	Result   any            `json:"result"`
+	Error    string         `json:"error,omitempty"`
+
+	finished bool
+}
+
+// TraceIteration records an element visited by for, some and every
+// expressions, together with the result of the return or filter
+// expression on it
+type TraceIteration struct {
+	Item   any `json:"item"`
+	Result any `json:"result"`
+}
+
+// Tracer is an EvalHook recording an evaluation as a tree of trace nodes
+type Tracer struct {
+	Roots []*TraceNode
+
+	stack []*TraceNode
+}
+
+func NewTracer() *Tracer {
+	return &Tracer{}
+}
+
+// EnableTrace creates a tracer recording the evaluations of intp
+func (intp *Interpreter) EnableTrace() *Tracer {
+	tracer := NewTracer()
+	intp.tracer = tracer
+	intp.AddHook(tracer)
+	return tracer
+}
+
+func nodeKind(node Node) string {
+	tp := reflect.TypeOf(node)
+	if tp.Kind() == reflect.Ptr {
+		tp = tp.Elem()
+	}
+	return tp.Name()
+}
+
+func (tracer *Tracer) current() *TraceNode {
+	if len(tracer.stack) == 0 {
+		return nil
+	}
+	return tracer.stack[len(tracer.stack)-1]
+}
+
+func (tracer *Tracer) BeforeEval(intp *Interpreter, node Node) error {
+	tn := &TraceNode{
+		Kind:  nodeKind(node),
+		Expr:  node.Repr(),
+		Range: node.TextRange(),
+	}
+	if parent := tracer.current(); parent != nil {
+		parent.Children = append(parent.Children, tn)
+	} else {
+		tracer.Roots = append(tracer.Roots, tn)
+	}
+	tracer.stack = append(tracer.stack, tn)
+	return nil
+}
+
+func (tracer *Tracer) AfterEval(intp *Interpreter, node Node, value any, err error) {
+	tn := tracer.current()
+	if tn == nil {
+		return
+	}
+	tracer.stack = tracer.stack[:len(tracer.stack)-1]
+	if err != nil {
+		tn.Error = err.Error()
+	} else {
+		tn.Value = value
+	}
+}
+
+func (tracer *Tracer) BeforeCall(intp *Interpreter, funcName string, fn any, args map[string]any) {
+	tn := tracer.current()
+	if tn == nil {
+		return
+	}
+	callArgs := make(map[string]any)
+	for name, arg := range args {
+		callArgs[name] = traceableValue(arg)
+	}
+	tn.Calls = append(tn.Calls, &TraceCall{Function: funcName, Args: callArgs})
+}
+
+func (tracer *Tracer) AfterCall(intp *Interpreter, funcName string, fn any, result any, err error) {
+	tn := tracer.current()
+	if tn == nil {
+		return
+	}
+	// the innermost unfinished call, as a macro may call functions
+	for i := len(tn.Calls) - 1; i >= 0; i-- {
+		call := tn.Calls[i]
+		if !call.finished {
+			call.finished = true
+			if err != nil {
+				call.Error = err.Error()
+			} else {
+				call.Result = result
+			}
+			return
+		}
+	}
+}
+
+// macro arguments are nodes, which are recorded as expressions
+func traceableValue(v any) any {
+	switch vv := v.(type) {
+	case Node:
+		return vv.Repr()
+	case []Node:
+		exprs := make([]any, len(vv))
+		for i, n := range vv {
+			exprs[i] = n.Repr()
+		}
+		return exprs
+	default:
+		return v
+	}
+}
+
+func (intp *Interpreter) traceBranch(branch string) {
+	if intp.tracer != nil {
+		if tn := intp.tracer.current(); tn != nil {
+			tn.Branch = branch
+		}
+	}
+}
+
+func (intp *Interpreter) traceIteration(item any, result any) {
+	if intp.tracer != nil {
+		if tn := intp.tracer.current(); tn != nil {
+			tn.Iterations = append(tn.Iterations, TraceIteration{Item: item, Result: result})
+		}
+	}
+}
+
+// JSON exports the trace tree
+func (tracer *Tracer) JSON() ([]byte, error) {
+	return json.MarshalIndent(tracer.Roots, "", "  ")
+}
+
+// Explain renders the trace tree as indented human readable text
+func (tracer *Tracer) Explain() string {
+	var sb strings.Builder
+	for _, root := range tracer.Roots {
+		root.explain(&sb, 0)
+	}
+	return sb.String()
+}
+
+func traceValueString(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func (tn *TraceNode) explain(sb *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	pos := tn.Range.Start
+	if tn.Error != "" {
+		fmt.Fprintf(sb, "%s%s => error: %s  (at %d:%d)\n", indent, tn.Expr, tn.Error, pos.Row+1, pos.Column+1)
+	} else {
+		fmt.Fprintf(sb, "%s%s => %s  (at %d:%d)\n", indent, tn.Expr, traceValueString(tn.Value), pos.Row+1, pos.Column+1)
+	}
+	for _, call := range tn.Calls {
+		var args []string
+		for name, arg := range call.Args {
+			args = append(args, fmt.Sprintf("%s: %s", name, traceValueString(arg)))
+		}
+		// map iteration order is random
+		sort.Strings(args)
+		result := traceValueString(call.Result)
+		if call.Error != "" {
+			result = "error: " + call.Error
+		}
+		fmt.Fprintf(sb, "%s  call %s(%s) => %s\n", indent, call.Function, strings.Join(args, ", "), result)
+	}
+	if tn.Branch != "" {
+		fmt.Fprintf(sb, "%s  take %s branch\n", indent, tn.Branch)
+	}
+	for _, iter := range tn.Iterations {
+		fmt.Fprintf(sb, "%s  item %s => %s\n", indent, traceValueString(iter.Item), traceValueString(iter.Result))
+	}
+	for _, child := range tn.Children {
+		child.explain(sb, depth+1)
+	}
+}
@@ -0,0 +1,67 @@
+package feel
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceEvaluation(t *testing.T) {
+	assert := assert.New(t)
+
+	intp := NewIntepreter()
+	assert.Nil(intp.PushVars(`{a: 5}`))
+	tracer := intp.EnableTrace()
+
+	ast, err := ParseString(`if a > 3 then sum([a, 2]) else "smaller"`)
+	assert.Nil(err)
+	res, err := intp.EvalNode(ast)
+	assert.Nil(err)
+	assert.True(cmp.Equal(N(7), res))
+
+	assert.Equal(1, len(tracer.Roots))
+	root := tracer.Roots[0]
+	assert.Equal("IfExpr", root.Kind)
+	assert.Equal("then", root.Branch)
+	assert.True(cmp.Equal(N(7), root.Value))
+
+	cond := root.Children[0]
+	assert.Equal("(> a 3)", cond.Expr)
+	assert.Equal(true, cond.Value)
+	assert.Equal(ScanPosition{Row: 0, Column: 3}, cond.Range.Start)
+
+	call := root.Children[1]
+	assert.Equal(1, len(call.Calls))
+	assert.Equal("sum", call.Calls[0].Function)
+	assert.True(cmp.Equal(N(7), call.Calls[0].Result))
+
+	explained := tracer.Explain()
+	assert.Contains(explained, "take then branch")
+	assert.Contains(explained, "call sum(list: [[5,2]]) => 7")
+
+	data, err := tracer.JSON()
+	assert.Nil(err)
+	var decoded []map[string]any
+	assert.Nil(json.Unmarshal(data, &decoded))
+	assert.Equal("IfExpr", decoded[0]["kind"])
+}
+
+func TestTraceQuantifier(t *testing.T) {
+	assert := assert.New(t)
+
+	intp := NewIntepreter()
+	tracer := intp.EnableTrace()
+	ast, err := ParseString(`some x in [1, 5, 8] satisfies x > 2`)
+	assert.Nil(err)
+	_, err = intp.EvalNode(ast)
+	assert.Nil(err)
+
+	iterations := tracer.Roots[0].Iterations
+	assert.Equal(2, len(iterations))
+	assert.True(cmp.Equal(N(1), iterations[0].Item))
+	assert.Equal(false, iterations[0].Result)
+	assert.True(cmp.Equal(N(5), iterations[1].Item))
+	assert.Equal(true, iterations[1].Result)
+}
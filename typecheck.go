@@ -0,0 +1,938 @@
+package feel
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TypeSeverity tells type errors from warnings
+type TypeSeverity string
+
+const (
+	// the expression fails whenever the node is evaluated
+	SeverityError TypeSeverity = "error"
+	// the expression may fail, such as on null values
+	SeverityWarning TypeSeverity = "warning"
+)
+
+// TypeDiagnostic is a problem found by the type checker
+type TypeDiagnostic struct {
+	Severity TypeSeverity `json:"severity"`
+	Message  string       `json:"message"`
+	Range    TextRange    `json:"range"`
+}
+
+func (diag TypeDiagnostic) String() string {
+	pos := diag.Range.Start
+	return fmt.Sprintf("%d:%d: %s: %s", pos.Row+1, pos.Column+1, diag.Severity, diag.Message)
+}
+
+// TypeCheckResult holds the inferred types and the diagnostics
+type TypeCheckResult struct {
+	// the type of the expression
+	Type        *Type
+	Diagnostics []TypeDiagnostic
+
+	types map[Node]*Type
+}
+
+// TypeOf returns the inferred type of a node of the checked AST
+func (result *TypeCheckResult) TypeOf(node Node) *Type {
+	if tp, ok := result.types[node]; ok {
+		return tp
+	}
+	return AnyType
+}
+
+func (result *TypeCheckResult) filter(severity TypeSeverity) []TypeDiagnostic {
+	var diags []TypeDiagnostic
+	for _, diag := range result.Diagnostics {
+		if diag.Severity == severity {
+			diags = append(diags, diag)
+		}
+	}
+	return diags
+}
+
+func (result *TypeCheckResult) Errors() []TypeDiagnostic {
+	return result.filter(SeverityError)
+}
+
+func (result *TypeCheckResult) Warnings() []TypeDiagnostic {
+	return result.filter(SeverityWarning)
+}
+
+func (result *TypeCheckResult) HasErrors() bool {
+	return len(result.Errors()) > 0
+}
+
+// CheckTypes infers the type of every node given the types of the
+// inputs, the type of the unary test input `?` can be declared by
+// the name "?". Undeclared inputs are Any with a warning
+func CheckTypes(node Node, inputs map[string]*Type) *TypeCheckResult {
+	checker := &typeChecker{
+		inputs:   inputs,
+		result:   &TypeCheckResult{types: make(map[Node]*Type)},
+		reported: make(map[string]bool),
+		record:   true,
+	}
+	checker.result.Type = checker.check(node)
+	return checker.result
+}
+
+// CheckTypesString parses the input and checks its types
+func CheckTypesString(input string, inputs map[string]*Type) (*TypeCheckResult, error) {
+	ast, err := ParseString(input)
+	if err != nil {
+		return nil, err
+	}
+	return CheckTypes(ast, inputs), nil
+}
+
+type typeChecker struct {
+	inputs   map[string]*Type
+	scopes   []map[string]*Type
+	result   *TypeCheckResult
+	reported map[string]bool
+	// types are recorded out of lambda instantiations
+	record bool
+	// lambdas being instantiated, to stop on recursion
+	instantiating []*FunDef
+	// inside `is defined()`
+	probing bool
+}
+
+func (checker *typeChecker) report(severity TypeSeverity, rng TextRange, format string, args ...any) {
+	diag := TypeDiagnostic{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Range:    rng,
+	}
+	key := diag.String()
+	if checker.reported[key] {
+		return
+	}
+	checker.reported[key] = true
+	checker.result.Diagnostics = append(checker.result.Diagnostics, diag)
+}
+
+func (checker *typeChecker) errorf(node Node, format string, args ...any) {
+	checker.report(SeverityError, node.TextRange(), format, args...)
+}
+
+func (checker *typeChecker) warnf(node Node, format string, args ...any) {
+	checker.report(SeverityWarning, node.TextRange(), format, args...)
+}
+
+// requireNonNull reports null operands of operations failing on null,
+// it returns false if the type is always null
+func (checker *typeChecker) requireNonNull(node Node, tp *Type, what string) bool {
+	if tp.Kind == NullKind {
+		checker.errorf(node, "%s is always null", what)
+		return false
+	} else if tp.Nullable {
+		checker.warnf(node, "%s may be null", what)
+	}
+	return true
+}
+
+func (checker *typeChecker) push(scope map[string]*Type) {
+	checker.scopes = append(checker.scopes, scope)
+}
+
+func (checker *typeChecker) pop() {
+	checker.scopes = checker.scopes[:len(checker.scopes)-1]
+}
+
+func (checker *typeChecker) checkScoped(node Node, name string, tp *Type) *Type {
+	checker.push(map[string]*Type{name: tp})
+	defer checker.pop()
+	return checker.check(node)
+}
+
+func (checker *typeChecker) lookup(name string) (*Type, bool) {
+	for i := len(checker.scopes) - 1; i >= 0; i-- {
+		if tp, ok := checker.scopes[i][name]; ok {
+			return tp, true
+		}
+	}
+	if tp, ok := checker.inputs[name]; ok {
+		return tp, true
+	}
+	if fn, ok := GetPrelude().Resolve(name); ok {
+		return builtinType(name, fn), true
+	}
+	return nil, false
+}
+
+func (checker *typeChecker) check(node Node) *Type {
+	tp := checker.infer(node)
+	if tp == nil {
+		tp = AnyType
+	}
+	if checker.record {
+		checker.result.types[node] = tp
+	}
+	return tp
+}
+
+// valueType returns the type of a value
+func valueType(v any) *Type {
+	switch vv := v.(type) {
+	case *Number:
+		return NumberType
+	case string:
+		return StringType
+	case bool:
+		return BooleanType
+	case *NullValue:
+		return NullType
+	case []any:
+		var elem *Type
+		for _, e := range vv {
+			elem = unionType(elem, valueType(e))
+		}
+		if elem == nil {
+			elem = AnyType
+		}
+		return ListOf(elem)
+	case map[string]any:
+		fields := make(map[string]*Type)
+		for k, e := range vv {
+			fields[k] = valueType(e)
+		}
+		return ContextOf(fields)
+	default:
+		return temporalType(v)
+	}
+}
+
+func temporalType(v any) *Type {
+	switch v.(type) {
+	case *FEELDate:
+		return DateType
+	case *FEELTime:
+		return TimeType
+	case *FEELDatetime:
+		return DateTimeType
+	case *FEELDuration:
+		return DurationType
+	default:
+		return AnyType
+	}
+}
+
+func (checker *typeChecker) infer(node Node) *Type {
+	switch v := node.(type) {
+	case *NumberNode:
+		return NumberType
+	case *StringNode:
+		return StringType
+	case *BoolNode:
+		return BooleanType
+	case *NullNode:
+		return NullType
+	case *EmptyNode:
+		return NullType
+	case *ConstNode:
+		return valueType(v.Value)
+	case *TemporalNode:
+		value, err := ParseTemporalValue(v.Content())
+		if err != nil {
+			reason := err.Error()
+			var perr *TemporalParseError
+			if errors.As(err, &perr) {
+				reason = perr.Reason
+			}
+			checker.errorf(node, "invalid temporal literal %s, %s", v.Value, reason)
+			return AnyType
+		}
+		return temporalType(value)
+	case *Var:
+		return checker.inferVar(v)
+	case *Binop:
+		return checker.inferBinop(v)
+	case *DotOp:
+		return checker.inferDotOp(v)
+	case *FunCall:
+		return checker.inferFunCall(v)
+	case *FunDef:
+		return checker.inferFunDef(v)
+	case *IfExpr:
+		checker.check(v.Cond)
+		return unionType(checker.check(v.ThenBranch), checker.check(v.ElseBranch))
+	case *ArrayNode:
+		var elem *Type
+		for _, e := range v.Elements {
+			elem = unionType(elem, checker.check(e))
+		}
+		if elem == nil {
+			elem = AnyType
+		}
+		return ListOf(elem)
+	case *MapNode:
+		fields := make(map[string]*Type)
+		checker.push(make(map[string]*Type))
+		defer checker.pop()
+		for _, item := range v.Values {
+			if _, ok := item.Value.(*FunDef); ok {
+				// recursive functions refer to themselves
+				checker.scopes[len(checker.scopes)-1][item.Name] = &Type{Kind: FunctionKind}
+			}
+			ft := checker.check(item.Value)
+			fields[item.Name] = ft
+			checker.scopes[len(checker.scopes)-1][item.Name] = ft
+		}
+		return ContextOf(fields)
+	case *RangeNode:
+		start := checker.check(v.Start)
+		end := checker.check(v.End)
+		if !comparableTypes(start.NonNull(), end.NonNull(), false) {
+			checker.errorf(node, "range bounds %s and %s are not comparable", start, end)
+		}
+		return RangeOf(unionType(start, end))
+	case *MultiTests:
+		for _, e := range v.Elements {
+			checker.check(e)
+		}
+		return BooleanType
+	case *ForExpr:
+		elem := checker.iterationElem(v.ListExpr, "for")
+		return ListOf(checker.checkScoped(v.ReturnExpr, v.Varname, elem))
+	case *SomeExpr:
+		elem := checker.iterationElem(v.ListExpr, "some")
+		checker.checkScoped(v.FilterExpr, v.Varname, elem)
+		// the first element satisfying the filter, or null
+		return elem.OrNull()
+	case *EveryExpr:
+		elem := checker.iterationElem(v.ListExpr, "every")
+		checker.checkScoped(v.FilterExpr, v.Varname, elem)
+		return ListOf(elem)
+	default:
+		return AnyType
+	}
+}
+
+func (checker *typeChecker) inferVar(v *Var) *Type {
+	if tp, ok := checker.lookup(v.Name); ok {
+		return tp
+	}
+	if v.Name != "?" && !checker.probing {
+		checker.warnf(v, "undeclared variable %s", v.Repr())
+	}
+	return AnyType
+}
+
+func (checker *typeChecker) iterationElem(listExpr Node, what string) *Type {
+	tp := checker.check(listExpr)
+	if !checker.requireNonNull(listExpr, tp, "the list of "+what) {
+		return AnyType
+	}
+	switch tp.Kind {
+	case ListKind:
+		if tp.Elem != nil {
+			return tp.Elem
+		}
+	case AnyKind:
+	default:
+		checker.errorf(listExpr, "%s expects a list, got %s", what, tp)
+	}
+	return AnyType
+}
+
+// comparableTypes mirrors compareInterfaces, which compares values of
+// the same kind, dates and durations included, and times with date and
+// times
+func comparableTypes(a, b *Type, equality bool) bool {
+	if a.Kind == AnyKind || b.Kind == AnyKind {
+		return true
+	}
+	if equality && (a.Kind == NullKind || b.Kind == NullKind) {
+		return true
+	}
+	isTime := func(tp *Type) bool {
+		return tp.Kind == TimeKind || tp.Kind == DateTimeKind
+	}
+	if isTime(a) && isTime(b) {
+		return true
+	}
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case NumberKind, StringKind, NullKind, BooleanKind, DateKind, DurationKind, ListKind, ContextKind:
+		return true
+	default:
+		return false
+	}
+}
+
+// arithmeticRules are the operand kinds supported by typedOp and numberOp
+var arithmeticRules = map[string][][3]TypeKind{
+	"+": {
+		{NumberKind, NumberKind, NumberKind}, {StringKind, StringKind, StringKind},
+		{DateKind, DurationKind, DateKind}, {DurationKind, DateKind, DateKind},
+		{TimeKind, DurationKind, TimeKind}, {DurationKind, TimeKind, TimeKind},
+		{DateTimeKind, DurationKind, DateTimeKind}, {DurationKind, DateTimeKind, DateTimeKind},
+		{DurationKind, DurationKind, DurationKind},
+	},
+	"-": {
+		{NumberKind, NumberKind, NumberKind},
+		{DateKind, DurationKind, DateKind}, {DateKind, DateKind, DurationKind},
+		{TimeKind, DurationKind, TimeKind}, {TimeKind, TimeKind, DurationKind},
+		{DateTimeKind, DurationKind, DateTimeKind}, {DateTimeKind, TimeKind, DurationKind}, {DateTimeKind, DateTimeKind, DurationKind},
+		{DurationKind, DurationKind, DurationKind},
+	},
+	"*":  {{NumberKind, NumberKind, NumberKind}},
+	"/":  {{NumberKind, NumberKind, NumberKind}},
+	"%":  {{NumberKind, NumberKind, NumberKind}},
+	"**": {{NumberKind, NumberKind, NumberKind}},
+}
+
+func arithmeticType(op string, left, right *Type) (*Type, bool) {
+	var result *Type
+	matched := false
+	for _, rule := range arithmeticRules[op] {
+		if (left.Kind == AnyKind || left.Kind == rule[0]) && (right.Kind == AnyKind || right.Kind == rule[1]) {
+			rt := &Type{Kind: rule[2]}
+			if matched && result.Kind != rt.Kind {
+				result = AnyType
+			} else {
+				result = rt
+			}
+			matched = true
+		}
+	}
+	return result, matched
+}
+
+// maybeNonBoolean tells whether an operand of and/or may be other than
+// true or false
+func maybeNonBoolean(tp *Type) bool {
+	return tp.Nullable || (tp.Kind != BooleanKind && tp.Kind != AnyKind)
+}
+
+func (checker *typeChecker) inferBinop(binop *Binop) *Type {
+	left := checker.check(binop.Left)
+	right := checker.check(binop.Right)
+
+	switch binop.Op {
+	case "and", "or":
+		// null or non-boolean operands give null
+		if maybeNonBoolean(left) || maybeNonBoolean(right) {
+			return BooleanType.OrNull()
+		}
+		return BooleanType
+	case "+", "-", "*", "/", "%", "**":
+		leftOk := checker.requireNonNull(binop.Left, left, fmt.Sprintf("the left operand of %s", binop.Op))
+		rightOk := checker.requireNonNull(binop.Right, right, fmt.Sprintf("the right operand of %s", binop.Op))
+		if !leftOk || !rightOk {
+			return AnyType
+		}
+		if tp, ok := arithmeticType(binop.Op, left, right); ok {
+			return tp
+		}
+		checker.errorf(binop, "cannot apply %s to %s and %s", binop.Op, left, right)
+		return AnyType
+	case "<", "<=", ">", ">=":
+		leftOk := checker.requireNonNull(binop.Left, left, fmt.Sprintf("the left operand of %s", binop.Op))
+		rightOk := checker.requireNonNull(binop.Right, right, fmt.Sprintf("the right operand of %s", binop.Op))
+		if leftOk && rightOk && !comparableTypes(left, right, false) {
+			checker.errorf(binop, "cannot compare %s with %s", left, right)
+		}
+		if left.Nullable || right.Nullable {
+			return BooleanType.OrNull()
+		}
+		return BooleanType
+	case "=":
+		if !comparableTypes(left.NonNull(), right.NonNull(), true) {
+			checker.errorf(binop, "cannot compare %s with %s", left, right)
+		}
+		return BooleanType
+	case "!=":
+		// values of different kinds are not equal
+		return BooleanType
+	case "in":
+		if checker.requireNonNull(binop.Right, right, "the right operand of in") {
+			switch right.Kind {
+			case ListKind, RangeKind, AnyKind:
+			default:
+				checker.errorf(binop, "in expects a list or range, got %s", right)
+			}
+		}
+		return BooleanType
+	case "[]":
+		return checker.inferIndex(binop, left, right)
+	default:
+		checker.errorf(binop, "unknown operator %s", binop.Op)
+		return AnyType
+	}
+}
+
+func (checker *typeChecker) inferIndex(binop *Binop, left, right *Type) *Type {
+	if !checker.requireNonNull(binop.Left, left, "the indexed value") {
+		return AnyType
+	}
+	switch left.Kind {
+	case ListKind:
+		if right.Kind != NumberKind && right.Kind != AnyKind {
+			checker.errorf(binop.Right, "list index must be a number, got %s", right)
+		}
+		if left.Elem != nil {
+			return left.Elem
+		}
+	case ContextKind:
+		if right.Kind != StringKind && right.Kind != AnyKind {
+			checker.errorf(binop.Right, "context key must be a string, got %s", right)
+		} else if key, ok := binop.Right.(*StringNode); ok {
+			return checker.fieldType(binop, left, key.Content())
+		}
+	case AnyKind:
+	default:
+		checker.errorf(binop, "cannot index %s", left)
+	}
+	return AnyType
+}
+
+func (checker *typeChecker) fieldType(node Node, ctx *Type, name string) *Type {
+	if ctx.Fields == nil {
+		return AnyType
+	}
+	if ft, ok := ctx.Fields[name]; ok {
+		return ft
+	}
+	checker.errorf(node, "context %s has no entry %s", ctx, name)
+	return AnyType
+}
+
+// temporalAttrs mirrors the GetAttr methods of temporal values
+var temporalAttrs = map[TypeKind]map[string]*Type{
+	DateKind: {"year": NumberType, "month": NumberType, "day": NumberType},
+	TimeKind: {"hour": NumberType, "minute": NumberType, "second": NumberType,
+		"timezone": StringType, "timezone offset": NumberType},
+	DateTimeKind: {"year": NumberType, "month": NumberType, "day": NumberType,
+		"hour": NumberType, "minute": NumberType, "second": NumberType,
+		"timezone": StringType, "timezone offset": NumberType},
+	DurationKind: {"years": NumberType, "months": NumberType, "days": NumberType,
+		"hours": NumberType, "minutes": NumberType, "seconds": NumberType},
+}
+
+func (checker *typeChecker) inferDotOp(op *DotOp) *Type {
+	left := checker.check(op.Left)
+	if !checker.requireNonNull(op.Left, left, fmt.Sprintf("the value of .%s", op.Attr)) {
+		return AnyType
+	}
+	switch left.Kind {
+	case ContextKind:
+		return checker.fieldType(op, left, op.Attr)
+	case AnyKind:
+		return AnyType
+	}
+	if attrs, ok := temporalAttrs[left.Kind]; ok {
+		if at, ok := attrs[op.Attr]; ok {
+			return at
+		}
+		checker.errorf(op, "%s has no attribute %s", left, op.Attr)
+	} else {
+		checker.errorf(op, "cannot get attribute %s of %s", op.Attr, left)
+	}
+	return AnyType
+}
+
+func (checker *typeChecker) inferFunDef(fdef *FunDef) *Type {
+	scope := make(map[string]*Type)
+	params := make([]*Type, len(fdef.Args))
+	for i, arg := range fdef.Args {
+		scope[arg] = AnyType
+		params[i] = AnyType
+	}
+	// the environment of the definition, used on instantiation
+	env := make([]map[string]*Type, len(checker.scopes))
+	copy(env, checker.scopes)
+
+	checker.push(scope)
+	result := checker.check(fdef.Body)
+	checker.pop()
+	return &Type{
+		Kind:       FunctionKind,
+		Params:     params,
+		ParamNames: fdef.Args,
+		Required:   len(fdef.Args),
+		Result:     result,
+		funDef:     fdef,
+		env:        env,
+	}
+}
+
+// instantiate checks the body of a lambda with the argument types
+func (checker *typeChecker) instantiate(fnType *Type, args map[string]*Type) *Type {
+	for _, fdef := range checker.instantiating {
+		if fdef == fnType.funDef {
+			return fnType.Result
+		}
+	}
+	scope := make(map[string]*Type)
+	for _, name := range fnType.ParamNames {
+		if at, ok := args[name]; ok {
+			scope[name] = at
+		} else {
+			scope[name] = NullType
+		}
+	}
+	savedScopes, savedRecord := checker.scopes, checker.record
+	checker.scopes = append(append([]map[string]*Type{}, fnType.env...), scope)
+	checker.record = false
+	checker.instantiating = append(checker.instantiating, fnType.funDef)
+	defer func() {
+		checker.scopes, checker.record = savedScopes, savedRecord
+		checker.instantiating = checker.instantiating[:len(checker.instantiating)-1]
+	}()
+	return checker.check(fnType.funDef.Body)
+}
+
+// assignable tells whether an argument of type arg may be passed to a
+// parameter of type param, ignoring nullness
+func assignable(param, arg *Type) bool {
+	if param.Kind == AnyKind || arg.Kind == AnyKind || arg.Kind == NullKind {
+		return true
+	}
+	if param.Kind == DateKind && arg.Kind == DateTimeKind {
+		// HasDate
+		return true
+	}
+	if param.Kind != arg.Kind {
+		return false
+	}
+	if (param.Kind == ListKind || param.Kind == RangeKind) && param.Elem != nil && arg.Elem != nil {
+		return assignable(param.Elem, arg.Elem)
+	}
+	return true
+}
+
+// bindTypeVars binds the type variables of param to the parts of arg
+func bindTypeVars(param, arg *Type, bindings map[string]*Type) {
+	if param.typeVar != "" {
+		bindings[param.typeVar] = unionType(bindings[param.typeVar], arg.NonNull())
+		return
+	}
+	if param.Elem != nil && arg.Elem != nil && param.Kind == arg.Kind {
+		bindTypeVars(param.Elem, arg.Elem, bindings)
+	}
+}
+
+func substituteTypeVars(tp *Type, bindings map[string]*Type) *Type {
+	if tp == nil {
+		return nil
+	}
+	if tp.typeVar != "" {
+		if bound, ok := bindings[tp.typeVar]; ok {
+			if tp.Nullable {
+				return bound.OrNull()
+			}
+			return bound
+		}
+		return AnyType
+	}
+	if tp.Elem != nil {
+		st := *tp
+		st.Elem = substituteTypeVars(tp.Elem, bindings)
+		return &st
+	}
+	return tp
+}
+
+func (checker *typeChecker) inferFunCall(call *FunCall) *Type {
+	var fnType *Type
+	funcName := call.FunRef.Repr()
+	if ref, ok := call.FunRef.(*Var); ok {
+		funcName = ref.Name
+		if tp, ok := checker.lookup(ref.Name); ok {
+			fnType = tp
+		} else {
+			checker.errorf(call.FunRef, "unknown function %s", ref.Repr())
+		}
+		if checker.record {
+			checker.result.types[call.FunRef] = fnType
+		}
+	} else {
+		fnType = checker.check(call.FunRef)
+	}
+
+	probing := checker.probing
+	checker.probing = probing || funcName == "is defined"
+	argTypes := make([]*Type, len(call.Args))
+	for i, arg := range call.Args {
+		argTypes[i] = checker.check(arg.Arg)
+	}
+	checker.probing = probing
+
+	if fnType == nil {
+		return AnyType
+	}
+	if !checker.requireNonNull(call.FunRef, fnType, "the function "+funcName) {
+		return AnyType
+	}
+	switch fnType.Kind {
+	case FunctionKind:
+	case AnyKind:
+		return AnyType
+	default:
+		checker.errorf(call.FunRef, "%s is not a function but %s", funcName, fnType)
+		return AnyType
+	}
+	if fnType.Params == nil && fnType.Variadic == nil {
+		if fnType.Result != nil {
+			return fnType.Result
+		}
+		return AnyType
+	}
+
+	// match the arguments with the parameters
+	bindings := make(map[string]*Type)
+	namedArgs := make(map[string]*Type)
+	var variadic []int
+	paramAt := func(i int) *Type {
+		if i < len(fnType.Params) {
+			return fnType.Params[i]
+		}
+		return fnType.Variadic
+	}
+	if call.KeywordArgs {
+		if fnType.ParamNames == nil {
+			checker.errorf(call, "%s does not accept keyword arguments", funcName)
+			return fnType.Result
+		}
+		for i, arg := range call.Args {
+			found := false
+			for j, name := range fnType.ParamNames {
+				if name == arg.Name {
+					found = true
+					checker.checkArg(call.Args[i].Arg, funcName, name, fnType.Params[j], argTypes[i], bindings)
+				}
+			}
+			if !found {
+				checker.warnf(arg.Arg, "%s has no parameter %s", funcName, arg.Name)
+			}
+			namedArgs[arg.Name] = argTypes[i]
+		}
+		for i, name := range fnType.ParamNames {
+			if _, ok := namedArgs[name]; !ok && i < fnType.Required {
+				if fnType.funDef != nil {
+					checker.warnf(call, "argument %s of %s is missing and bound to null", name, funcName)
+				} else {
+					checker.errorf(call, "argument %s of %s is missing", name, funcName)
+				}
+			}
+		}
+	} else {
+		if len(call.Args) < fnType.Required {
+			checker.errorf(call, "too few arguments for %s, expect %d, got %d", funcName, fnType.Required, len(call.Args))
+		} else if fnType.Variadic == nil && len(call.Args) > len(fnType.Params) {
+			checker.errorf(call, "too many arguments for %s, expect %d, got %d", funcName, len(fnType.Params), len(call.Args))
+		}
+		for i, arg := range call.Args {
+			if i >= len(fnType.Params) {
+				variadic = append(variadic, i)
+				continue
+			}
+			name := fmt.Sprintf("%d", i+1)
+			if i < len(fnType.ParamNames) {
+				name = fnType.ParamNames[i]
+				namedArgs[name] = argTypes[i]
+			}
+			checker.checkArg(arg.Arg, funcName, name, paramAt(i), argTypes[i], bindings)
+		}
+	}
+
+	if len(variadic) > 0 && fnType.Variadic != nil {
+		first := variadic[0]
+		if len(variadic) == 1 && (argTypes[first].Kind == ListKind || argTypes[first].Kind == AnyKind) {
+			// list functions accept a list or the items as arguments
+			elem := argTypes[first].Elem
+			if elem == nil {
+				elem = AnyType
+			}
+			checker.checkArg(call.Args[first].Arg, funcName, "list", ListOf(fnType.Variadic), ListOf(elem), bindings)
+		} else {
+			for _, i := range variadic {
+				checker.checkArg(call.Args[i].Arg, funcName, "list", fnType.Variadic, argTypes[i], bindings)
+			}
+		}
+	}
+
+	if fnType.funDef != nil {
+		return checker.instantiate(fnType, namedArgs)
+	}
+	return substituteTypeVars(fnType.Result, bindings)
+}
+
+func (checker *typeChecker) checkArg(node Node, funcName, paramName string, param, arg *Type, bindings map[string]*Type) {
+	if param == nil {
+		return
+	}
+	if !param.Nullable && param.Kind != AnyKind && arg.Nullable {
+		checker.warnf(node, "argument %s of %s may be null", paramName, funcName)
+	}
+	if !assignable(substituteTypeVars(param, bindings), arg) && !assignable(param, arg) {
+		checker.errorf(node, "argument %s of %s expects %s, got %s", paramName, funcName, param, arg)
+		return
+	}
+	bindTypeVars(param, arg, bindings)
+}
+
+// builtinSignatures declares the types of the prelude functions, the
+// arity and the parameter names come from the functions themselves
+var builtinSignatures = map[string]string{
+	"string":            "function<Any> -> string",
+	"number":            "function<Any, string, string> -> number?",
+	"not":               "function<Any> -> boolean?",
+	"is defined":        "function<Any> -> boolean",
+	"string length":     "function<string> -> number",
+	"substring":         "function<string, number, number> -> string",
+	"upper case":        "function<string> -> string",
+	"lower case":        "function<string> -> string",
+	"contains":          "function<string, string> -> boolean",
+	"starts with":       "function<string, string> -> boolean",
+	"ends with":         "function<string, string> -> boolean",
+	"list contains":     "function<list, Any> -> boolean",
+	"count":             "function<Any...> -> number",
+	"min":               "function<T...> -> T",
+	"max":               "function<T...> -> T",
+	"sum":               "function<number...> -> number",
+	"product":           "function<number...> -> number",
+	"mean":              "function<number...> -> number",
+	"stddev":            "function<number...> -> number",
+	"median":            "function<number...> -> number",
+	"variance":          "function<number...> -> number",
+	"mode":              "function<number...> -> list<number>",
+	"percentile":        "function<list<number>, number> -> number",
+	"quantile":          "function<list<number>, number> -> number",
+	"decimal":           "function<number, number> -> number",
+	"floor":             "function<number, number> -> number",
+	"ceiling":           "function<number, number> -> number",
+	"round up":          "function<number, number> -> number",
+	"round down":        "function<number, number> -> number",
+	"round half up":     "function<number, number> -> number",
+	"round half down":   "function<number, number> -> number",
+	"abs":               "function<T> -> T",
+	"modulo":            "function<number, number> -> number",
+	"sqrt":              "function<number> -> number",
+	"log":               "function<number> -> number",
+	"exp":               "function<number> -> number",
+	"odd":               "function<number> -> boolean",
+	"even":              "function<number> -> boolean",
+	"all":               "function<boolean?...> -> boolean?",
+	"and":               "function<boolean?...> -> boolean?",
+	"any":               "function<boolean?...> -> boolean?",
+	"or":                "function<boolean?...> -> boolean?",
+	"sublist":           "function<list<T>, number, number> -> list<T>",
+	"append":            "function<list<T>, T...> -> list<T>",
+	"concatenate":       "function<list<T>...> -> list<T>",
+	"insert before":     "function<list<T>, number, T> -> list<T>",
+	"remove":            "function<list<T>, number> -> list<T>",
+	"reverse":           "function<list<T>> -> list<T>",
+	"index of":          "function<list, Any> -> list<number>",
+	"union":             "function<list<T>...> -> list<T>",
+	"distinct values":   "function<list<T>> -> list<T>",
+	"flatten":           "function<list> -> list",
+	"sort":              "function<list<T>, function> -> list<T>",
+	"string join":       "function<list<string>, string, string, string> -> string",
+	"get value":         "function<context, string> -> Any",
+	"get entries":       "function<context> -> list<context<key: string, value: Any>>",
+	"context put":       "function<context, string, Any> -> context",
+	"context merge":     "function<list<context>> -> context",
+	"block":             "function<Any...> -> Any",
+	"help":              "function<Any> -> string",
+	"typeof":            "function<Any> -> string",
+	"date":              "function<Any> -> date",
+	"time":              "function<Any> -> time",
+	"date and time":     "function<Any> -> date and time",
+	"duration":          "function<string> -> duration",
+	"now":               "function<> -> date and time",
+	"today":             "function<> -> date",
+	"random number":     "function<> -> number",
+	"uuid":              "function<> -> string",
+	"day of week":       "function<date> -> number",
+	"day of year":       "function<date> -> number",
+	"week of year":      "function<date> -> number",
+	"month of year":     "function<date> -> number",
+	"last day of month": "function<date> -> number",
+}
+
+var rangeFunctionNames = []string{
+	"before", "after", "meets", "met by", "overlaps", "overlaps before",
+	"overlaps after", "finishes", "starts", "finished by", "started by",
+	"includes", "during", "coincides",
+}
+
+func init() {
+	for _, name := range rangeFunctionNames {
+		builtinSignatures[name] = "function<Any, Any> -> boolean"
+	}
+}
+
+var parsedSignatures sync.Map
+
+func builtinSignature(name string) *Type {
+	if sig, ok := parsedSignatures.Load(name); ok {
+		return sig.(*Type)
+	}
+	s, ok := builtinSignatures[name]
+	if !ok {
+		return nil
+	}
+	sig := MustParseType(s)
+	parsedSignatures.Store(name, sig)
+	return sig
+}
+
+// builtinType combines the signature with the arity of the prelude function
+func builtinType(name string, fn any) *Type {
+	var required, optional []string
+	var varArgName string
+	switch f := fn.(type) {
+	case *NativeFun:
+		if len(f.overloads) > 0 {
+			// the arguments of overloaded functions are not checked
+			tp := &Type{Kind: FunctionKind, Result: AnyType}
+			if sig := builtinSignature(name); sig != nil {
+				tp.Result = sig.Result
+			}
+			return tp
+		}
+		required, optional, varArgName = f.requiredArgNames, f.optionalArgNames, f.varArgName
+	case *Macro:
+		required, optional, varArgName = f.requiredArgNames, f.optionalArgNames, f.varArgName
+	default:
+		return AnyType
+	}
+	tp := &Type{
+		Kind:       FunctionKind,
+		ParamNames: append(append([]string{}, required...), optional...),
+		Required:   len(required),
+		Result:     AnyType,
+	}
+	sig := builtinSignature(name)
+	if sig != nil {
+		tp.Result = sig.Result
+	}
+	tp.Params = make([]*Type, len(tp.ParamNames))
+	for i := range tp.Params {
+		if sig != nil && i < len(sig.Params) {
+			tp.Params[i] = sig.Params[i]
+		} else {
+			tp.Params[i] = AnyType
+		}
+		if i >= tp.Required {
+			tp.Params[i] = tp.Params[i].OrNull()
+		}
+	}
+	if varArgName != "" {
+		tp.Variadic = AnyType
+		if sig != nil && sig.Variadic != nil {
+			tp.Variadic = sig.Variadic
+		}
+	}
+	return tp
+}
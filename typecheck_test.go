@@ -0,0 +1,172 @@
+package feel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseType(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, s := range []string{
+		"number", "string?", "date and time", "list<number>",
+		"context<age: number, home address: context<zip: string>>",
+		"function<number, string> -> boolean", "range<date>",
+	} {
+		tp, err := ParseType(s)
+		assert.Nil(err)
+		assert.Equal(s, tp.String())
+	}
+	_, err := ParseType("list<number")
+	assert.NotNil(err)
+	_, err = ParseType("numbr")
+	assert.NotNil(err)
+}
+
+func TestCheckTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	inputs := map[string]*Type{
+		"applicant": MustParseType("context<name: string, age: number, birthday: date?, loans: list<context<amount: number>>>"),
+		"rate":      NumberType,
+	}
+
+	result, err := CheckTypesString(`for loan in applicant.loans return loan.amount * rate`, inputs)
+	assert.Nil(err)
+	assert.Equal(0, len(result.Diagnostics))
+	assert.Equal("list<number>", result.Type.String())
+
+	result, err = CheckTypesString(`if applicant.age > 18 then applicant.name else null`, inputs)
+	assert.Nil(err)
+	assert.Equal(0, len(result.Diagnostics))
+	assert.Equal("string?", result.Type.String())
+
+	// errors on the branch not taken are found as well
+	result, err = CheckTypesString(`if rate > 1 then rate else applicant.name + @"2023-06-01"`, inputs)
+	assert.Nil(err)
+	assert.Equal(1, len(result.Errors()))
+	assert.Equal("1:28: error: cannot apply + to string and date", result.Errors()[0].String())
+
+	result, err = CheckTypesString(`applicant.birthday.year + applicant.salary`, inputs)
+	assert.Nil(err)
+	assert.Equal(1, len(result.Warnings()))
+	assert.Equal("the value of .year may be null", result.Warnings()[0].Message)
+	assert.Equal(1, len(result.Errors()))
+	assert.Contains(result.Errors()[0].Message, "has no entry salary")
+
+	// builtin signatures
+	result, err = CheckTypesString(`sum(for loan in applicant.loans return loan.amount) + string length(applicant.age)`, inputs)
+	assert.Nil(err)
+	assert.Equal(1, len(result.Errors()))
+	assert.Equal("argument string of string length expects string, got number", result.Errors()[0].Message)
+	assert.Equal("number", result.Type.String())
+
+	result, err = CheckTypesString(`reverse(["a", "b"])`, nil)
+	assert.Nil(err)
+	assert.Equal("list<string>", result.Type.String())
+
+	result, err = CheckTypesString(`substring("abc")`, nil)
+	assert.Nil(err)
+	assert.Equal("too few arguments for substring, expect 2, got 1", result.Errors()[0].Message)
+
+	// undeclared inputs
+	result, err = CheckTypesString(`is defined(x) and y > 1`, nil)
+	assert.Nil(err)
+	assert.Equal(1, len(result.Warnings()))
+	assert.Equal("undeclared variable y", result.Warnings()[0].Message)
+
+	// three-valued logic, null operands give null
+	result, err = CheckTypesString(`applicant.age > 18 and rate < 1`, inputs)
+	assert.Nil(err)
+	assert.Equal("boolean", result.Type.String())
+
+	result, err = CheckTypesString(`applicant.age > 18 and applicant.birthday`, inputs)
+	assert.Nil(err)
+	assert.Equal("boolean?", result.Type.String())
+
+	result, err = CheckTypesString(`all([rate > 1, applicant.age > 18])`, inputs)
+	assert.Nil(err)
+	assert.Equal("boolean?", result.Type.String())
+}
+
+func TestCheckLambdaTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	// lambdas are checked with the argument types at calls
+	result, err := CheckTypesString(`{double: function(v) v * 2, a: double(3), b: double("x")}`, nil)
+	assert.Nil(err)
+	assert.Equal("context<a: number, b: Any, double: function<Any> -> number>", result.Type.String())
+	assert.Equal(1, len(result.Errors()))
+	assert.Equal("1:22: error: cannot apply * to string and number", result.Errors()[0].String())
+
+	// recursion
+	result, err = CheckTypesString(`{fact: function(n) if n < 2 then 1 else n * fact(n - 1), r: fact(5)}.r`, nil)
+	assert.Nil(err)
+	assert.Equal(0, len(result.Diagnostics))
+	assert.Equal("number", result.Type.String())
+
+	// types of every node
+	ast, err := ParseString(`[1, 2][1] + 2`)
+	assert.Nil(err)
+	result = CheckTypes(ast, nil)
+	assert.Equal(NumberType, result.TypeOf(ast.(*Binop).Left))
+	assert.Equal("list<number>", result.TypeOf(ast.(*Binop).Left.(*Binop).Left).String())
+}
+
+func TestCheckTemporalTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	inputs := map[string]*Type{
+		"due":    DateType,
+		"start":  DateTimeType,
+		"open":   TimeType,
+		"period": DurationType,
+	}
+
+	temporal := func(s string) any {
+		v, err := ParseTemporalValue(s)
+		assert.Nil(err)
+		return v
+	}
+	// the checked types agree with the evaluated values
+	scope := Scope{
+		"due":    temporal(`2024-03-01`),
+		"start":  temporal(`2024-03-01T09:00:00`),
+		"open":   temporal(`09:00:00`),
+		"period": temporal(`P1D`),
+	}
+	cases := map[string]string{
+		`due < @"2024-04-01"`:            "boolean",
+		`period >= @"PT12H"`:             "boolean",
+		`due = @"2024-03-01"`:            "boolean",
+		`due + period`:                   "date",
+		`period + due`:                   "date",
+		`due - period`:                   "date",
+		`due - @"2024-01-01"`:            "duration",
+		`open + @"PT30M"`:                "time",
+		`open - @"08:00:00"`:             "duration",
+		`start + period`:                 "date and time",
+		`start - @"2024-01-01T00:00:00"`: "duration",
+		`period + @"PT1H"`:               "duration",
+		`period - period`:                "duration",
+	}
+	for input, expect := range cases {
+		result, err := CheckTypesString(input, inputs)
+		assert.Nil(err)
+		assert.Equal(0, len(result.Diagnostics), "%s: %v", input, result.Diagnostics)
+		assert.Equal(expect, result.Type.String(), input)
+
+		res, err := EvalStringWithScope(input, scope)
+		assert.Nil(err, input)
+		assert.Equal(expect, valueType(res).String(), input)
+	}
+
+	result, err := CheckTypesString(`due < period`, inputs)
+	assert.Nil(err)
+	assert.Equal("1:1: error: cannot compare date with duration", result.Errors()[0].String())
+
+	result, err = CheckTypesString(`period - due`, inputs)
+	assert.Nil(err)
+	assert.Equal("1:1: error: cannot apply - to duration and date", result.Errors()[0].String())
+}
@@ -0,0 +1,406 @@
+package feel
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TypeKind is the kind of a FEEL type
+type TypeKind int
+
+const (
+	AnyKind TypeKind = iota
+	NullKind
+	BooleanKind
+	NumberKind
+	StringKind
+	DateKind
+	TimeKind
+	DateTimeKind
+	DurationKind
+	RangeKind
+	ListKind
+	ContextKind
+	FunctionKind
+)
+
+var kindNames = map[TypeKind]string{
+	AnyKind:      "Any",
+	NullKind:     "Null",
+	BooleanKind:  "boolean",
+	NumberKind:   "number",
+	StringKind:   "string",
+	DateKind:     "date",
+	TimeKind:     "time",
+	DateTimeKind: "date and time",
+	DurationKind: "duration",
+	RangeKind:    "range",
+	ListKind:     "list",
+	ContextKind:  "context",
+	FunctionKind: "function",
+}
+
+func (kind TypeKind) String() string {
+	return kindNames[kind]
+}
+
+// Type is a FEEL type used by the type checker
+type Type struct {
+	Kind TypeKind
+	// whether the value may be null
+	Nullable bool
+
+	// the element type of lists and ranges
+	Elem *Type
+	// the entries of contexts, nil when they are unknown
+	Fields map[string]*Type
+
+	// the parameters of functions, Params is nil when they are unknown
+	Params     []*Type
+	ParamNames []string
+	// the number of required parameters
+	Required int
+	// the type of the variadic arguments, nil if not variadic
+	Variadic *Type
+	Result   *Type
+
+	// type variable in builtin signatures, such as T in list<T>
+	typeVar string
+	// lambdas are checked again with the argument types at calls
+	funDef *FunDef
+	env    []map[string]*Type
+}
+
+var (
+	AnyType      = &Type{Kind: AnyKind}
+	NullType     = &Type{Kind: NullKind, Nullable: true}
+	BooleanType  = &Type{Kind: BooleanKind}
+	NumberType   = &Type{Kind: NumberKind}
+	StringType   = &Type{Kind: StringKind}
+	DateType     = &Type{Kind: DateKind}
+	TimeType     = &Type{Kind: TimeKind}
+	DateTimeType = &Type{Kind: DateTimeKind}
+	DurationType = &Type{Kind: DurationKind}
+)
+
+// ListOf returns the type of lists of elem
+func ListOf(elem *Type) *Type {
+	return &Type{Kind: ListKind, Elem: elem}
+}
+
+// RangeOf returns the type of ranges of elem
+func RangeOf(elem *Type) *Type {
+	return &Type{Kind: RangeKind, Elem: elem}
+}
+
+// ContextOf returns the type of contexts with the entries
+func ContextOf(fields map[string]*Type) *Type {
+	return &Type{Kind: ContextKind, Fields: fields}
+}
+
+// FunctionOf returns the type of functions
+func FunctionOf(result *Type, params ...*Type) *Type {
+	return &Type{Kind: FunctionKind, Params: params, Required: len(params), Result: result}
+}
+
+// OrNull returns the nullable version of the type
+func (tp *Type) OrNull() *Type {
+	if tp.Nullable {
+		return tp
+	}
+	nt := *tp
+	nt.Nullable = true
+	return &nt
+}
+
+// NonNull returns the type without null
+func (tp *Type) NonNull() *Type {
+	if !tp.Nullable || tp.Kind == NullKind {
+		return tp
+	}
+	nt := *tp
+	nt.Nullable = false
+	return &nt
+}
+
+func (tp *Type) String() string {
+	var s string
+	switch tp.Kind {
+	case AnyKind:
+		if tp.typeVar != "" {
+			s = tp.typeVar
+		} else {
+			s = "Any"
+		}
+	case ListKind, RangeKind:
+		s = tp.Kind.String()
+		if tp.Elem != nil && tp.Elem.Kind != AnyKind {
+			s = fmt.Sprintf("%s<%s>", s, tp.Elem)
+		}
+	case ContextKind:
+		s = "context"
+		if tp.Fields != nil {
+			names := make([]string, 0, len(tp.Fields))
+			for name := range tp.Fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			entries := make([]string, len(names))
+			for i, name := range names {
+				entries[i] = fmt.Sprintf("%s: %s", name, tp.Fields[name])
+			}
+			s = fmt.Sprintf("context<%s>", strings.Join(entries, ", "))
+		}
+	case FunctionKind:
+		s = "function"
+		if tp.Params != nil || tp.Variadic != nil {
+			params := make([]string, 0, len(tp.Params)+1)
+			for _, param := range tp.Params {
+				params = append(params, param.String())
+			}
+			if tp.Variadic != nil {
+				params = append(params, tp.Variadic.String()+"...")
+			}
+			s = fmt.Sprintf("function<%s>", strings.Join(params, ", "))
+		}
+		if tp.Result != nil {
+			s = fmt.Sprintf("%s -> %s", s, tp.Result)
+		}
+	default:
+		s = tp.Kind.String()
+	}
+	if tp.Nullable && tp.Kind != NullKind && tp.Kind != AnyKind {
+		s += "?"
+	}
+	return s
+}
+
+// unionType returns a type containing the values of both
+func unionType(a, b *Type) *Type {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	nullable := a.Nullable || b.Nullable
+	if a.Kind == NullKind {
+		return b.OrNull()
+	}
+	if b.Kind == NullKind {
+		return a.OrNull()
+	}
+	if a.Kind != b.Kind || a.Kind == AnyKind {
+		return &Type{Kind: AnyKind, Nullable: nullable}
+	}
+	var ut *Type
+	switch a.Kind {
+	case ListKind, RangeKind:
+		ut = &Type{Kind: a.Kind, Elem: unionType(a.Elem, b.Elem)}
+	case ContextKind:
+		ut = &Type{Kind: ContextKind}
+		if a.Fields != nil && b.Fields != nil && len(a.Fields) == len(b.Fields) {
+			fields := make(map[string]*Type)
+			for name, ft := range a.Fields {
+				if bt, ok := b.Fields[name]; ok {
+					fields[name] = unionType(ft, bt)
+				} else {
+					fields = nil
+					break
+				}
+			}
+			ut.Fields = fields
+		}
+	case FunctionKind:
+		if a == b {
+			ut = a.NonNull()
+		} else {
+			ut = &Type{Kind: FunctionKind}
+		}
+	default:
+		ut = &Type{Kind: a.Kind}
+	}
+	if nullable {
+		ut.Nullable = true
+	}
+	return ut
+}
+
+// ParseType parses type names such as `number`, `date and time`,
+// `list<string>`, `context<name: string, age: number>`,
+// `range<date>` and `function<number, string> -> boolean`, a
+// trailing `?` marks the type as nullable
+func ParseType(s string) (*Type, error) {
+	parser := &typeParser{input: []rune(s)}
+	tp, err := parser.parseType()
+	if err != nil {
+		return nil, err
+	}
+	parser.skipSpaces()
+	if parser.pos < len(parser.input) {
+		return nil, parser.errorf("unexpected %q", string(parser.input[parser.pos:]))
+	}
+	return tp, nil
+}
+
+// MustParseType is like ParseType but panics on errors
+func MustParseType(s string) *Type {
+	tp, err := ParseType(s)
+	if err != nil {
+		panic(err)
+	}
+	return tp
+}
+
+type typeParser struct {
+	input []rune
+	pos   int
+}
+
+func (parser *typeParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("bad type %q at %d, %s", string(parser.input), parser.pos, fmt.Sprintf(format, args...))
+}
+
+func (parser *typeParser) skipSpaces() {
+	for parser.pos < len(parser.input) && unicode.IsSpace(parser.input[parser.pos]) {
+		parser.pos++
+	}
+}
+
+func (parser *typeParser) accept(token string) bool {
+	parser.skipSpaces()
+	rs := []rune(token)
+	if parser.pos+len(rs) <= len(parser.input) && string(parser.input[parser.pos:parser.pos+len(rs)]) == token {
+		parser.pos += len(rs)
+		return true
+	}
+	return false
+}
+
+// parseName reads words separated by spaces, such as `date and time`
+func (parser *typeParser) parseName() string {
+	parser.skipSpaces()
+	start := parser.pos
+	end := start
+	for parser.pos < len(parser.input) {
+		r := parser.input[parser.pos]
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			parser.pos++
+			end = parser.pos
+		} else if r == ' ' {
+			parser.pos++
+		} else {
+			break
+		}
+	}
+	parser.pos = end
+	return string(parser.input[start:end])
+}
+
+func (parser *typeParser) parseType() (*Type, error) {
+	name := parser.parseName()
+	var tp *Type
+	switch name {
+	case "Any", "any":
+		tp = &Type{Kind: AnyKind}
+	case "Null", "null":
+		tp = &Type{Kind: NullKind, Nullable: true}
+	case "boolean", "bool":
+		tp = &Type{Kind: BooleanKind}
+	case "number":
+		tp = &Type{Kind: NumberKind}
+	case "string":
+		tp = &Type{Kind: StringKind}
+	case "date":
+		tp = &Type{Kind: DateKind}
+	case "time":
+		tp = &Type{Kind: TimeKind}
+	case "date and time", "datetime":
+		tp = &Type{Kind: DateTimeKind}
+	case "duration", "days and time duration", "years and months duration":
+		tp = &Type{Kind: DurationKind}
+	case "list", "range":
+		kind := ListKind
+		if name == "range" {
+			kind = RangeKind
+		}
+		tp = &Type{Kind: kind, Elem: AnyType}
+		if parser.accept("<") {
+			elem, err := parser.parseType()
+			if err != nil {
+				return nil, err
+			}
+			if !parser.accept(">") {
+				return nil, parser.errorf("expect >")
+			}
+			tp.Elem = elem
+		}
+	case "context":
+		tp = &Type{Kind: ContextKind}
+		if parser.accept("<") {
+			tp.Fields = make(map[string]*Type)
+			for !parser.accept(">") {
+				fieldName := parser.parseName()
+				if fieldName == "" || !parser.accept(":") {
+					return nil, parser.errorf("expect entry name and :")
+				}
+				ft, err := parser.parseType()
+				if err != nil {
+					return nil, err
+				}
+				tp.Fields[fieldName] = ft
+				if !parser.accept(",") && !parser.accept(">") {
+					return nil, parser.errorf("expect , or >")
+				} else if parser.input[parser.pos-1] == '>' {
+					break
+				}
+			}
+		}
+	case "function":
+		tp = &Type{Kind: FunctionKind}
+		if parser.accept("<") {
+			tp.Params = []*Type{}
+			for !parser.accept(">") {
+				param, err := parser.parseType()
+				if err != nil {
+					return nil, err
+				}
+				if parser.accept("...") {
+					tp.Variadic = param
+				} else {
+					tp.Params = append(tp.Params, param)
+				}
+				if !parser.accept(",") && !parser.accept(">") {
+					return nil, parser.errorf("expect , or >")
+				} else if parser.input[parser.pos-1] == '>' {
+					break
+				}
+			}
+			tp.Required = len(tp.Params)
+		}
+		if parser.accept("->") {
+			result, err := parser.parseType()
+			if err != nil {
+				return nil, err
+			}
+			tp.Result = result
+		} else {
+			tp.Result = AnyType
+		}
+	case "":
+		return nil, parser.errorf("expect type name")
+	default:
+		if len(name) == 1 && unicode.IsUpper(rune(name[0])) {
+			// type variables
+			tp = &Type{Kind: AnyKind, typeVar: name}
+		} else {
+			return nil, parser.errorf("unknown type %s", name)
+		}
+	}
+	if parser.accept("?") {
+		tp.Nullable = true
+	}
+	return tp, nil
+}
@@ -12,7 +12,26 @@ func typeIsStruct(tp reflect.Type) bool {
 		(tp.Kind() == reflect.Ptr && typeIsStruct(tp.Elem())))
 }
 
+// passedAsIs tells whether values of the type need no decoding, as
+// they are scalars or shared already
+func passedAsIs(tp reflect.Type) bool {
+	switch tp.Kind() {
+	case reflect.Bool, reflect.String, reflect.Ptr, reflect.Interface,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
 func interfaceToValue(a interface{}, outputType reflect.Type) (reflect.Value, error) {
+	if a != nil {
+		if tp := reflect.TypeOf(a); passedAsIs(tp) && (tp == outputType || (outputType.Kind() == reflect.Interface && tp.Implements(outputType))) {
+			return reflect.ValueOf(a), nil
+		}
+	}
 	output := reflect.Zero(outputType).Interface()
 	config := &mapstructure.DecoderConfig{
 		Metadata: nil,
@@ -31,6 +50,9 @@ func interfaceToValue(a interface{}, outputType reflect.Type) (reflect.Value, er
 }
 
 func valueToInterface(tp reflect.Type, val reflect.Value) (interface{}, error) {
+	if passedAsIs(tp) {
+		return val.Interface(), nil
+	}
 	var output interface{}
 	//if typeIsStruct(tp) {
 	if false {
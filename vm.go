@@ -0,0 +1,349 @@
+package feel
+
+// vmIter iterates a list in for, some and every expressions
+type vmIter struct {
+	list    []any
+	pos     int
+	results []any
+}
+
+// vm is the state of a program run
+type vm struct {
+	prog  *Program
+	intp  *Interpreter
+	stack []any
+
+	locals   []any
+	globals  []any
+	resolved []bool
+}
+
+// Run evaluates the program in the scopes of the interpreter. When the
+// interpreter has hooks attached, such as a tracer, a debugger or a
+// profiler, the AST is evaluated by the tree walker instead, so that
+// the hooks observe every node.
+func (prog *Program) Run(intp *Interpreter) (any, error) {
+	if intp.startEval() {
+		defer intp.endEval()
+	}
+	if len(intp.hooks) > 0 {
+		return intp.EvalNode(prog.node)
+	}
+	m := &vm{
+		prog:     prog,
+		intp:     intp,
+		stack:    make([]any, 0, 16),
+		locals:   make([]any, prog.nLocs),
+		globals:  make([]any, len(prog.names)),
+		resolved: make([]bool, len(prog.names)),
+	}
+	return m.run()
+}
+
+// RunWithScope evaluates the program in a new interpreter with the scope
+func (prog *Program) RunWithScope(scope Scope) (any, error) {
+	intp := NewIntepreter()
+	if err := intp.pushScope(scope); err != nil {
+		return nil, err
+	}
+	return prog.Run(intp)
+}
+
+func (m *vm) push(v any) {
+	m.stack = append(m.stack, v)
+}
+
+func (m *vm) pop() any {
+	v := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return v
+}
+
+func (m *vm) top() any {
+	return m.stack[len(m.stack)-1]
+}
+
+// global resolves the name at the first use in the run
+func (m *vm) global(idx int) (any, error) {
+	if m.resolved[idx] {
+		return m.globals[idx], nil
+	}
+	v, ok, err := m.intp.lookup(m.prog.names[idx])
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		v = Null
+	}
+	m.globals[idx] = v
+	m.resolved[idx] = true
+	return v, nil
+}
+
+// pushLocals makes the visible locals accessible to the tree walker
+func (m *vm) pushLocals(visible []localVar) bool {
+	if len(visible) == 0 {
+		return false
+	}
+	scope := make(Scope, len(visible))
+	for _, local := range visible {
+		scope[local.name] = m.locals[local.slot]
+	}
+	m.intp.ScopeStack = append(m.intp.ScopeStack, scope)
+	return true
+}
+
+func (m *vm) run() (any, error) {
+	code := m.prog.code
+	for pc := 0; pc < len(code); pc++ {
+		inst := code[pc]
+		switch inst.op {
+		case opConst:
+			m.push(m.prog.consts[inst.arg])
+		case opLoadLocal:
+			m.push(m.locals[inst.arg])
+		case opSetLocal:
+			m.locals[inst.arg] = m.top()
+		case opLoadGlobal:
+			v, err := m.global(inst.arg)
+			if err != nil {
+				return nil, err
+			}
+			m.push(v)
+		case opBinary:
+			right := m.pop()
+			left := m.pop()
+			site := m.prog.binops[inst.arg]
+			if m.intp.nullMode && site.nullPropagates && (isNull(left) || isNull(right)) {
+				m.push(Null)
+				continue
+			}
+			v, err := site.fn(left, right)
+			if err != nil {
+				if v, err = m.fail(inst, err); err != nil {
+					return nil, err
+				}
+			}
+			m.push(v)
+		case opDot:
+			left := m.pop()
+			if m.intp.nullMode && isNull(left) {
+				m.push(Null)
+				continue
+			}
+			v, err := attrValue(left, m.prog.names[inst.arg])
+			if err != nil {
+				if v, err = m.fail(inst, err); err != nil {
+					return nil, err
+				}
+			}
+			m.push(v)
+		case opAnd:
+			if m.top() == false {
+				pc = inst.arg - 1
+			}
+		case opOr:
+			if m.top() == true {
+				pc = inst.arg - 1
+			}
+		case opJump:
+			pc = inst.arg - 1
+		case opJumpUnlessTrue:
+			if m.pop() != true {
+				pc = inst.arg - 1
+			}
+		case opTest:
+			t, err := m.intp.unaryTest(inst.node, m.pop())
+			if err != nil {
+				return nil, err
+			}
+			if t == true {
+				m.stack[len(m.stack)-1] = true
+				pc = inst.arg - 1
+			} else if t != false {
+				m.stack[len(m.stack)-1] = Null
+			}
+		case opList:
+			var arr []any
+			if inst.arg > 0 {
+				arr = make([]any, inst.arg)
+				copy(arr, m.stack[len(m.stack)-inst.arg:])
+				m.stack = m.stack[:len(m.stack)-inst.arg]
+			}
+			m.push(arr)
+		case opRange:
+			end := m.pop()
+			start := m.pop()
+			m.push(&RangeValue{
+				Start:     start,
+				StartOpen: inst.arg&rangeStartOpen != 0,
+				End:       end,
+				EndOpen:   inst.arg&rangeEndOpen != 0,
+			})
+		case opContext:
+			names := m.prog.contexts[inst.arg]
+			values := m.stack[len(m.stack)-len(names):]
+			mapVal := make(map[string]any, len(names))
+			for i, name := range names {
+				mapVal[name] = values[i]
+			}
+			m.stack = m.stack[:len(m.stack)-len(names)]
+			m.push(mapVal)
+		case opIter:
+			aList, ok := m.pop().([]any)
+			if !ok {
+				v, err := m.fail(inst, NewErrTypeMismatch("list"))
+				if err != nil {
+					return nil, err
+				}
+				m.push(v)
+				pc = inst.arg2 - 1
+				continue
+			}
+			m.push(&vmIter{list: aList, results: make([]any, 0)})
+		case opNext:
+			iter := m.top().(*vmIter)
+			if iter.pos >= len(iter.list) {
+				pc = inst.arg2 - 1
+			} else {
+				m.locals[inst.arg] = iter.list[iter.pos]
+				iter.pos++
+			}
+		case opCollect:
+			v := m.pop()
+			iter := m.top().(*vmIter)
+			iter.results = append(iter.results, v)
+		case opFilter:
+			v := m.pop()
+			iter := m.top().(*vmIter)
+			if v == true {
+				iter.results = append(iter.results, iter.list[iter.pos-1])
+			}
+		case opFind:
+			v := m.pop()
+			iter := m.top().(*vmIter)
+			if v == true {
+				m.stack[len(m.stack)-1] = iter.list[iter.pos-1]
+				pc = inst.arg2 - 1
+			}
+		case opEndIter:
+			m.stack[len(m.stack)-1] = m.top().(*vmIter).results
+		case opEndFind:
+			m.stack[len(m.stack)-1] = nil
+		case opFunDef:
+			fundef := m.prog.fundefs[inst.arg]
+			m.push(&FunDef{Args: fundef.Args, Body: fundef.Body})
+		case opPrepareCall:
+			site := m.prog.calls[inst.arg]
+			var err error
+			switch callee := m.top().(type) {
+			case *Macro:
+				// the arguments of macros are nodes
+				pushed := m.pushLocals(site.visible)
+				var v any
+				v, err = site.node.EvalMacro(m.intp, callee)
+				if pushed {
+					m.intp.Pop()
+				}
+				if err == nil {
+					m.stack[len(m.stack)-1] = v
+					pc = inst.arg2 - 1
+				}
+			case *NativeFun:
+				if site.argNames == nil && site.argc < len(callee.requiredArgNames) {
+					err = NewErrTooFewArguments(callee.requiredArgNames[site.argc:])
+				}
+			case *FunDef:
+				if len(callee.Args) > site.argc {
+					err = NewErrTooFewArguments(callee.Args[site.argc:])
+				} else if len(callee.Args) < site.argc {
+					err = NewErrTooManyArguments()
+				}
+			default:
+				err = NewErrTypeMismatch("function")
+			}
+			if err != nil {
+				v, err := m.fail(inst, err)
+				if err != nil {
+					return nil, err
+				}
+				m.stack[len(m.stack)-1] = v
+				pc = inst.arg2 - 1
+			}
+		case opCall:
+			site := m.prog.calls[inst.arg]
+			args := m.stack[len(m.stack)-site.argc:]
+			callee := m.stack[len(m.stack)-site.argc-1]
+			var v any
+			var err error
+			switch fn := callee.(type) {
+			case *NativeFun:
+				var argVals map[string]any
+				argVals, err = fn.argMap(args, site.argNames)
+				if err == nil {
+					v, err = fn.Call(m.intp, argVals)
+					if err != nil && m.intp.nullMode {
+						err = functionError(site.node.FunRef.Repr(), err)
+					}
+				}
+			case *FunDef:
+				v, err = m.callFunDef(site, fn, args)
+			}
+			if err != nil {
+				if v, err = m.fail(inst, err); err != nil {
+					return nil, err
+				}
+			}
+			m.stack = m.stack[:len(m.stack)-site.argc]
+			m.stack[len(m.stack)-1] = v
+		case opEval:
+			site := m.prog.nodes[inst.arg]
+			pushed := m.pushLocals(site.visible)
+			v, err := m.intp.EvalNode(site.node)
+			if pushed {
+				m.intp.Pop()
+			}
+			if err != nil {
+				return nil, err
+			}
+			m.push(v)
+		}
+	}
+	return m.pop(), nil
+}
+
+// fail handles the error of the instruction, errors give null with
+// warnings in the null mode unless they are hard errors, undefined
+// results always give null
+func (m *vm) fail(inst instruction, err error) (any, error) {
+	return m.intp.nullOnError(inst.node, err)
+}
+
+// callFunDef evaluates the body of the function by the tree walker,
+// the locals visible at the call site are accessible to the body as
+// functions are dynamically scoped
+func (m *vm) callFunDef(site *callSite, funDef *FunDef, args []any) (any, error) {
+	pushed := m.pushLocals(site.visible)
+	if pushed {
+		defer m.intp.Pop()
+	}
+	m.intp.PushEmpty()
+	defer m.intp.Pop()
+	if site.argNames != nil {
+		kwArgMap := make(map[string]any, len(args))
+		for i, name := range site.argNames {
+			kwArgMap[name] = args[i]
+		}
+		for _, argName := range funDef.Args {
+			if v, ok := kwArgMap[argName]; ok {
+				m.intp.Bind(argName, v)
+			} else {
+				m.intp.Bind(argName, Null)
+			}
+		}
+	} else {
+		for i, argName := range funDef.Args {
+			m.intp.Bind(argName, args[i])
+		}
+	}
+	return m.intp.EvalNode(funDef.Body)
+}
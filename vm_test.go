@@ -0,0 +1,196 @@
+package feel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVMMatchesTreeWalker(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, p := range evalPairs {
+		prog, err := CompileString(p.input)
+		assert.Nil(err)
+		intp := NewIntepreter()
+		if p.context != "" {
+			assert.Nil(intp.PushVars(p.context))
+		}
+		depth := intp.Len()
+		res, err := prog.Run(intp)
+		assert.Nil(err, p.input)
+		assert.True(cmp.Equal(p.expect, res), "%s gives %v\n%s", p.input, res, prog)
+		assert.Equal(depth, intp.Len(), "scopes are restored")
+	}
+}
+
+func TestVMResults(t *testing.T) {
+	assert := assert.New(t)
+
+	inputs := []struct {
+		input string
+		vars  string
+	}{
+		{`[]`, ""},
+		{`for x in [] return x`, ""},
+		{`some x in [1, 2] satisfies x > 5`, ""},
+		{`every x in [1, 2, 3] satisfies x >= 2`, ""},
+		{`for x in [1, 2], y in [3, 4] return x * y`, ""},
+		{`{a: 1, b: a + 1, c: {d: b * 2}}.c.d`, ""},
+		{`if a > 1 and b then "yes" else "no"`, `{a: 2, b: false}`},
+		{`[1, 2, 3][2] in [2..5)`, ""},
+		// macros and functions are evaluated by the tree walker, and
+		// see the locals of the call sites
+		{`for x in [1, 2] return typeof(x)`, ""},
+		{`for x in [1, 2] return is defined(x)`, ""},
+		{`for n in [1, 2, 3] return add(n)`, `{add: function(a) a + n * 10}`},
+		{`{f: function(a, b) a - b, r: f(b: 1, a: 5)}.r`, ""},
+		{`fact(5)`, `{fact: function(n) if n < 2 then 1 else n * fact(n - 1)}`},
+		{`sort([3, 1, 2], function(x, y) x < y)`, ""},
+		{`string length(name) + count(items)`, `{name: "feel", items: [1, 2]}`},
+		{`substring(string: "hello", start position: 2)`, ""},
+		{`missing = null`, ""},
+	}
+	for _, input := range inputs {
+		expect, err := EvalString(input.input, input.vars)
+		assert.Nil(err, input.input)
+
+		prog, err := CompileString(input.input)
+		assert.Nil(err)
+		intp := NewIntepreter()
+		if input.vars != "" {
+			assert.Nil(intp.PushVars(input.vars))
+		}
+		res, err := prog.Run(intp)
+		assert.Nil(err, input.input)
+		assert.True(cmp.Equal(expect, res), "%s gives %v, expect %v\n%s", input.input, res, expect, prog)
+	}
+}
+
+func TestVMErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	inputs := []string{
+		`1 + "a"`,
+		`@"P1D" * 30`,
+		`@"bad"`,
+		`[1, 2][5]`,
+		`{a: 1}.b`,
+		`for x in 5 return x`,
+		`substring("abc")`,
+		`substring(start position: 1)`,
+		`(function(a) a)(1, 2)`,
+		`5(1)`,
+		`typeof()`,
+	}
+	for _, input := range inputs {
+		_, expect := EvalString(input)
+		assert.NotNil(expect, input)
+
+		prog, err := CompileString(input)
+		assert.Nil(err)
+		_, err = prog.Run(NewIntepreter())
+		assert.Equal(expect, err, input)
+	}
+}
+
+func TestVMWithHooks(t *testing.T) {
+	assert := assert.New(t)
+
+	prog, err := CompileString(`for x in [1, 2] return x + 1`)
+	assert.Nil(err)
+	intp := NewIntepreter()
+	tracer := intp.EnableTrace()
+	res, err := prog.Run(intp)
+	assert.Nil(err)
+	assert.True(cmp.Equal([]any{N(2), N(3)}, res))
+	assert.Equal(1, len(tracer.Roots))
+	assert.Equal("ForExpr", tracer.Roots[0].Kind)
+}
+
+const benchmarkInput = `for item in items return if item.price * item.quantity > 100 and item.category in ["a", "b"] then item.price * 2 else item.price + discount`
+
+func benchmarkScope() Scope {
+	var items []any
+	for i := 0; i < 20; i++ {
+		category := "a"
+		if i%3 == 0 {
+			category = "c"
+		}
+		items = append(items, map[string]any{
+			"price":    N(i * 7),
+			"quantity": N(i % 5),
+			"category": category,
+		})
+	}
+	return Scope{"items": items, "discount": N(3)}
+}
+
+func BenchmarkTreeWalker(b *testing.B) {
+	ast, err := ParseString(benchmarkInput)
+	if err != nil {
+		b.Fatal(err)
+	}
+	scope := benchmarkScope()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intp := NewIntepreter()
+		intp.Push(scope)
+		if _, err := intp.EvalNode(ast); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVM(b *testing.B) {
+	prog, err := CompileString(benchmarkInput)
+	if err != nil {
+		b.Fatal(err)
+	}
+	scope := benchmarkScope()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intp := NewIntepreter()
+		intp.Push(scope)
+		if _, err := prog.Run(intp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTreeWalkerNativeCalls(b *testing.B) {
+	ast, err := ParseString(`for s in names return upper case(substring(s, 1, 3)) + string(string length(s))`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	scope := Scope{"names": []any{"alpha", "beta", "gamma", "delta", "epsilon"}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intp := NewIntepreter()
+		intp.Push(scope)
+		if _, err := intp.EvalNode(ast); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVMNativeCalls(b *testing.B) {
+	prog, err := CompileString(`for s in names return upper case(substring(s, 1, 3)) + string(string length(s))`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	scope := Scope{"names": []any{"alpha", "beta", "gamma", "delta", "epsilon"}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intp := NewIntepreter()
+		intp.Push(scope)
+		if _, err := prog.Run(intp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
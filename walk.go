@@ -0,0 +1,213 @@
+package feel
+
+// Visitor visits the nodes met by Walk. Visit is called with each node,
+// the children of the node are walked with the returned visitor unless
+// it's nil, and Visit(nil) is called with it after the children.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Children returns the child nodes in the evaluation order
+func Children(node Node) []Node {
+	var children []Node
+	switch v := node.(type) {
+	case *Binop:
+		children = []Node{v.Left, v.Right}
+	case *DotOp:
+		children = []Node{v.Left}
+	case *FunCall:
+		children = make([]Node, 0, len(v.Args)+1)
+		children = append(children, v.FunRef)
+		for _, arg := range v.Args {
+			children = append(children, arg.Arg)
+		}
+	case *FunDef:
+		children = []Node{v.Body}
+	case *MapNode:
+		children = make([]Node, 0, len(v.Values))
+		for _, item := range v.Values {
+			children = append(children, item.Value)
+		}
+	case *RangeNode:
+		children = []Node{v.Start, v.End}
+	case *IfExpr:
+		children = []Node{v.Cond, v.ThenBranch, v.ElseBranch}
+	case *ArrayNode:
+		children = v.Elements
+	case *MultiTests:
+		children = v.Elements
+	case *ForExpr:
+		children = []Node{v.ListExpr, v.ReturnExpr}
+	case *SomeExpr:
+		children = []Node{v.ListExpr, v.FilterExpr}
+	case *EveryExpr:
+		children = []Node{v.ListExpr, v.FilterExpr}
+	}
+	return children
+}
+
+// Walk traverses the AST in depth-first order, see Visitor
+func Walk(v Visitor, node Node) {
+	if node == nil {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range Children(node) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the AST in depth-first order, the children of a
+// node are skipped when f returns false, f(nil) is called after the
+// children
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite transforms the AST bottom up, f is called with each node whose
+// children are already rewritten and the node returned by f takes its
+// place. The input AST is never modified, nodes are copied only when
+// their children are replaced, so unchanged subtrees are shared.
+func Rewrite(node Node, f func(Node) Node) Node {
+	if node == nil {
+		return nil
+	}
+	return f(rewriteChildren(node, f))
+}
+
+func rewriteChildren(node Node, f func(Node) Node) Node {
+	switch v := node.(type) {
+	case *Binop:
+		left, right := Rewrite(v.Left, f), Rewrite(v.Right, f)
+		if left != v.Left || right != v.Right {
+			nv := *v
+			nv.Left, nv.Right = left, right
+			return &nv
+		}
+	case *DotOp:
+		if left := Rewrite(v.Left, f); left != v.Left {
+			nv := *v
+			nv.Left = left
+			return &nv
+		}
+	case *FunCall:
+		funRef := Rewrite(v.FunRef, f)
+		var args []FunCallArg
+		for i, arg := range v.Args {
+			if a := Rewrite(arg.Arg, f); a != arg.Arg {
+				if args == nil {
+					args = make([]FunCallArg, len(v.Args))
+					copy(args, v.Args)
+				}
+				args[i] = FunCallArg{Name: arg.Name, Arg: a}
+			}
+		}
+		if funRef != v.FunRef || args != nil {
+			nv := *v
+			nv.FunRef = funRef
+			if args != nil {
+				nv.Args = args
+			}
+			return &nv
+		}
+	case *FunDef:
+		if body := Rewrite(v.Body, f); body != v.Body {
+			nv := *v
+			nv.Body = body
+			return &nv
+		}
+	case *MapNode:
+		var values []MapItem
+		for i, item := range v.Values {
+			if value := Rewrite(item.Value, f); value != item.Value {
+				if values == nil {
+					values = make([]MapItem, len(v.Values))
+					copy(values, v.Values)
+				}
+				values[i] = MapItem{Name: item.Name, Value: value}
+			}
+		}
+		if values != nil {
+			nv := *v
+			nv.Values = values
+			return &nv
+		}
+	case *RangeNode:
+		start, end := Rewrite(v.Start, f), Rewrite(v.End, f)
+		if start != v.Start || end != v.End {
+			nv := *v
+			nv.Start, nv.End = start, end
+			return &nv
+		}
+	case *IfExpr:
+		cond := Rewrite(v.Cond, f)
+		thenBranch := Rewrite(v.ThenBranch, f)
+		elseBranch := Rewrite(v.ElseBranch, f)
+		if cond != v.Cond || thenBranch != v.ThenBranch || elseBranch != v.ElseBranch {
+			nv := *v
+			nv.Cond, nv.ThenBranch, nv.ElseBranch = cond, thenBranch, elseBranch
+			return &nv
+		}
+	case *ArrayNode:
+		if elements := rewriteNodes(v.Elements, f); elements != nil {
+			nv := *v
+			nv.Elements = elements
+			return &nv
+		}
+	case *MultiTests:
+		if elements := rewriteNodes(v.Elements, f); elements != nil {
+			nv := *v
+			nv.Elements = elements
+			return &nv
+		}
+	case *ForExpr:
+		list, ret := Rewrite(v.ListExpr, f), Rewrite(v.ReturnExpr, f)
+		if list != v.ListExpr || ret != v.ReturnExpr {
+			nv := *v
+			nv.ListExpr, nv.ReturnExpr = list, ret
+			return &nv
+		}
+	case *SomeExpr:
+		list, filter := Rewrite(v.ListExpr, f), Rewrite(v.FilterExpr, f)
+		if list != v.ListExpr || filter != v.FilterExpr {
+			nv := *v
+			nv.ListExpr, nv.FilterExpr = list, filter
+			return &nv
+		}
+	case *EveryExpr:
+		list, filter := Rewrite(v.ListExpr, f), Rewrite(v.FilterExpr, f)
+		if list != v.ListExpr || filter != v.FilterExpr {
+			nv := *v
+			nv.ListExpr, nv.FilterExpr = list, filter
+			return &nv
+		}
+	}
+	return node
+}
+
+// rewriteNodes rewrites the nodes, it returns nil if none is replaced
+func rewriteNodes(nodes []Node, f func(Node) Node) []Node {
+	var rewritten []Node
+	for i, node := range nodes {
+		if n := Rewrite(node, f); n != node {
+			if rewritten == nil {
+				rewritten = make([]Node, len(nodes))
+				copy(rewritten, nodes)
+			}
+			rewritten[i] = n
+		}
+	}
+	return rewritten
+}
@@ -0,0 +1,136 @@
+package feel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+type depthVisitor struct {
+	depth    int
+	maxDepth *int
+	count    *int
+}
+
+func (v depthVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		return nil
+	}
+	*v.count++
+	if v.depth > *v.maxDepth {
+		*v.maxDepth = v.depth
+	}
+	return depthVisitor{depth: v.depth + 1, maxDepth: v.maxDepth, count: v.count}
+}
+
+func TestWalk(t *testing.T) {
+	assert := assert.New(t)
+
+	ast, err := ParseString(`if a > 1 then [a, b.c] else f(x: 2)`)
+	assert.Nil(err)
+
+	var count, maxDepth int
+	Walk(depthVisitor{maxDepth: &maxDepth, count: &count}, ast)
+	// if, >, a, 1, array, a, ., b, call, f, 2
+	assert.Equal(11, count)
+	assert.Equal(3, maxDepth)
+}
+
+func TestInspect(t *testing.T) {
+	assert := assert.New(t)
+
+	ast, err := ParseString(`sum(for x in items return max(x.a, abs(x.b))) + count(items)`)
+	assert.Nil(err)
+
+	var funcs []string
+	Inspect(ast, func(node Node) bool {
+		if call, ok := node.(*FunCall); ok {
+			funcs = append(funcs, call.FunRef.Repr())
+		}
+		return true
+	})
+	assert.Equal([]string{"sum", "max", "abs", "count"}, funcs)
+
+	// the children of the for expression are skipped
+	funcs = nil
+	Inspect(ast, func(node Node) bool {
+		if call, ok := node.(*FunCall); ok {
+			funcs = append(funcs, call.FunRef.Repr())
+		}
+		_, isFor := node.(*ForExpr)
+		return !isFor
+	})
+	assert.Equal([]string{"sum", "count"}, funcs)
+}
+
+func TestRewrite(t *testing.T) {
+	assert := assert.New(t)
+
+	ast, err := ParseString(`{total: price * qty, label: upper case(name)}`)
+	assert.Nil(err)
+	original := ast.Repr()
+
+	// rename the variable price
+	renamed := Rewrite(ast, func(node Node) Node {
+		if v, ok := node.(*Var); ok && v.Name == "price" {
+			return &Var{Name: "unit price", Range: v.Range}
+		}
+		return node
+	})
+	assert.Equal(original, ast.Repr(), "the input is not modified")
+	assert.Equal("(map (\"total\" (* `unit price` qty)) (\"label\" (call `upper case` [name])))", renamed.Repr())
+
+	// the unchanged entries are shared
+	assert.True(ast.(*MapNode).Values[1].Value == renamed.(*MapNode).Values[1].Value)
+	assert.True(ast.(*MapNode).Values[0].Value != renamed.(*MapNode).Values[0].Value)
+
+	intp := NewIntepreter()
+	intp.Push(Scope{"unit price": 2, "qty": 3, "name": "pen"})
+	res, err := intp.EvalNode(renamed)
+	assert.Nil(err)
+	assert.True(cmp.Equal(map[string]any{"total": N(6), "label": "PEN"}, res))
+
+	// nothing replaced gives the same tree
+	same := Rewrite(ast, func(node Node) Node { return node })
+	assert.True(same == ast)
+}
+
+func TestRewriteGuards(t *testing.T) {
+	assert := assert.New(t)
+
+	ast, err := ParseString(`applicant.age > 18`)
+	assert.Nil(err)
+
+	// guard the attribute accesses against null
+	guarded := Rewrite(ast, func(node Node) Node {
+		if dot, ok := node.(*DotOp); ok {
+			return &IfExpr{
+				Cond:       &Binop{Op: "=", Left: dot.Left, Right: &NullNode{}},
+				ThenBranch: &NullNode{},
+				ElseBranch: dot,
+				Range:      dot.Range,
+			}
+		}
+		return node
+	})
+	assert.Equal("(> (if (= applicant null) null (. applicant age)) 18)", guarded.Repr())
+
+	intp := NewIntepreter()
+	intp.Push(Scope{"applicant": Null})
+	res, err := intp.EvalNode(guarded)
+	assert.Nil(err)
+	assert.Equal(Null, res)
+
+	intp = NewIntepreter()
+	intp.Push(Scope{"applicant": map[string]any{"age": 20}})
+	res, err = intp.EvalNode(guarded)
+	assert.Nil(err)
+	assert.Equal(true, res)
+
+	// the rewritten tree compiles as well
+	prog := Compile(guarded)
+	res, err = prog.RunWithScope(Scope{"applicant": map[string]any{"age": 12}})
+	assert.Nil(err)
+	assert.Equal(false, res)
+}